@@ -2,17 +2,36 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/approve"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkbranch"
 	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkmr"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkrelease"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/changelog"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/cherrypick"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/divergence"
 	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/merge"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/prune"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/report"
 )
 
+const version = "1.0.0"
+
 type arrayFlags []string
 
 func (i *arrayFlags) String() string {
@@ -24,10 +43,82 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
+type retryFlags struct {
+	maxRetries *int
+	maxDelay   *time.Duration
+}
+
+func addRetryFlags(fs *flag.FlagSet) retryFlags {
+	defaults := gitlab.DefaultRetryPolicy()
+	return retryFlags{
+		maxRetries: fs.Int("max-retries", defaults.MaxRetries, "Maximum retries for rate-limited or failed API requests (0 disables)"),
+		maxDelay:   fs.Duration("retry-max-delay", defaults.MaxDelay, "Longest wait allowed between retries"),
+	}
+}
+
+func newClient(gitlabURL, token string, verbose bool, retry retryFlags) *gitlab.Client {
+	client := gitlab.NewClient(gitlabURL, token, verbose)
+
+	policy := gitlab.DefaultRetryPolicy()
+	policy.MaxRetries = *retry.maxRetries
+	policy.MaxDelay = *retry.maxDelay
+	client.SetRetryPolicy(policy)
+
+	return client
+}
+
+const (
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitTimedOut     = 124
+	exitInterrupted  = 130
+)
+
+func exitCodeForError(err error) int {
+	switch {
+	case gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err):
+		return exitUnauthorized
+	case gitlab.IsNotFound(err):
+		return exitNotFound
+	default:
+		return 1
+	}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// exitIfCanceled terminates with a conventional exit code when the run was
+// interrupted by a signal or ran past its --timeout deadline.
+func exitIfCanceled(ctx context.Context) {
+	switch ctx.Err() {
+	case context.Canceled:
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		os.Exit(exitInterrupted)
+	case context.DeadlineExceeded:
+		fmt.Fprintln(os.Stderr, "Timed out")
+		os.Exit(exitTimedOut)
+	}
+}
+
 func main() {
 	// Try to load .env file (silently fail if not present)
 	_ = godotenv.Load()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The first signal cancels in-flight work; restoring the default handler
+	// lets a second Ctrl-C terminate immediately.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
@@ -37,17 +128,31 @@ func main() {
 
 	switch command {
 	case "bulk-mr":
-		bulkMRCommand()
+		bulkMRCommand(ctx)
 	case "bulk-mr-topic":
-		bulkMRTopicCommand()
+		bulkMRTopicCommand(ctx)
+	case "bulk-mr-apply":
+		bulkMRApplyCommand(ctx)
+	case "bulk-branch":
+		bulkBranchCommand(ctx)
+	case "bulk-release":
+		bulkReleaseCommand(ctx)
+	case "bulk-cherry-pick":
+		bulkCherryPickCommand(ctx)
+	case "branches":
+		branchesCommand(ctx)
+	case "status":
+		statusCommand(ctx)
 	case "merge":
-		mergeCommand()
+		mergeCommand(ctx)
+	case "approve":
+		approveCommand(ctx)
 	case "topics":
-		topicsCommand()
+		topicsCommand(ctx)
 	case "projects":
-		projectsCommand()
+		projectsCommand(ctx)
 	case "version":
-		fmt.Println("gitlab-tools v1.0.0")
+		fmt.Printf("gitlab-tools v%s\n", version)
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -66,7 +171,14 @@ func printUsage() {
 	fmt.Println("Commands:")
 	fmt.Println("  bulk-mr         Create bulk merge requests across multiple projects")
 	fmt.Println("  bulk-mr-topic   Create bulk merge requests for all projects in a topic")
+	fmt.Println("  bulk-mr-apply   Create the merge requests recorded in a dry-run plan")
+	fmt.Println("  bulk-branch     Create a branch across multiple projects (bulk-branch create)")
+	fmt.Println("  bulk-release    Tag a branch head and create releases across multiple projects")
+	fmt.Println("  bulk-cherry-pick Cherry-pick hotfix commits onto a branch across multiple projects")
+	fmt.Println("  branches        Delete merged or stale branches across a topic (branches prune)")
+	fmt.Println("  status          Show how far environment branches diverge across a topic")
 	fmt.Println("  merge           Interactively merge open MRs by target branch and topic")
+	fmt.Println("  approve         Review diffs and approve open MRs by target branch and topic")
 	fmt.Println("  topics          List all GitLab topics")
 	fmt.Println("  projects        List all projects for a specific topic")
 	fmt.Println("  version    Show version information")
@@ -75,7 +187,7 @@ func printUsage() {
 	fmt.Println("Run 'gitlab-tools <command> --help' for more information on a command.")
 }
 
-func bulkMRCommand() {
+func bulkMRCommand(ctx context.Context) {
 	fs := flag.NewFlagSet("bulk-mr", flag.ExitOnError)
 
 	origin := fs.String("origin", "", "Origin (source) branch name (required)")
@@ -83,7 +195,11 @@ func bulkMRCommand() {
 	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
 	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
 	group := fs.String("group", "", "Default group/namespace prefix (optional)")
+	bulkFlags := addBulkMRFlags(fs)
 	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
 
 	var projects arrayFlags
 	fs.Var(&projects, "project", "Project path (can be repeated)")
@@ -106,6 +222,11 @@ func bulkMRCommand() {
 		fmt.Println("  gitlab-tools bulk-mr --origin op-stage --target op-rc \\")
 		fmt.Println("    --group mygroup --project repo-a --project repo-b")
 		fmt.Println()
+		fmt.Println("  # With a custom title template")
+		fmt.Println("  gitlab-tools bulk-mr --origin op-stage --target op-rc \\")
+		fmt.Println("    --title-template 'Release {{date \"2006.01\" .Run.StartedAt}} → {{.TargetBranch}}' \\")
+		fmt.Println("    --project group/repo-a")
+		fmt.Println()
 		fmt.Println("Environment Variables:")
 		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
 		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
@@ -115,6 +236,8 @@ func bulkMRCommand() {
 		os.Exit(1)
 	}
 
+	p := mustPrinter(*outputFormat)
+
 	if *origin == "" {
 		fmt.Fprintln(os.Stderr, "Error: --origin is required")
 		fs.Usage()
@@ -161,91 +284,262 @@ func bulkMRCommand() {
 		Verbose:      *verbose,
 	}
 
+	if err := bulkFlags.apply(&config, p); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	if *verbose {
 		log.SetFlags(log.Ltime)
 	} else {
 		log.SetFlags(0)
 	}
 
-	fmt.Printf("Processing %d project(s)...\n\n", len(processedProjects))
+	p.statusf("Processing %d project(s)...\n\n", len(processedProjects))
 
-	client := gitlab.NewClient(*gitlabURL, *token, *verbose)
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
 	service := bulkmr.NewService(client, config)
 
-	results, summary := service.ProcessProjects()
+	runBulkMR(ctx, p, service, fs.Name(), config, bulkFlags)
+}
+
+type bulkMRFlags struct {
+	concurrency         *int
+	titleTemplate       *string
+	descriptionTemplate *string
+	templateFile        *string
+	changelog           *bool
+	issuePattern        *string
+	labels              *arrayFlags
+	assignees           *arrayFlags
+	reviewers           *arrayFlags
+	codeownerReviewers  *bool
+	milestone           *string
+	dryRun              *bool
+	planOut             *string
+	junitReport         *string
+}
 
-	for _, result := range results {
-		printResult(result)
+func addBulkMRFlags(fs *flag.FlagSet) bulkMRFlags {
+	f := bulkMRFlags{
+		concurrency:         fs.Int("concurrency", 4, "Number of projects to process in parallel"),
+		titleTemplate:       fs.String("title-template", "", "Go text/template for the MR title (default: \"Merge <origin> into <target>\")"),
+		descriptionTemplate: fs.String("description-template", "", "Go text/template for the MR description"),
+		templateFile:        fs.String("template-file", "", "File defining {{define \"title\"}} and/or {{define \"description\"}} templates"),
+		changelog:           fs.Bool("changelog", true, "Include a changelog of commits, issues, authors and files in the MR description"),
+		issuePattern:        fs.String("issue-pattern", changelog.DefaultIssuePattern.String(), "Regular expression for issue references in commit messages"),
+		labels:              &arrayFlags{},
+		assignees:           &arrayFlags{},
+		reviewers:           &arrayFlags{},
+		codeownerReviewers:  fs.Bool("reviewers-from-codeowners", false, "Request review from the CODEOWNERS of the changed files"),
+		milestone:           fs.String("milestone", "", "Milestone title to set on created MRs"),
+		dryRun:              fs.Bool("dry-run", false, "Run every check and report WOULD_CREATE without creating MRs"),
+		planOut:             fs.String("plan-out", "", "Write the dry-run plan to this file for bulk-mr-apply (implies --dry-run)"),
+		junitReport:         addJUnitFlag(fs),
 	}
 
-	fmt.Println()
-	printSummary(summary)
+	fs.Var(f.labels, "label", "Label to set on created MRs (comma-separated or repeated)")
+	fs.Var(f.assignees, "assignee", "Username to assign created MRs to (comma-separated or repeated)")
+	fs.Var(f.reviewers, "reviewer", "Username to request review from (comma-separated or repeated)")
 
-	if summary.Errors > 0 {
-		os.Exit(1)
+	return f
+}
+
+func (f bulkMRFlags) apply(config *bulkmr.Config, p *printer) error {
+	templates, err := bulkmr.LoadTemplates(*f.titleTemplate, *f.descriptionTemplate, *f.templateFile)
+	if err != nil {
+		return err
+	}
+
+	issuePattern, err := regexp.Compile(*f.issuePattern)
+	if err != nil {
+		return fmt.Errorf("invalid --issue-pattern: %w", err)
+	}
+
+	config.Templates = templates
+	config.Changelog = *f.changelog
+	config.IssuePattern = issuePattern
+	config.Concurrency = *f.concurrency
+	config.Labels = splitList(*f.labels)
+	config.Assignees = splitList(*f.assignees)
+	config.Reviewers = splitList(*f.reviewers)
+	config.ReviewersFromCodeowners = *f.codeownerReviewers
+	config.Milestone = *f.milestone
+	config.DryRun = *f.dryRun || *f.planOut != ""
+	config.Progress = p.bulkMRProgress
+	config.Run.StartedAt = time.Now()
+	config.Run.Version = version
+
+	return nil
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
 	}
+	return items
 }
 
-func printResult(result bulkmr.ProjectResult) {
-	statusIcon := getStatusIcon(result.Status)
-	fmt.Printf("[%s] %s %s\n", result.Project, statusIcon, result.Status)
+func runBulkMR(ctx context.Context, p *printer, service *bulkmr.Service, command string, config bulkmr.Config, f bulkMRFlags) {
+	results, summary := service.ProcessProjects(ctx)
+	p.bulkMRReport(results, summary)
 
-	if result.Details != "" {
-		fmt.Printf("  %s\n", result.Details)
+	writeJUnitReport(*f.junitReport, report.BulkMRSuite(command, config.TargetBranch, config.Run.StartedAt, results))
+
+	exitIfCanceled(ctx)
+
+	if planOut := *f.planOut; planOut != "" {
+		plan := service.Plan(results)
+		if err := bulkmr.SavePlan(planOut, plan); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		p.statusf("\nPlan with %d merge request(s) written to %s\n", len(plan.Entries), planOut)
+		p.statusf("Apply it with: gitlab-tools bulk-mr-apply --plan %s\n", planOut)
 	}
 
-	if result.ErrorMessage != "" {
-		fmt.Printf("  Error: %s\n", result.ErrorMessage)
+	exitForSummary(summary)
+}
+
+func addJUnitFlag(fs *flag.FlagSet) *string {
+	return fs.String("junit-report", "", "Write per-project outcomes to this file as a JUnit XML report")
+}
+
+// writeJUnitReport runs even for canceled or failed runs, so CI still gets
+// the outcomes that are known.
+func writeJUnitReport(path string, suites ...report.Suite) {
+	if path == "" {
+		return
 	}
 
-	fmt.Println()
+	if err := report.WriteJUnit(path, suites...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
 
-func getStatusIcon(status bulkmr.ResultStatus) string {
-	switch status {
-	case bulkmr.StatusCreated:
-		return "✓"
-	case bulkmr.StatusSkippedExists:
-		return "→"
-	case bulkmr.StatusSkippedDraft:
-		return "⊘"
-	case bulkmr.StatusSkippedBranch:
-		return "⚠"
-	case bulkmr.StatusSkippedNoChange:
-		return "≡"
-	case bulkmr.StatusError:
-		return "✗"
-	default:
-		return "?"
+func exitForSummary(summary bulkmr.Summary) {
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	case summary.NotFound > 0:
+		os.Exit(exitNotFound)
 	}
 }
 
-func printSummary(summary bulkmr.Summary) {
-	fmt.Println("Summary:")
-	fmt.Printf("  Total projects: %d\n", summary.Total)
-	fmt.Printf("  Created: %d\n", summary.Created)
-	fmt.Printf("  Skipped (exists): %d\n", summary.SkippedExists)
-	fmt.Printf("  Skipped (draft): %d\n", summary.SkippedDraft)
-	fmt.Printf("  Skipped (no changes): %d\n", summary.SkippedNoChange)
-	fmt.Printf("  Skipped (no branch): %d\n", summary.SkippedBranch)
-	fmt.Printf("  Errors: %d\n", summary.Errors)
-	fmt.Println()
+func bulkMRApplyCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("bulk-mr-apply", flag.ExitOnError)
+
+	planFile := fs.String("plan", "", "Plan file written by --plan-out (required)")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	junitReport := addJUnitFlag(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	fs.Usage = func() {
+		fmt.Println("Create the merge requests recorded in a dry-run plan, exactly as planned")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools bulk-mr-apply --plan <file>")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Review a plan, then apply it")
+		fmt.Println("  gitlab-tools bulk-mr-topic --origin op-stage --target op-rc --topic backend --plan-out plan.json")
+		fmt.Println("  gitlab-tools bulk-mr-apply --plan plan.json")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *planFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --plan is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	plan, err := bulkmr.LoadPlan(*planFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	if summary.Errors == 0 {
-		fmt.Println("✓ Completed successfully")
+	if *verbose {
+		log.SetFlags(log.Ltime)
 	} else {
-		fmt.Println("✗ Completed with errors")
+		log.SetFlags(0)
 	}
+
+	p.statusf("Applying plan from %s: %d merge request(s) from %s into %s\n\n",
+		*planFile, len(plan.Entries), plan.OriginBranch, plan.TargetBranch)
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	service := bulkmr.NewService(client, bulkmr.Config{
+		Verbose:     *verbose,
+		Concurrency: *concurrency,
+		Progress:    p.bulkMRProgress,
+	})
+
+	startedAt := time.Now()
+	results, summary := service.ApplyPlan(ctx, plan)
+	p.bulkMRReport(results, summary)
+
+	writeJUnitReport(*junitReport, report.BulkMRSuite("bulk-mr-apply", plan.TargetBranch, startedAt, results))
+
+	exitIfCanceled(ctx)
+	exitForSummary(summary)
 }
 
-func topicsCommand() {
+func topicsCommand(ctx context.Context) {
 	fs := flag.NewFlagSet("topics", flag.ExitOnError)
 
 	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
 	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
 	perPage := fs.Int("per-page", 50, "Number of topics per page")
 	page := fs.Int("page", 1, "Page number")
+	all := fs.Bool("all", false, "Fetch every page instead of a single one")
 	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
 
 	fs.Usage = func() {
 		fmt.Println("List all GitLab topics")
@@ -263,6 +557,9 @@ func topicsCommand() {
 		fmt.Println("  # List topics with pagination")
 		fmt.Println("  gitlab-tools topics --page 2 --per-page 20")
 		fmt.Println()
+		fmt.Println("  # List every topic across all pages")
+		fmt.Println("  gitlab-tools topics --all")
+		fmt.Println()
 		fmt.Println("Environment Variables:")
 		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
 		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
@@ -272,6 +569,8 @@ func topicsCommand() {
 		os.Exit(1)
 	}
 
+	p := mustPrinter(*outputFormat)
+
 	if *gitlabURL == "" {
 		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
 		fs.Usage()
@@ -284,17 +583,27 @@ func topicsCommand() {
 		os.Exit(1)
 	}
 
-	client := gitlab.NewClient(*gitlabURL, *token, *verbose)
-	topics, err := client.ListTopics(*page, *perPage)
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	var topics []gitlab.Topic
+	var err error
+	if *all {
+		topics, err = client.ListAllTopics(ctx, *perPage)
+	} else {
+		topics, err = client.ListTopics(ctx, *page, *perPage)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching topics: %v\n", err)
-		os.Exit(1)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
 	}
 
-	renderTopics(topics)
+	p.topics(topics)
 }
 
-func projectsCommand() {
+func projectsCommand(ctx context.Context) {
 	fs := flag.NewFlagSet("projects", flag.ExitOnError)
 
 	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
@@ -302,7 +611,11 @@ func projectsCommand() {
 	topic := fs.String("topic", "", "Topic name (required)")
 	perPage := fs.Int("per-page", 50, "Number of projects per page")
 	page := fs.Int("page", 1, "Page number")
+	all := fs.Bool("all", false, "Fetch every page instead of a single one")
 	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
 
 	fs.Usage = func() {
 		fmt.Println("List all projects for a specific topic")
@@ -320,6 +633,9 @@ func projectsCommand() {
 		fmt.Println("  # List projects with pagination")
 		fmt.Println("  gitlab-tools projects --topic backend --page 2 --per-page 20")
 		fmt.Println()
+		fmt.Println("  # List every project in the topic across all pages")
+		fmt.Println("  gitlab-tools projects --topic backend --all")
+		fmt.Println()
 		fmt.Println("Environment Variables:")
 		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
 		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
@@ -329,6 +645,8 @@ func projectsCommand() {
 		os.Exit(1)
 	}
 
+	p := mustPrinter(*outputFormat)
+
 	if *topic == "" {
 		fmt.Fprintln(os.Stderr, "Error: --topic is required")
 		fs.Usage()
@@ -347,85 +665,27 @@ func projectsCommand() {
 		os.Exit(1)
 	}
 
-	client := gitlab.NewClient(*gitlabURL, *token, *verbose)
-	projects, err := client.ListProjectsByTopic(*topic, *page, *perPage)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
-		os.Exit(1)
-	}
-
-	renderProjects(*topic, projects)
-}
-
-func renderTopics(topics []gitlab.Topic) {
-	fmt.Printf("\n📚 \033[1;35mGitLab Topics\033[0m\n\n")
-
-	if len(topics) == 0 {
-		fmt.Println("\033[2mNo topics found.\033[0m")
-		return
-	}
-
-	for i, topic := range topics {
-		fmt.Printf("\033[1m%d.\033[0m \033[1;35m%s\033[0m", i+1, topic.Name)
-
-		if topic.Title != "" && topic.Title != topic.Name {
-			fmt.Printf(" - %s", topic.Title)
-		}
-
-		fmt.Println()
-
-		if topic.TotalProjectsCount > 0 {
-			fmt.Printf("   \033[35m📦 %d projects\033[0m\n", topic.TotalProjectsCount)
-		}
-
-		if topic.Description != "" && topic.Description != topic.Title {
-			desc := topic.Description
-			if len(desc) > 80 {
-				desc = desc[:77] + "..."
-			}
-			fmt.Printf("   \033[2m%s\033[0m\n", desc)
-		}
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
 
-		fmt.Println()
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	var projects []gitlab.Project
+	var err error
+	if *all {
+		projects, err = client.ListAllProjectsByTopic(ctx, *topic, *perPage)
+	} else {
+		projects, err = client.ListProjectsByTopic(ctx, *topic, *page, *perPage)
 	}
-}
-
-func renderProjects(topicName string, projects []gitlab.Project) {
-	fmt.Printf("\n📁 \033[1;35mProjects in topic: %s\033[0m\n\n", topicName)
-
-	if len(projects) == 0 {
-		fmt.Println("\033[2mNo projects found for this topic.\033[0m")
-		return
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
 	}
 
-	for i, project := range projects {
-		fmt.Printf("\033[1m%d.\033[0m \033[1m%s\033[0m\n", i+1, project.Name)
-		fmt.Printf("   \033[2m%s\033[0m\n", project.PathWithNamespace)
-
-		if project.Description != "" {
-			desc := project.Description
-			if len(desc) > 80 {
-				desc = desc[:77] + "..."
-			}
-			fmt.Printf("   \033[3;2m%s\033[0m\n", desc)
-		}
-
-		if len(project.Topics) > 0 {
-			fmt.Print("   ")
-			for _, t := range project.Topics {
-				if t != topicName {
-					fmt.Printf("\033[45m\033[37m %s \033[0m ", t)
-				}
-			}
-			fmt.Println()
-		}
-
-		fmt.Printf("   \033[4;32m%s\033[0m\n", project.WebURL)
-		fmt.Println()
-	}
+	p.projects(*topic, projects)
 }
 
-func bulkMRTopicCommand() {
+func bulkMRTopicCommand(ctx context.Context) {
 	fs := flag.NewFlagSet("bulk-mr-topic", flag.ExitOnError)
 
 	origin := fs.String("origin", "", "Origin (source) branch name (required)")
@@ -434,7 +694,11 @@ func bulkMRTopicCommand() {
 	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
 	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
 	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	bulkFlags := addBulkMRFlags(fs)
 	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
 
 	fs.Usage = func() {
 		fmt.Println("Create merge requests from origin to target branch for all projects in a topic")
@@ -461,6 +725,8 @@ func bulkMRTopicCommand() {
 		os.Exit(1)
 	}
 
+	p := mustPrinter(*outputFormat)
+
 	if *origin == "" {
 		fmt.Fprintln(os.Stderr, "Error: --origin is required")
 		fs.Usage()
@@ -497,35 +763,26 @@ func bulkMRTopicCommand() {
 		log.SetFlags(0)
 	}
 
-	client := gitlab.NewClient(*gitlabURL, *token, *verbose)
-
-	fmt.Printf("Fetching projects for topic: \033[1;35m%s\033[0m\n\n", *topic)
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
 
-	// Fetch all projects for the topic (handle pagination)
-	var allProjects []gitlab.Project
-	page := 1
-	for {
-		projects, err := client.ListProjectsByTopic(*topic, page, *perPage)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
-			os.Exit(1)
-		}
+	client := newClient(*gitlabURL, *token, *verbose, retry)
 
-		if len(projects) == 0 {
-			break
-		}
-
-		allProjects = append(allProjects, projects...)
-
-		if len(projects) < *perPage {
-			break
-		}
+	p.statusf("Fetching projects for topic: %s\n\n", p.paint("1;35", *topic))
 
-		page++
+	allProjects, err := client.ListAllProjectsByTopic(ctx, *topic, *perPage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
 	}
 
 	if len(allProjects) == 0 {
-		fmt.Printf("No projects found for topic: %s\n", *topic)
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.bulkMRReport(nil, bulkmr.Summary{})
+		}
+		writeJUnitReport(*bulkFlags.junitReport, report.BulkMRSuite(fs.Name(), *target, time.Now(), nil))
 		os.Exit(0)
 	}
 
@@ -535,137 +792,1264 @@ func bulkMRTopicCommand() {
 		projectPaths[i] = project.PathWithNamespace
 	}
 
-	fmt.Printf("Found %d project(s) in topic \033[1;35m%s\033[0m\n\n", len(projectPaths), *topic)
+	p.statusf("Found %d project(s) in topic %s\n\n", len(projectPaths), p.paint("1;35", *topic))
 
 	config := bulkmr.Config{
 		OriginBranch: *origin,
 		TargetBranch: *target,
 		Projects:     projectPaths,
+		Run:          bulkmr.RunInfo{Topic: *topic},
 		Verbose:      *verbose,
 	}
 
+	if err := bulkFlags.apply(&config, p); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	service := bulkmr.NewService(client, config)
-	results, summary := service.ProcessProjects()
+	runBulkMR(ctx, p, service, fs.Name(), config, bulkFlags)
+}
 
-	for _, result := range results {
-		printResult(result)
+func bulkBranchCommand(ctx context.Context) {
+	if len(os.Args) < 3 || os.Args[2] != "create" {
+		fmt.Fprintln(os.Stderr, "Usage: gitlab-tools bulk-branch create [options]")
+		os.Exit(1)
 	}
 
-	fmt.Println()
-	printSummary(summary)
+	fs := flag.NewFlagSet("bulk-branch-create", flag.ExitOnError)
 
-	if summary.Errors > 0 {
+	ref := fs.String("ref", "", "Branch, tag or commit SHA to create the branch from (required)")
+	branch := fs.String("branch", "", "Name of the branch to create (required)")
+	topic := fs.String("topic", "", "Create the branch in all projects of this topic")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	group := fs.String("group", "", "Default group/namespace prefix for --project (optional)")
+	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	junitReport := addJUnitFlag(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	var projects arrayFlags
+	fs.Var(&projects, "project", "Project path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println("Create a branch from a ref across multiple projects")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools bulk-branch create --ref <ref> --branch <name> --topic <topic>")
+		fmt.Println("  gitlab-tools bulk-branch create --ref <ref> --branch <name> --project <path> [--project <path>...]")
+		fmt.Println()
+		fmt.Println("Projects that already have the branch at the ref's commit are skipped, so")
+		fmt.Println("the command can be re-run safely.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Cut a release line from op-stage in all backend projects")
+		fmt.Println("  gitlab-tools bulk-branch create --ref op-stage --branch op-rc-2026.10 --topic backend")
+		fmt.Println()
+		fmt.Println("  # For two projects")
+		fmt.Println("  gitlab-tools bulk-branch create --ref op-stage --branch op-rc-2026.10 \\")
+		fmt.Println("    --group mygroup --project repo-a --project repo-b")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[3:]); err != nil {
 		os.Exit(1)
 	}
-}
 
-func mergeCommand() {
-	mergeCmd := flag.NewFlagSet("merge", flag.ExitOnError)
-	target := mergeCmd.String("target", "", "Target branch to merge into (required)")
-	topic := mergeCmd.String("topic", "", "Topic to filter projects (required)")
+	p := mustPrinter(*outputFormat)
+
+	if *ref == "" {
+		fmt.Fprintln(os.Stderr, "Error: --ref is required")
+		fs.Usage()
+		os.Exit(1)
+	}
 
-	mergeCmd.Parse(os.Args[2:])
+	if *branch == "" {
+		fmt.Fprintln(os.Stderr, "Error: --branch is required")
+		fs.Usage()
+		os.Exit(1)
+	}
 
-	if *target == "" || *topic == "" {
-		fmt.Println("\033[31mError: both --target and --topic are required\033[0m")
-		mergeCmd.PrintDefaults()
+	if (*topic == "") == (len(projects) == 0) {
+		fmt.Fprintln(os.Stderr, "Error: exactly one of --topic or --project is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
 		os.Exit(1)
 	}
 
-	baseURL := os.Getenv("GITLAB_BASE_URL")
-	token := os.Getenv("GITLAB_TOKEN")
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
 
-	if baseURL == "" || token == "" {
-		log.Fatal("GITLAB_BASE_URL and GITLAB_TOKEN environment variables are required")
+	if *verbose {
+		log.SetFlags(log.Ltime)
+	} else {
+		log.SetFlags(0)
 	}
 
-	client := gitlab.NewClient(baseURL, token, false)
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	startedAt := time.Now()
 
-	// Get all projects for the topic
-	fmt.Printf("\033[36m📦 Fetching projects for topic: %s\033[0m\n", *topic)
-	projects, err := client.ListProjectsByTopic(*topic, 1, 100)
+	projectPaths, err := resolveProjectPaths(ctx, p, client, *topic, projects, *group, *perPage)
 	if err != nil {
-		log.Fatalf("Failed to fetch projects: %v", err)
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
 	}
 
-	if len(projects) == 0 {
-		fmt.Printf("\033[33m⚠️  No projects found for topic: %s\033[0m\n", *topic)
-		return
+	if len(projectPaths) == 0 {
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.bulkBranchReport(nil, bulkbranch.Summary{})
+		}
+		writeJUnitReport(*junitReport, report.BulkBranchSuite(fs.Name(), *branch, startedAt, nil))
+		os.Exit(0)
 	}
 
-	fmt.Printf("\033[32m✓ Found %d projects\033[0m\n\n", len(projects))
+	p.statusf("Creating %s from %s in %d project(s)...\n\n", p.paint("1;36", *branch), *ref, len(projectPaths))
 
-	scanner := bufio.NewScanner(os.Stdin)
-	mergedCount := 0
-	skippedCount := 0
-	errorCount := 0
+	service := bulkbranch.NewService(client, bulkbranch.Config{
+		Ref:         *ref,
+		Branch:      *branch,
+		Projects:    projectPaths,
+		Verbose:     *verbose,
+		Concurrency: *concurrency,
+		Progress:    p.bulkBranchProgress,
+	})
 
-	// Process each project
-	for _, project := range projects {
-		// Get open merge requests for this project targeting the specified branch
-		mrs, err := client.ListOpenMergeRequestsByTarget(project.ID, *target)
-		if err != nil {
-			fmt.Printf("\033[31m✗ Error fetching MRs for %s: %v\033[0m\n", project.PathWithNamespace, err)
-			errorCount++
-			continue
-		}
+	results, summary := service.Create(ctx)
+	p.bulkBranchReport(results, summary)
 
-		// Filter out draft MRs
-		nonDraftMRs := []gitlab.MergeRequest{}
-		for _, mr := range mrs {
-			if !mr.IsDraft() {
-				nonDraftMRs = append(nonDraftMRs, mr)
-			}
-		}
+	writeJUnitReport(*junitReport, report.BulkBranchSuite(fs.Name(), *branch, startedAt, results))
 
-		if len(nonDraftMRs) == 0 {
-			continue
-		}
+	exitIfCanceled(ctx)
 
-		// Display and prompt for each MR
-		for _, mr := range nonDraftMRs {
-			fmt.Printf("\033[36m━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\033[0m\n")
-			fmt.Printf("\033[1;36mProject:\033[0m %s\n", project.PathWithNamespace)
-			fmt.Printf("\033[1;36mMR Title:\033[0m %s\n", mr.Title)
-			fmt.Printf("\033[1;36mBranches:\033[0m %s → %s\n", mr.SourceBranch, mr.TargetBranch)
-			fmt.Printf("\033[1;36mURL:\033[0m %s\n", mr.WebURL)
-			fmt.Printf("\033[36m━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\033[0m\n")
-			fmt.Print("\033[1;33mMerge this MR? (y/n): \033[0m")
-
-			if !scanner.Scan() {
-				break
-			}
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	case summary.NotFound > 0:
+		os.Exit(exitNotFound)
+	}
+}
 
-			response := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-			if response == "y" || response == "yes" {
-				_, err := client.AcceptMergeRequest(project.ID, mr.IID)
-				if err != nil {
-					fmt.Printf("\033[31m✗ Failed to merge: %v\033[0m\n\n", err)
-					errorCount++
-				} else {
-					fmt.Printf("\033[32m✓ Successfully merged!\033[0m\n\n")
-					mergedCount++
-				}
+// resolveProjectPaths returns the paths of the projects in topic, or the
+// given projects with the group prefix applied.
+func resolveProjectPaths(ctx context.Context, p *printer, client *gitlab.Client, topic string, projects []string, group string, perPage int) ([]string, error) {
+	if topic == "" {
+		paths := make([]string, len(projects))
+		for i, project := range projects {
+			if group != "" && !strings.Contains(project, "/") {
+				paths[i] = fmt.Sprintf("%s/%s", group, project)
 			} else {
-				fmt.Printf("\033[33m⊘ Skipped\033[0m\n\n")
-				skippedCount++
+				paths[i] = project
 			}
 		}
+		return paths, nil
 	}
 
-	// Print summary
-	fmt.Printf("\033[36m━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\033[0m\n")
-	fmt.Printf("\033[1;36m📊 Summary\033[0m\n")
-	fmt.Printf("\033[36m━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\033[0m\n")
-	fmt.Printf("\033[32m✓ Merged:  %d\033[0m\n", mergedCount)
-	fmt.Printf("\033[33m⊘ Skipped: %d\033[0m\n", skippedCount)
-	if errorCount > 0 {
-		fmt.Printf("\033[31m✗ Errors:  %d\033[0m\n", errorCount)
+	p.statusf("Fetching projects for topic: %s\n\n", p.paint("1;35", topic))
+
+	found, err := client.ListAllProjectsByTopic(ctx, topic, perPage)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("\033[36m━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\033[0m\n")
 
-	if errorCount > 0 {
-		os.Exit(1)
+	paths := make([]string, len(found))
+	for i, project := range found {
+		paths[i] = project.PathWithNamespace
+	}
+	return paths, nil
+}
+
+func bulkReleaseCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("bulk-release", flag.ExitOnError)
+
+	ref := fs.String("ref", "", "Branch whose head is tagged (required)")
+	tag := fs.String("tag", "", "Tag and release version, e.g. v2026.10 (required)")
+	name := fs.String("name", "", "Release title (default: the tag)")
+	message := fs.String("message", "", "Tag message; makes the tag an annotated tag")
+	topic := fs.String("topic", "", "Release all projects of this topic")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	group := fs.String("group", "", "Default group/namespace prefix for --project (optional)")
+	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	issuePattern := fs.String("issue-pattern", changelog.DefaultIssuePattern.String(), "Regular expression for issue references in commit messages")
+	dryRun := fs.Bool("dry-run", false, "Build the release notes and report WOULD_CREATE without tagging")
+	junitReport := addJUnitFlag(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	var projects arrayFlags
+	fs.Var(&projects, "project", "Project path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println("Tag the head of a branch and create a release with notes across multiple projects")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools bulk-release --ref <branch> --tag <version> --topic <topic>")
+		fmt.Println("  gitlab-tools bulk-release --ref <branch> --tag <version> --project <path> [--project <path>...]")
+		fmt.Println()
+		fmt.Println("The release notes list the commits since the project's previous tag. Projects")
+		fmt.Println("that already have the tag at the same commit are skipped.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Preview the release notes")
+		fmt.Println("  gitlab-tools bulk-release --ref op-rc --tag v2026.10 --topic backend --dry-run")
+		fmt.Println()
+		fmt.Println("  # Tag and release every backend service")
+		fmt.Println("  gitlab-tools bulk-release --ref op-rc --tag v2026.10 --topic backend")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *ref == "" {
+		fmt.Fprintln(os.Stderr, "Error: --ref is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *tag == "" {
+		fmt.Fprintln(os.Stderr, "Error: --tag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if (*topic == "") == (len(projects) == 0) {
+		fmt.Fprintln(os.Stderr, "Error: exactly one of --topic or --project is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	issues, err := regexp.Compile(*issuePattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --issue-pattern: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *verbose {
+		log.SetFlags(log.Ltime)
+	} else {
+		log.SetFlags(0)
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	startedAt := time.Now()
+
+	projectPaths, err := resolveProjectPaths(ctx, p, client, *topic, projects, *group, *perPage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
+	}
+
+	if len(projectPaths) == 0 {
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.bulkReleaseReport(nil, bulkrelease.Summary{}, false)
+		}
+		writeJUnitReport(*junitReport, report.BulkReleaseSuite(fs.Name(), *tag, startedAt, nil))
+		os.Exit(0)
+	}
+
+	p.statusf("Releasing %s from %s in %d project(s)...\n\n", p.paint("1;36", *tag), *ref, len(projectPaths))
+
+	service := bulkrelease.NewService(client, bulkrelease.Config{
+		Ref:          *ref,
+		Tag:          *tag,
+		Name:         *name,
+		Message:      *message,
+		Projects:     projectPaths,
+		IssuePattern: issues,
+		DryRun:       *dryRun,
+		Verbose:      *verbose,
+		Concurrency:  *concurrency,
+		Progress:     p.bulkReleaseProgress,
+	})
+
+	results, summary := service.Release(ctx)
+	p.bulkReleaseReport(results, summary, *dryRun)
+
+	writeJUnitReport(*junitReport, report.BulkReleaseSuite(fs.Name(), *tag, startedAt, results))
+
+	exitIfCanceled(ctx)
+
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	case summary.NotFound > 0:
+		os.Exit(exitNotFound)
+	}
+}
+
+func bulkCherryPickCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("bulk-cherry-pick", flag.ExitOnError)
+
+	target := fs.String("target", "", "Branch to cherry-pick onto, e.g. op-rc (required)")
+	branch := fs.String("branch", "", "Pick onto this new branch, created from --target, and open an MR into --target")
+	pattern := fs.String("pattern", "", "Pick the commits on --source whose message matches this regular expression")
+	source := fs.String("source", "main", "Branch searched for commits matching --pattern")
+	sinceDays := fs.Int("since-days", 0, "Only search commits from the last this many days (0 = all)")
+	topic := fs.String("topic", "", "Cherry-pick in all projects of this topic")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	group := fs.String("group", "", "Default group/namespace prefix for --project and --commit (optional)")
+	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	titleTemplate := fs.String("title-template", "", "Go text/template for the MR title (with --branch)")
+	descriptionTemplate := fs.String("description-template", "", "Go text/template for the MR description (with --branch)")
+	issuePattern := fs.String("issue-pattern", changelog.DefaultIssuePattern.String(), "Regular expression for issue references in commit messages")
+	dryRun := fs.Bool("dry-run", false, "Check that the commits apply and report WOULD_PICK without committing")
+	junitReport := addJUnitFlag(fs)
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	var projects, commits, labels arrayFlags
+	fs.Var(&projects, "project", "Project path (can be repeated)")
+	fs.Var(&commits, "commit", "Commit to pick in a project, as <project>=<sha> (can be repeated)")
+	fs.Var(&labels, "label", "Label to set on created MRs (comma-separated or repeated)")
+
+	fs.Usage = func() {
+		fmt.Println("Cherry-pick hotfix commits onto a branch across multiple projects")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools bulk-cherry-pick --target <branch> --pattern <regexp> --topic <topic>")
+		fmt.Println("  gitlab-tools bulk-cherry-pick --target <branch> --commit <project>=<sha> [--commit <project>=<sha>...]")
+		fmt.Println()
+		fmt.Println("Matching commits are picked oldest first. Commits the branch already has are")
+		fmt.Println("skipped, and the first conflict stops a project. With --branch the commits go")
+		fmt.Println("onto a new branch and a merge request into --target is opened for it.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Check which projects the OPS-42 fix applies to")
+		fmt.Println("  gitlab-tools bulk-cherry-pick --target op-rc --pattern 'OPS-42' --topic backend --dry-run")
+		fmt.Println()
+		fmt.Println("  # Pick it through a merge request")
+		fmt.Println("  gitlab-tools bulk-cherry-pick --target op-rc --pattern 'OPS-42' --topic backend \\")
+		fmt.Println("    --branch hotfix/OPS-42")
+		fmt.Println()
+		fmt.Println("  # Pick a specific commit per project")
+		fmt.Println("  gitlab-tools bulk-cherry-pick --target op-rc --group mygroup \\")
+		fmt.Println("    --commit repo-a=4f2c9e1 --commit repo-b=a17d03b")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "Error: --target is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *pattern == "" && len(commits) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one of --pattern or --commit is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *topic != "" && len(projects) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: --topic and --project cannot be combined")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *topic == "" && len(projects) == 0 && len(commits) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: one of --topic, --project or --commit is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *branch == *target {
+		fmt.Fprintln(os.Stderr, "Error: --branch must differ from --target")
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	shas := make(map[string]string)
+	var commitProjects []string
+	for _, value := range commits {
+		project, sha, ok := strings.Cut(value, "=")
+		if !ok || project == "" || sha == "" {
+			fmt.Fprintf(os.Stderr, "Error: invalid --commit %q, expected <project>=<sha>\n", value)
+			os.Exit(1)
+		}
+		if *group != "" && !strings.Contains(project, "/") {
+			project = fmt.Sprintf("%s/%s", *group, project)
+		}
+		if _, seen := shas[project]; !seen {
+			commitProjects = append(commitProjects, project)
+		}
+		shas[project] = sha
+	}
+
+	var matcher *regexp.Regexp
+	if *pattern != "" {
+		var err error
+		if matcher, err = regexp.Compile(*pattern); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --pattern: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	issues, err := regexp.Compile(*issuePattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --issue-pattern: %v\n", err)
+		os.Exit(1)
+	}
+
+	var templates *bulkmr.Templates
+	if *branch != "" {
+		if templates, err = bulkmr.LoadTemplates(*titleTemplate, *descriptionTemplate, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *verbose {
+		log.SetFlags(log.Ltime)
+	} else {
+		log.SetFlags(0)
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+	startedAt := time.Now()
+
+	var projectPaths []string
+	if *topic != "" || len(projects) > 0 {
+		projectPaths, err = resolveProjectPaths(ctx, p, client, *topic, projects, *group, *perPage)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+			exitIfCanceled(ctx)
+			os.Exit(exitCodeForError(err))
+		}
+	}
+	for _, project := range commitProjects {
+		if !slices.Contains(projectPaths, project) {
+			projectPaths = append(projectPaths, project)
+		}
+	}
+
+	if len(projectPaths) == 0 {
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.cherryPickReport(nil, cherrypick.Summary{}, nil)
+		}
+		writeJUnitReport(*junitReport, report.CherryPickSuite(fs.Name(), *target, startedAt, nil))
+		os.Exit(0)
+	}
+
+	onto := *target
+	if *branch != "" {
+		onto = *branch
+	}
+	p.statusf("Cherry-picking onto %s in %d project(s)...\n\n", p.paint("1;36", onto), len(projectPaths))
+
+	config := cherrypick.Config{
+		Target:      *target,
+		Branch:      *branch,
+		Projects:    projectPaths,
+		SHAs:        shas,
+		Pattern:     matcher,
+		Source:      *source,
+		DryRun:      *dryRun,
+		Verbose:     *verbose,
+		Concurrency: *concurrency,
+		Progress:    p.cherryPickProgress,
+	}
+	if *sinceDays > 0 {
+		config.Since = startedAt.AddDate(0, 0, -*sinceDays)
+	}
+
+	results, summary := cherrypick.NewService(client, config).Run(ctx)
+
+	// Projects whose branch already had the change are included, so a rerun
+	// opens the merge requests a failed run did not get to.
+	var mrs *bulkMRReport
+	var ready []string
+	for _, result := range results {
+		if result.Status == cherrypick.StatusPicked || result.Status == cherrypick.StatusSkippedApplied {
+			ready = append(ready, result.Project)
+		}
+	}
+	if *branch != "" && !*dryRun && len(ready) > 0 && ctx.Err() == nil {
+		p.statusf("\nOpening merge requests from %s into %s in %d project(s)...\n\n", *branch, *target, len(ready))
+
+		mrResults, mrSummary := bulkmr.NewService(client, bulkmr.Config{
+			OriginBranch: *branch,
+			TargetBranch: *target,
+			Projects:     ready,
+			Verbose:      *verbose,
+			Concurrency:  *concurrency,
+			Templates:    templates,
+			Run:          bulkmr.RunInfo{StartedAt: startedAt, Version: version, Topic: *topic},
+			Changelog:    true,
+			IssuePattern: issues,
+			Labels:       splitList(labels),
+			Progress:     p.bulkMRProgress,
+		}).ProcessProjects(ctx)
+		mrs = &bulkMRReport{Results: mrResults, Summary: mrSummary}
+	}
+
+	p.cherryPickReport(results, summary, mrs)
+
+	suites := []report.Suite{report.CherryPickSuite(fs.Name(), onto, startedAt, results)}
+	if mrs != nil {
+		suites = append(suites, report.BulkMRSuite(fs.Name()+"-mr", *target, startedAt, mrs.Results))
+	}
+	writeJUnitReport(*junitReport, suites...)
+
+	exitIfCanceled(ctx)
+
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	case summary.NotFound > 0:
+		os.Exit(exitNotFound)
+	}
+	if mrs != nil {
+		exitForSummary(mrs.Summary)
+	}
+}
+
+func branchesCommand(ctx context.Context) {
+	if len(os.Args) < 3 || os.Args[2] != "prune" {
+		fmt.Fprintln(os.Stderr, "Usage: gitlab-tools branches prune [options]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("branches-prune", flag.ExitOnError)
+
+	topic := fs.String("topic", "", "Topic to filter projects (required)")
+	target := fs.String("target", "", "Prune branches merged into this branch")
+	merged := fs.Bool("merged", false, "Prune branches merged into each project's default branch")
+	staleDays := fs.Int("stale-days", 0, "Prune branches without commits in this many days (0 = off)")
+	yes := fs.Bool("yes", false, "Delete the planned branches without prompting")
+	dryRun := fs.Bool("dry-run", false, "Only show the plan, delete nothing")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	fs.Usage = func() {
+		fmt.Println("Delete merged or stale branches in every project of a topic")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools branches prune --topic <topic> [--target <branch> | --merged] [--stale-days <n>] [--yes | --dry-run]")
+		fmt.Println()
+		fmt.Println("Protected and default branches, the target and branches with an open MR are")
+		fmt.Println("never deleted. The plan is shown first and branches are only deleted after")
+		fmt.Println("confirmation or with --yes.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Review and delete feature branches merged into op-stage")
+		fmt.Println("  gitlab-tools branches prune --topic backend --target op-stage")
+		fmt.Println()
+		fmt.Println("  # Delete merged branches and branches idle for 90 days, without prompting")
+		fmt.Println("  gitlab-tools branches prune --topic backend --merged --stale-days 90 --yes")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[3:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: --topic is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *target == "" && !*merged && *staleDays <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one of --target, --merged or --stale-days is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *yes && *dryRun {
+		fmt.Fprintln(os.Stderr, "Error: --yes and --dry-run cannot be combined")
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *verbose {
+		log.SetFlags(log.Ltime)
+	} else {
+		log.SetFlags(0)
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+
+	p.statusf("Fetching projects for topic: %s\n\n", p.paint("1;35", *topic))
+
+	projects, err := client.ListAllProjectsByTopic(ctx, *topic, *perPage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
+	}
+
+	if len(projects) == 0 {
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.pruneReport(nil, prune.Summary{})
+		}
+		os.Exit(0)
+	}
+
+	p.statusf("Planning in %d project(s)...\n\n", len(projects))
+
+	service := prune.NewService(client, prune.Config{
+		Projects:    projects,
+		Merged:      *target != "" || *merged,
+		Target:      *target,
+		StaleAfter:  time.Duration(*staleDays) * 24 * time.Hour,
+		Verbose:     *verbose,
+		Concurrency: *concurrency,
+		Progress:    p.pruneProgress,
+	})
+
+	results, summary := service.Plan(ctx)
+	exitIfCanceled(ctx)
+
+	if summary.Branches > 0 {
+		p.prunePlan(results, summary)
+	}
+
+	proceed := summary.Branches > 0 && !*dryRun
+	if proceed && !*yes {
+		p.statusf("%s", p.paint("1;33", fmt.Sprintf("Delete %d branch(es)? (y/n): ", summary.Branches)))
+
+		answer, ok := prompt(ctx, readLines(os.Stdin))
+		if !ok {
+			p.statusf("\n")
+		}
+		response := strings.ToLower(strings.TrimSpace(answer))
+		proceed = response == "y" || response == "yes"
+	}
+
+	if proceed {
+		p.statusf("\nDeleting branches...\n\n")
+		results, summary = service.Prune(ctx, results)
+	}
+
+	p.pruneReport(results, summary)
+
+	exitIfCanceled(ctx)
+
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	}
+}
+
+func statusCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+
+	topic := fs.String("topic", "", "Topic to filter projects (required)")
+	chain := fs.String("chain", "develop,op-stage,op-rc,main", "Environment branches in promotion order (comma-separated)")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	perPage := fs.Int("per-page", 100, "Number of projects to fetch per page")
+	concurrency := fs.Int("concurrency", 4, "Number of projects to process in parallel")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	fs.Usage = func() {
+		fmt.Println("Show pending changes between environment branches for all projects in a topic")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools status --topic <topic> [--chain develop,op-stage,op-rc,main]")
+		fmt.Println()
+		fmt.Println("Each branch of the chain is compared with the next. Cells show the commits the")
+		fmt.Println("branch is ahead (↑) and behind (↓) the next one, \"=\" when they match, missing")
+		fmt.Println("branches and the open MRs (!iid) between them.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # What is waiting to be promoted in the backend services?")
+		fmt.Println("  gitlab-tools status --topic backend")
+		fmt.Println()
+		fmt.Println("  # Feed a dashboard")
+		fmt.Println("  gitlab-tools status --topic backend --chain op-stage,op-rc,main --output json")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: --topic is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	branches := splitList([]string{*chain})
+	if len(branches) < 2 {
+		fmt.Fprintln(os.Stderr, "Error: --chain needs at least two branches")
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *verbose {
+		log.SetFlags(log.Ltime)
+	} else {
+		log.SetFlags(0)
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+
+	p.statusf("Fetching projects for topic: %s\n\n", p.paint("1;35", *topic))
+
+	projects, err := client.ListAllProjectsByTopic(ctx, *topic, *perPage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		exitIfCanceled(ctx)
+		os.Exit(exitCodeForError(err))
+	}
+
+	if len(projects) == 0 {
+		p.statusf("No projects found for topic: %s\n", *topic)
+		if !p.table() {
+			p.statusMatrix(branches, nil, divergence.Summary{})
+		}
+		os.Exit(0)
+	}
+
+	p.statusf("Comparing %s in %d project(s)...\n\n", strings.Join(branches, " → "), len(projects))
+
+	service := divergence.NewService(client, divergence.Config{
+		Projects:    projects,
+		Chain:       branches,
+		Concurrency: *concurrency,
+		Progress:    p.statusProgress,
+	})
+
+	results, summary := service.Run(ctx)
+	p.statusMatrix(branches, results, summary)
+
+	exitIfCanceled(ctx)
+
+	switch {
+	case summary.Unauthorized > 0:
+		os.Exit(exitUnauthorized)
+	case summary.Errors > 0:
+		os.Exit(1)
+	}
+}
+
+func mergeCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+
+	target := fs.String("target", "", "Target branch to merge into (required unless --target-pattern is set)")
+	topic := fs.String("topic", "", "Topic to filter projects (required)")
+	yes := fs.Bool("yes", false, "Merge without prompting every MR that passes the policy (pipeline succeeded, approvals met, no conflicts, not draft)")
+	auto := fs.Bool("auto", false, "Alias for --yes")
+	sourcePattern := fs.String("source-pattern", "", "Only merge MRs whose source branch matches this regular expression")
+	targetPattern := fs.String("target-pattern", "", "Only merge MRs whose target branch matches this regular expression")
+	requirePipeline := fs.Bool("require-pipeline-success", false, "Skip MRs whose head pipeline has not succeeded (always on with --yes)")
+	requireApprovals := fs.Bool("require-approvals", false, "Skip MRs that still need approvals (always on with --yes)")
+	autoMerge := fs.Bool("merge-when-pipeline-succeeds", false, "Set MRs with a running pipeline to merge automatically once it succeeds")
+	squash := fs.Bool("squash", false, "Squash the commits of each MR into one when merging")
+	removeSource := fs.Bool("remove-source-branch", false, "Delete the source branch after merging")
+	mergeCommitMessage := fs.String("merge-commit-message", "", "Go text/template for the merge commit message (fields: .Project, .MergeRequest)")
+	squashCommitMessage := fs.String("squash-commit-message", "", "Go text/template for the squash commit message, used with --squash")
+	shaGuard := fs.Bool("sha-guard", true, "Refuse the merge if the source branch moved after the MR was checked")
+	rebase := fs.Bool("rebase", false, "Rebase MRs that are behind the target and wait for their new pipeline before merging")
+	pipelineTimeout := fs.Duration("pipeline-timeout", 30*time.Minute, "How long to wait for the pipeline of a rebased MR")
+	mergeTimeout := fs.Duration("merge-timeout", 10*time.Minute, "How long to wait for GitLab to finish each merge")
+	train := fs.Bool("train", false, "Add MRs to the target's merge train instead of merging them, then wait until each merged or was dropped")
+	trainTimeout := fs.Duration("train-timeout", time.Hour, "How long to wait for each MR on a merge train")
+	orderFile := fs.String("order-file", "", "JSON file declaring which projects must be merged before others (\"after:<project>\" topics are always honored)")
+	junitReport := addJUnitFlag(fs)
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	fs.Usage = func() {
+		fmt.Println("Interactively merge open, non-draft MRs targeting a branch across a topic")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools merge --target <branch> --topic <topic> [--yes]")
+		fmt.Println("  gitlab-tools merge --target-pattern <regexp> --topic <topic> [--yes]")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Merge release MRs into main from CI, without prompts")
+		fmt.Println("  gitlab-tools merge --target main --topic backend --yes --source-pattern '^release/'")
+		fmt.Println()
+		fmt.Println("  # Merge hotfixes into any release branch")
+		fmt.Println("  gitlab-tools merge --target-pattern '^release/' --topic backend --yes --source-pattern '^hotfix/'")
+		fmt.Println()
+		fmt.Println("  # Squash, delete the source branch and use a custom squash commit message")
+		fmt.Println("  gitlab-tools merge --target main --topic backend --squash --remove-source-branch \\")
+		fmt.Println("    --squash-commit-message '{{.MergeRequest.Title}} (!{{.MergeRequest.IID}})'")
+		fmt.Println()
+		fmt.Println("  # Merge shared libraries before the services that depend on them")
+		fmt.Println("  gitlab-tools merge --target main --topic backend --yes --order-file merge-order.json")
+		fmt.Println()
+		fmt.Println("  # Queue all approved MRs on the merge train of main and wait for them")
+		fmt.Println("  gitlab-tools merge --target main --topic backend --yes --train")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if (*target == "" && *targetPattern == "") || *topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: --topic and one of --target or --target-pattern are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Merge trains merge with the MR's own settings and test the merged
+	// result, so these options have nothing to act on.
+	if *train && (*rebase || *removeSource || *mergeCommitMessage != "" || *squashCommitMessage != "") {
+		fmt.Fprintln(os.Stderr, "Error: --train cannot be combined with --rebase, --remove-source-branch, --merge-commit-message or --squash-commit-message")
+		os.Exit(1)
+	}
+
+	var policy merge.Policy
+	if *sourcePattern != "" {
+		pattern, err := regexp.Compile(*sourcePattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --source-pattern: %v\n", err)
+			os.Exit(1)
+		}
+		policy.SourcePattern = pattern
+	}
+	if *targetPattern != "" {
+		pattern, err := regexp.Compile(*targetPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --target-pattern: %v\n", err)
+			os.Exit(1)
+		}
+		policy.TargetPattern = pattern
+	}
+
+	messages, err := merge.NewMessages(*mergeCommitMessage, *squashCommitMessage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var dependencies merge.Dependencies
+	if *orderFile != "" {
+		if dependencies, err = merge.LoadDependencies(*orderFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	policy.RequirePipelineSuccess = *requirePipeline
+	policy.RequireApprovals = *requireApprovals
+
+	nonInteractive := *yes || *auto
+	if nonInteractive {
+		policy.RequirePipelineSuccess = true
+		policy.RequireApprovals = true
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+
+	p.statusf("%s\n", p.paint("36", "📦 Fetching projects for topic: "+*topic))
+	projects, err := client.ListAllProjectsByTopic(ctx, *topic, 0)
+	if err != nil {
+		exitIfCanceled(ctx)
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		os.Exit(exitCodeForError(err))
+	}
+
+	if len(projects) == 0 {
+		p.statusf("%s\n", p.paint("33", "⚠️  No projects found for topic: "+*topic))
+		if !p.table() {
+			p.mergeReport(nil, merge.Summary{})
+		}
+		writeJUnitReport(*junitReport, report.MergeSuite(*target, time.Now(), nil))
+		return
+	}
+
+	p.statusf("%s\n\n", p.paint("32", fmt.Sprintf("✓ Found %d projects", len(projects))))
+
+	projects, dependencies, err = merge.OrderProjects(projects, dependencies)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(dependencies) > 0 {
+		p.mergeOrder(projects, dependencies)
+	}
+
+	config := merge.Config{
+		TargetBranch:              *target,
+		Projects:                  projects,
+		Dependencies:              dependencies,
+		Policy:                    policy,
+		MergeWhenPipelineSucceeds: *autoMerge,
+		Squash:                    *squash,
+		RemoveSourceBranch:        *removeSource,
+		SHAGuard:                  *shaGuard,
+		Messages:                  messages,
+		Rebase:                    *rebase,
+		PipelineTimeout:           *pipelineTimeout,
+		MergeTimeout:              *mergeTimeout,
+		Train:                     *train,
+		TrainTimeout:              *trainTimeout,
+		Progress:                  p.mergeProgress,
+	}
+
+	var tracking bool
+	config.Tracked = func(result merge.Result) {
+		if !tracking {
+			tracking = true
+			p.mergeTrainStart()
+		}
+		p.mergeAutoProgress(result)
+	}
+
+	if nonInteractive {
+		config.Progress = p.mergeAutoProgress
+	} else {
+		answers := readLines(os.Stdin)
+		config.Confirm = func(ctx context.Context, candidate merge.Candidate) (bool, error) {
+			p.mergeCandidate(candidate)
+			p.statusf("%s", p.paint("1;33", "Merge this MR? (y/n): "))
+
+			answer, ok := prompt(ctx, answers)
+			if !ok {
+				p.statusf("\n")
+				return false, errNoAnswer
+			}
+
+			response := strings.ToLower(strings.TrimSpace(answer))
+			return response == "y" || response == "yes", nil
+		}
+	}
+
+	service := merge.NewService(client, config)
+
+	startedAt := time.Now()
+	results, summary := service.Run(ctx)
+	if *train && p.table() {
+		p.mergeTrainReport(results)
+	}
+	p.mergeReport(results, summary)
+
+	writeJUnitReport(*junitReport, report.MergeSuite(*target, startedAt, results))
+
+	exitIfCanceled(ctx)
+
+	if summary.Unauthorized > 0 {
+		os.Exit(exitUnauthorized)
+	}
+
+	if summary.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func approveCommand(ctx context.Context) {
+	fs := flag.NewFlagSet("approve", flag.ExitOnError)
+
+	target := fs.String("target", "", "Target branch of the MRs to approve (required)")
+	topic := fs.String("topic", "", "Topic to filter projects (required)")
+	source := fs.String("source", "", "Only approve MRs from this source branch")
+	yes := fs.Bool("yes", false, "Approve without showing diffs or prompting")
+	unapprove := fs.Bool("unapprove", false, "Revoke your approval instead of approving (no prompts)")
+	maxDiffLines := fs.Int("max-diff-lines", 200, "Maximum diff lines shown per MR (0 = no limit)")
+	gitlabURL := fs.String("gitlab-url", os.Getenv("GITLAB_BASE_URL"), "GitLab base URL (default: GITLAB_BASE_URL env)")
+	token := fs.String("token", os.Getenv("GITLAB_TOKEN"), "GitLab API token (default: GITLAB_TOKEN env)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	retry := addRetryFlags(fs)
+	timeout := fs.Duration("timeout", 0, "Overall deadline for the command (0 = no limit)")
+	outputFormat := addOutputFlag(fs)
+
+	fs.Usage = func() {
+		fmt.Println("Show the diff of every open, non-draft MR targeting a branch across a topic and approve it")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gitlab-tools approve --target <branch> --topic <topic> [--source <branch>] [--yes]")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  # Review and approve the stage -> main promotion MRs")
+		fmt.Println("  gitlab-tools approve --target main --source stage --topic backend")
+		fmt.Println()
+		fmt.Println("Environment Variables:")
+		fmt.Println("  GITLAB_BASE_URL    GitLab instance base URL (e.g., https://gitlab.example.com)")
+		fmt.Println("  GITLAB_TOKEN       Personal access token for GitLab API")
+	}
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+
+	p := mustPrinter(*outputFormat)
+
+	if *target == "" || *topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: both --target and --topic are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *gitlabURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab URL must be provided via --gitlab-url or GITLAB_BASE_URL env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Error: GitLab token must be provided via --token or GITLAB_TOKEN env")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := newClient(*gitlabURL, *token, *verbose, retry)
+
+	p.statusf("%s\n", p.paint("36", "📦 Fetching projects for topic: "+*topic))
+	projects, err := client.ListAllProjectsByTopic(ctx, *topic, 0)
+	if err != nil {
+		exitIfCanceled(ctx)
+		fmt.Fprintf(os.Stderr, "Error fetching projects: %v\n", err)
+		os.Exit(exitCodeForError(err))
+	}
+
+	if len(projects) == 0 {
+		p.statusf("%s\n", p.paint("33", "⚠️  No projects found for topic: "+*topic))
+		if !p.table() {
+			p.approveReport(nil, approve.Summary{})
+		}
+		return
+	}
+
+	p.statusf("%s\n\n", p.paint("32", fmt.Sprintf("✓ Found %d projects", len(projects))))
+
+	config := approve.Config{
+		TargetBranch: *target,
+		SourceBranch: *source,
+		Projects:     projects,
+		Unapprove:    *unapprove,
+		Progress:     p.approveAutoProgress,
+	}
+
+	if !*yes && !*unapprove {
+		config.Progress = p.approveProgress
+		answers := readLines(os.Stdin)
+		config.Confirm = func(ctx context.Context, candidate approve.Candidate) (bool, error) {
+			p.approveCandidate(candidate, *maxDiffLines)
+			p.statusf("%s", p.paint("1;33", "Approve this MR? (y/n): "))
+
+			answer, ok := prompt(ctx, answers)
+			if !ok {
+				p.statusf("\n")
+				return false, errNoAnswer
+			}
+
+			response := strings.ToLower(strings.TrimSpace(answer))
+			return response == "y" || response == "yes", nil
+		}
+	}
+
+	service := approve.NewService(client, config)
+
+	results, summary := service.Run(ctx)
+	p.approveReport(results, summary)
+
+	exitIfCanceled(ctx)
+
+	if summary.Unauthorized > 0 {
+		os.Exit(exitUnauthorized)
+	}
+
+	if summary.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+var errNoAnswer = errors.New("no answer: input closed or run canceled")
+
+// readLines feeds stdin lines into a channel so prompts can be abandoned
+// when the context is canceled instead of blocking on a read.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func prompt(ctx context.Context, lines <-chan string) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	case line, ok := <-lines:
+		return line, ok
 	}
 }
@@ -0,0 +1,1333 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/approve"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkbranch"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkmr"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkrelease"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/cherrypick"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/divergence"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/merge"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/output"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/prune"
+)
+
+// printer writes a command's results in the selected format. Progress lines,
+// prompts and other chatter go to status, which is stderr for the
+// machine-readable formats so stdout only carries the document.
+type printer struct {
+	format output.Format
+	out    io.Writer
+	status io.Writer
+	colors output.Colors
+}
+
+func addOutputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", "table", "Output format: table, json, yaml or csv")
+}
+
+func newPrinter(value string) (*printer, error) {
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{format: format, out: os.Stdout, status: os.Stdout}
+	if format != output.FormatTable {
+		p.status = os.Stderr
+	}
+	p.colors = output.NewColors(p.status)
+
+	return p, nil
+}
+
+func mustPrinter(value string) *printer {
+	p, err := newPrinter(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --output: %v\n", err)
+		os.Exit(1)
+	}
+	return p
+}
+
+func (p *printer) paint(code, text string) string {
+	return p.colors.Paint(code, text)
+}
+
+func (p *printer) statusf(format string, args ...any) {
+	fmt.Fprintf(p.status, format, args...)
+}
+
+func (p *printer) table() bool {
+	return p.format == output.FormatTable
+}
+
+// document writes v as JSON or YAML, or header and rows as CSV.
+func (p *printer) document(v any, header []string, rows [][]string) {
+	var err error
+	if p.format == output.FormatCSV {
+		err = output.WriteCSV(p.out, header, rows)
+	} else {
+		err = output.Encode(p.out, p.format, v)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type bulkMRReport struct {
+	Results []bulkmr.ProjectResult `json:"results"`
+	Summary bulkmr.Summary         `json:"summary"`
+}
+
+func (p *printer) bulkMRProgress(done, total int, result bulkmr.ProjectResult) {
+	p.statusf("[%d/%d] %s %s %s\n", done, total, result.Project, getStatusIcon(result.Status), result.Status)
+}
+
+func (p *printer) bulkMRReport(results []bulkmr.ProjectResult, summary bulkmr.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []bulkmr.ProjectResult{}
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				result.Project,
+				string(result.Status),
+				optionalInt(result.MergeRequestID),
+				optionalInt(result.MergeRequestIID),
+				result.MergeRequestURL,
+				optionalInt(result.Commits),
+				result.Blocker,
+				result.Details,
+				result.ErrorMessage,
+			})
+		}
+
+		header := []string{"project", "status", "merge_request_id", "merge_request_iid", "merge_request_url", "commits", "blocker", "details", "error"}
+		p.document(bulkMRReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	for _, result := range results {
+		p.bulkMRResult(result)
+	}
+
+	fmt.Fprintln(p.out)
+	p.bulkMRSummary(summary)
+}
+
+func (p *printer) bulkMRResult(result bulkmr.ProjectResult) {
+	statusIcon := getStatusIcon(result.Status)
+	fmt.Fprintf(p.out, "[%s] %s %s\n", result.Project, statusIcon, result.Status)
+
+	if result.Details != "" {
+		fmt.Fprintf(p.out, "  %s\n", result.Details)
+	}
+
+	if result.Status == bulkmr.StatusCreated && result.Blocker != "" {
+		fmt.Fprintf(p.out, "  ⚠ Cannot be merged as it is: %s\n", result.Blocker)
+	}
+
+	if result.ErrorMessage != "" {
+		fmt.Fprintf(p.out, "  Error: %s\n", result.ErrorMessage)
+	}
+
+	fmt.Fprintln(p.out)
+}
+
+func getStatusIcon(status bulkmr.ResultStatus) string {
+	switch status {
+	case bulkmr.StatusCreated:
+		return "✓"
+	case bulkmr.StatusWouldCreate:
+		return "+"
+	case bulkmr.StatusSkippedExists:
+		return "→"
+	case bulkmr.StatusSkippedDraft:
+		return "⊘"
+	case bulkmr.StatusSkippedBranch:
+		return "⚠"
+	case bulkmr.StatusSkippedNoChange:
+		return "≡"
+	case bulkmr.StatusConflict:
+		return "⚔"
+	case bulkmr.StatusNotMergeable:
+		return "⊠"
+	case bulkmr.StatusNotFound:
+		return "∅"
+	case bulkmr.StatusUnauthorized:
+		return "⛔"
+	case bulkmr.StatusError:
+		return "✗"
+	case bulkmr.StatusCanceled:
+		return "⊗"
+	default:
+		return "?"
+	}
+}
+
+func (p *printer) bulkMRSummary(summary bulkmr.Summary) {
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Created: %d\n", summary.Created)
+	if summary.Blocked > 0 {
+		fmt.Fprintf(p.out, "  Created, not mergeable as is: %d\n", summary.Blocked)
+	}
+	if summary.WouldCreate > 0 {
+		fmt.Fprintf(p.out, "  Would create: %d\n", summary.WouldCreate)
+	}
+	fmt.Fprintf(p.out, "  Skipped (exists): %d\n", summary.SkippedExists)
+	fmt.Fprintf(p.out, "  Skipped (draft): %d\n", summary.SkippedDraft)
+	fmt.Fprintf(p.out, "  Skipped (no changes): %d\n", summary.SkippedNoChange)
+	fmt.Fprintf(p.out, "  Skipped (no branch): %d\n", summary.SkippedBranch)
+	if summary.Conflicts > 0 {
+		fmt.Fprintf(p.out, "  Conflicts: %d\n", summary.Conflicts)
+	}
+	if summary.NotMergeable > 0 {
+		fmt.Fprintf(p.out, "  Not mergeable: %d\n", summary.NotMergeable)
+	}
+	if summary.NotFound > 0 {
+		fmt.Fprintf(p.out, "  Not found: %d\n", summary.NotFound)
+	}
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+	if summary.Canceled > 0 {
+		fmt.Fprintf(p.out, "  Canceled: %d\n", summary.Canceled)
+	}
+	fmt.Fprintln(p.out)
+
+	switch {
+	case summary.Canceled > 0:
+		fmt.Fprintln(p.out, "✗ Canceled before completion")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.WouldCreate > 0:
+		fmt.Fprintln(p.out, "✓ Dry run completed, no merge requests were created")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.Conflicts+summary.NotMergeable+summary.Blocked > 0:
+		fmt.Fprintln(p.out, "⚠ Completed, but some merge requests cannot be merged as they are")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0:
+		fmt.Fprintln(p.out, "✓ Completed successfully")
+	default:
+		fmt.Fprintln(p.out, "✗ Completed with errors")
+	}
+}
+
+type bulkBranchReport struct {
+	Results []bulkbranch.ProjectResult `json:"results"`
+	Summary bulkbranch.Summary         `json:"summary"`
+}
+
+func (p *printer) bulkBranchProgress(done, total int, result bulkbranch.ProjectResult) {
+	p.statusf("[%d/%d] %s %s %s\n", done, total, result.Project, branchStatusIcon(result.Status), result.Status)
+}
+
+func (p *printer) bulkBranchReport(results []bulkbranch.ProjectResult, summary bulkbranch.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []bulkbranch.ProjectResult{}
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				result.Project,
+				string(result.Status),
+				result.Branch,
+				result.SHA,
+				result.BranchURL,
+				result.Details,
+				result.ErrorMessage,
+			})
+		}
+
+		header := []string{"project", "status", "branch", "sha", "branch_url", "details", "error"}
+		p.document(bulkBranchReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	for _, result := range results {
+		fmt.Fprintf(p.out, "[%s] %s %s\n", result.Project, branchStatusIcon(result.Status), result.Status)
+		if result.Details != "" {
+			fmt.Fprintf(p.out, "  %s\n", result.Details)
+		}
+		if result.ErrorMessage != "" {
+			fmt.Fprintf(p.out, "  Error: %s\n", result.ErrorMessage)
+		}
+		fmt.Fprintln(p.out)
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Created: %d\n", summary.Created)
+	fmt.Fprintf(p.out, "  Skipped (exists): %d\n", summary.SkippedExists)
+	fmt.Fprintf(p.out, "  Skipped (no ref): %d\n", summary.SkippedNoRef)
+	if summary.Conflicts > 0 {
+		fmt.Fprintf(p.out, "  Conflicts: %d\n", summary.Conflicts)
+	}
+	if summary.NotFound > 0 {
+		fmt.Fprintf(p.out, "  Not found: %d\n", summary.NotFound)
+	}
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+	if summary.Canceled > 0 {
+		fmt.Fprintf(p.out, "  Canceled: %d\n", summary.Canceled)
+	}
+	fmt.Fprintln(p.out)
+
+	switch {
+	case summary.Canceled > 0:
+		fmt.Fprintln(p.out, "✗ Canceled before completion")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.Conflicts > 0:
+		fmt.Fprintln(p.out, "⚠ Completed, but some projects already have the branch at another commit")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0:
+		fmt.Fprintln(p.out, "✓ Completed successfully")
+	default:
+		fmt.Fprintln(p.out, "✗ Completed with errors")
+	}
+}
+
+func branchStatusIcon(status bulkbranch.ResultStatus) string {
+	switch status {
+	case bulkbranch.StatusCreated:
+		return "✓"
+	case bulkbranch.StatusSkippedExists:
+		return "→"
+	case bulkbranch.StatusSkippedNoRef:
+		return "⚠"
+	case bulkbranch.StatusConflict:
+		return "⚔"
+	case bulkbranch.StatusNotFound:
+		return "∅"
+	case bulkbranch.StatusUnauthorized:
+		return "⛔"
+	case bulkbranch.StatusError:
+		return "✗"
+	case bulkbranch.StatusCanceled:
+		return "⊗"
+	default:
+		return "?"
+	}
+}
+
+type bulkReleaseReport struct {
+	Results []bulkrelease.ProjectResult `json:"results"`
+	Summary bulkrelease.Summary         `json:"summary"`
+}
+
+func (p *printer) bulkReleaseProgress(done, total int, result bulkrelease.ProjectResult) {
+	p.statusf("[%d/%d] %s %s %s\n", done, total, result.Project, releaseStatusIcon(result.Status), result.Status)
+}
+
+func (p *printer) bulkReleaseReport(results []bulkrelease.ProjectResult, summary bulkrelease.Summary, showNotes bool) {
+	if !p.table() {
+		if results == nil {
+			results = []bulkrelease.ProjectResult{}
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				result.Project,
+				string(result.Status),
+				result.Tag,
+				result.SHA,
+				result.PreviousTag,
+				optionalInt(result.Commits),
+				result.ReleaseURL,
+				result.Details,
+				result.ErrorMessage,
+			})
+		}
+
+		header := []string{"project", "status", "tag", "sha", "previous_tag", "commits", "release_url", "details", "error"}
+		p.document(bulkReleaseReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	for _, result := range results {
+		fmt.Fprintf(p.out, "[%s] %s %s\n", result.Project, releaseStatusIcon(result.Status), result.Status)
+		if result.Details != "" {
+			fmt.Fprintf(p.out, "  %s\n", result.Details)
+		}
+		if result.ReleaseURL != "" {
+			fmt.Fprintf(p.out, "  %s\n", result.ReleaseURL)
+		}
+		if result.ErrorMessage != "" {
+			fmt.Fprintf(p.out, "  Error: %s\n", result.ErrorMessage)
+		}
+		if showNotes && result.Notes != "" {
+			fmt.Fprintln(p.out)
+			for _, line := range strings.Split(result.Notes, "\n") {
+				fmt.Fprintln(p.out, strings.TrimRight("    "+line, " "))
+			}
+		}
+		fmt.Fprintln(p.out)
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Created: %d\n", summary.Created)
+	if summary.WouldCreate > 0 {
+		fmt.Fprintf(p.out, "  Would create: %d\n", summary.WouldCreate)
+	}
+	fmt.Fprintf(p.out, "  Skipped (exists): %d\n", summary.SkippedExists)
+	fmt.Fprintf(p.out, "  Skipped (no ref): %d\n", summary.SkippedNoRef)
+	if summary.Conflicts > 0 {
+		fmt.Fprintf(p.out, "  Conflicts: %d\n", summary.Conflicts)
+	}
+	if summary.NotFound > 0 {
+		fmt.Fprintf(p.out, "  Not found: %d\n", summary.NotFound)
+	}
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+	if summary.Canceled > 0 {
+		fmt.Fprintf(p.out, "  Canceled: %d\n", summary.Canceled)
+	}
+	fmt.Fprintln(p.out)
+
+	switch {
+	case summary.Canceled > 0:
+		fmt.Fprintln(p.out, "✗ Canceled before completion")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.WouldCreate > 0:
+		fmt.Fprintln(p.out, "✓ Dry run completed, no tags or releases were created")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.Conflicts > 0:
+		fmt.Fprintln(p.out, "⚠ Completed, but some projects already have the tag at another commit")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0:
+		fmt.Fprintln(p.out, "✓ Completed successfully")
+	default:
+		fmt.Fprintln(p.out, "✗ Completed with errors")
+	}
+}
+
+func releaseStatusIcon(status bulkrelease.ResultStatus) string {
+	switch status {
+	case bulkrelease.StatusCreated:
+		return "✓"
+	case bulkrelease.StatusWouldCreate:
+		return "+"
+	case bulkrelease.StatusSkippedExists:
+		return "→"
+	case bulkrelease.StatusSkippedNoRef:
+		return "⚠"
+	case bulkrelease.StatusConflict:
+		return "⚔"
+	case bulkrelease.StatusNotFound:
+		return "∅"
+	case bulkrelease.StatusUnauthorized:
+		return "⛔"
+	case bulkrelease.StatusError:
+		return "✗"
+	case bulkrelease.StatusCanceled:
+		return "⊗"
+	default:
+		return "?"
+	}
+}
+
+type cherryPickReport struct {
+	Results       []cherrypick.ProjectResult `json:"results"`
+	Summary       cherrypick.Summary         `json:"summary"`
+	MergeRequests *bulkMRReport              `json:"merge_requests,omitempty"`
+}
+
+func (p *printer) cherryPickProgress(done, total int, result cherrypick.ProjectResult) {
+	p.statusf("[%d/%d] %s %s %s\n", done, total, result.Project, cherryPickStatusIcon(result.Status), result.Status)
+}
+
+// cherryPickReport prints the picks and, when the commits went onto a new
+// branch, the merge requests opened for it.
+func (p *printer) cherryPickReport(results []cherrypick.ProjectResult, summary cherrypick.Summary, mrs *bulkMRReport) {
+	if !p.table() {
+		if results == nil {
+			results = []cherrypick.ProjectResult{}
+		}
+
+		mrURLs := make(map[string]string)
+		if mrs != nil {
+			for _, mr := range mrs.Results {
+				mrURLs[mr.Project] = mr.MergeRequestURL
+			}
+		}
+
+		// One row per commit, and one for projects without any.
+		var rows [][]string
+		for _, result := range results {
+			if len(result.Commits) == 0 {
+				rows = append(rows, []string{result.Project, string(result.Status), result.Branch, "", "", "", "", mrURLs[result.Project], result.Details, result.ErrorMessage})
+				continue
+			}
+			for _, commit := range result.Commits {
+				rows = append(rows, []string{
+					result.Project,
+					string(result.Status),
+					result.Branch,
+					commit.SHA,
+					commit.Title,
+					commit.Outcome,
+					commit.PickedSHA,
+					mrURLs[result.Project],
+					result.Details,
+					result.ErrorMessage,
+				})
+			}
+		}
+
+		header := []string{"project", "status", "branch", "sha", "title", "outcome", "picked_sha", "merge_request_url", "details", "error"}
+		p.document(cherryPickReport{Results: results, Summary: summary, MergeRequests: mrs}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	for _, result := range results {
+		fmt.Fprintf(p.out, "[%s] %s %s\n", result.Project, cherryPickStatusIcon(result.Status), result.Status)
+		if result.Details != "" {
+			fmt.Fprintf(p.out, "  %s\n", result.Details)
+		}
+		for _, commit := range result.Commits {
+			fmt.Fprintf(p.out, "  - %s %s (%s)\n", shortSHA(commit.SHA), truncateText(commit.Title, 60), commit.Outcome)
+		}
+		if result.ErrorMessage != "" {
+			fmt.Fprintf(p.out, "  Error: %s\n", result.ErrorMessage)
+		}
+		fmt.Fprintln(p.out)
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Picked: %d\n", summary.Picked)
+	if summary.WouldPick > 0 {
+		fmt.Fprintf(p.out, "  Would pick: %d\n", summary.WouldPick)
+	}
+	fmt.Fprintf(p.out, "  Skipped (already applied): %d\n", summary.SkippedApplied)
+	fmt.Fprintf(p.out, "  Skipped (no commit): %d\n", summary.SkippedNoCommit)
+	fmt.Fprintf(p.out, "  Skipped (no branch): %d\n", summary.SkippedBranch)
+	if summary.Conflicts > 0 {
+		fmt.Fprintf(p.out, "  Conflicts: %d\n", summary.Conflicts)
+	}
+	if summary.NotFound > 0 {
+		fmt.Fprintf(p.out, "  Not found: %d\n", summary.NotFound)
+	}
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+	if summary.Canceled > 0 {
+		fmt.Fprintf(p.out, "  Canceled: %d\n", summary.Canceled)
+	}
+	fmt.Fprintln(p.out)
+
+	switch {
+	case summary.Canceled > 0:
+		fmt.Fprintln(p.out, "✗ Canceled before completion")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.Conflicts > 0:
+		fmt.Fprintln(p.out, "⚠ Completed, but some projects need the commits picked by hand")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0 && summary.WouldPick > 0:
+		fmt.Fprintln(p.out, "✓ Dry run completed, nothing was cherry-picked")
+	case summary.Errors == 0 && summary.NotFound == 0 && summary.Unauthorized == 0:
+		fmt.Fprintln(p.out, "✓ Completed successfully")
+	default:
+		fmt.Fprintln(p.out, "✗ Completed with errors")
+	}
+
+	if mrs != nil {
+		fmt.Fprintln(p.out)
+		fmt.Fprintln(p.out, "Merge requests:")
+		p.bulkMRReport(mrs.Results, mrs.Summary)
+	}
+}
+
+func cherryPickStatusIcon(status cherrypick.ResultStatus) string {
+	switch status {
+	case cherrypick.StatusPicked:
+		return "✓"
+	case cherrypick.StatusWouldPick:
+		return "+"
+	case cherrypick.StatusSkippedApplied:
+		return "≡"
+	case cherrypick.StatusSkippedNoCommit:
+		return "→"
+	case cherrypick.StatusSkippedBranch:
+		return "⚠"
+	case cherrypick.StatusConflict:
+		return "⚔"
+	case cherrypick.StatusNotFound:
+		return "∅"
+	case cherrypick.StatusUnauthorized:
+		return "⛔"
+	case cherrypick.StatusError:
+		return "✗"
+	case cherrypick.StatusCanceled:
+		return "⊗"
+	default:
+		return "?"
+	}
+}
+
+type pruneReport struct {
+	Results []prune.ProjectResult `json:"results"`
+	Summary prune.Summary         `json:"summary"`
+}
+
+func (p *printer) pruneProgress(done, total int, result prune.ProjectResult) {
+	p.statusf("[%d/%d] %s %s %s\n", done, total, result.Project, pruneStatusIcon(result.Status), result.Status)
+}
+
+// prunePlan lists the branches selected in each project before anything is
+// deleted.
+func (p *printer) prunePlan(results []prune.ProjectResult, summary prune.Summary) {
+	fmt.Fprintln(p.status)
+	for _, result := range results {
+		if len(result.Branches) == 0 {
+			continue
+		}
+		fmt.Fprintf(p.status, "[%s] %d branch(es)\n", result.Project, len(result.Branches))
+		for _, branch := range result.Branches {
+			fmt.Fprintf(p.status, "  - %s %s (%s, last commit %s)\n",
+				branch.Name, shortSHA(branch.SHA), branch.Reason, branch.CommittedDate.Format("2006-01-02"))
+		}
+		fmt.Fprintln(p.status)
+	}
+
+	fmt.Fprintf(p.status, "%d branch(es) to delete in %d of %d project(s)\n", summary.Branches, summary.Planned, summary.Total)
+}
+
+func (p *printer) pruneReport(results []prune.ProjectResult, summary prune.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []prune.ProjectResult{}
+		}
+
+		// One row per branch, and one for projects without any.
+		var rows [][]string
+		for _, result := range results {
+			if len(result.Branches) == 0 {
+				rows = append(rows, []string{result.Project, string(result.Status), "", "", "", "", "", "", result.Details, result.ErrorMessage})
+				continue
+			}
+			for _, branch := range result.Branches {
+				errorMessage := branch.ErrorMessage
+				if errorMessage == "" {
+					errorMessage = result.ErrorMessage
+				}
+				rows = append(rows, []string{
+					result.Project,
+					string(result.Status),
+					branch.Name,
+					branch.SHA,
+					branch.CommittedDate.Format(time.RFC3339),
+					branch.Reason,
+					strconv.FormatBool(branch.Deleted),
+					branch.Kept,
+					result.Details,
+					errorMessage,
+				})
+			}
+		}
+
+		header := []string{"project", "status", "branch", "sha", "committed_date", "reason", "deleted", "kept", "details", "error"}
+		p.document(pruneReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	for _, result := range results {
+		fmt.Fprintf(p.out, "[%s] %s %s\n", result.Project, pruneStatusIcon(result.Status), result.Status)
+		if result.Details != "" {
+			fmt.Fprintf(p.out, "  %s\n", result.Details)
+		}
+		for _, branch := range result.Branches {
+			switch {
+			case branch.ErrorMessage != "":
+				fmt.Fprintf(p.out, "  ✗ %s: %s\n", branch.Name, branch.ErrorMessage)
+			case branch.Kept != "":
+				fmt.Fprintf(p.out, "  → %s kept: %s\n", branch.Name, branch.Kept)
+			}
+		}
+		if result.ErrorMessage != "" {
+			fmt.Fprintf(p.out, "  Error: %s\n", result.ErrorMessage)
+		}
+		fmt.Fprintln(p.out)
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Pruned: %d\n", summary.Pruned)
+	if summary.Planned > 0 {
+		fmt.Fprintf(p.out, "  Planned: %d\n", summary.Planned)
+	}
+	fmt.Fprintf(p.out, "  Nothing to prune: %d\n", summary.NothingToPrune)
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+	if summary.Canceled > 0 {
+		fmt.Fprintf(p.out, "  Canceled: %d\n", summary.Canceled)
+	}
+	fmt.Fprintf(p.out, "  Branches deleted: %d of %d\n", summary.Deleted, summary.Branches)
+	fmt.Fprintln(p.out)
+
+	switch {
+	case summary.Canceled > 0:
+		fmt.Fprintln(p.out, "✗ Canceled before completion")
+	case summary.Errors > 0 || summary.Unauthorized > 0:
+		fmt.Fprintln(p.out, "✗ Completed with errors")
+	case summary.Planned > 0:
+		fmt.Fprintln(p.out, "✓ Plan completed, no branches were deleted")
+	default:
+		fmt.Fprintln(p.out, "✓ Completed successfully")
+	}
+}
+
+func pruneStatusIcon(status prune.ResultStatus) string {
+	switch status {
+	case prune.StatusPruned:
+		return "✓"
+	case prune.StatusPlanned:
+		return "+"
+	case prune.StatusNothingToPrune:
+		return "≡"
+	case prune.StatusUnauthorized:
+		return "⛔"
+	case prune.StatusError:
+		return "✗"
+	case prune.StatusCanceled:
+		return "⊗"
+	default:
+		return "?"
+	}
+}
+
+type statusReport struct {
+	Chain    []string                   `json:"chain"`
+	Projects []divergence.ProjectStatus `json:"projects"`
+	Summary  divergence.Summary         `json:"summary"`
+}
+
+func (p *printer) statusProgress(done, total int, result divergence.ProjectStatus) {
+	p.statusf("[%d/%d] %s\n", done, total, result.Project)
+}
+
+// statusMatrix shows one row per project and one column per pair of the
+// chain, with the commits each branch is ahead (↑) and behind (↓) the next
+// and the open MRs (!iid) between them.
+func (p *printer) statusMatrix(chain []string, results []divergence.ProjectStatus, summary divergence.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []divergence.ProjectStatus{}
+		}
+
+		var rows [][]string
+		for _, result := range results {
+			if result.ErrorMessage != "" {
+				rows = append(rows, []string{result.Project, "", "", "", "", "", "", result.ErrorMessage})
+				continue
+			}
+			for _, pair := range result.Pairs {
+				var iids []string
+				for _, mr := range pair.MergeRequests {
+					iids = append(iids, strconv.Itoa(mr.IID))
+				}
+				rows = append(rows, []string{
+					result.Project,
+					pair.From,
+					pair.To,
+					strconv.Itoa(pair.Ahead),
+					strconv.Itoa(pair.Behind),
+					strings.Join(pair.Missing, ";"),
+					strings.Join(iids, ";"),
+					pair.ErrorMessage,
+				})
+			}
+		}
+
+		header := []string{"project", "from", "to", "ahead", "behind", "missing", "merge_requests", "error"}
+		p.document(statusReport{Chain: chain, Projects: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out)
+	w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+	fmt.Fprint(w, "PROJECT")
+	for i := 0; i+1 < len(chain); i++ {
+		fmt.Fprintf(w, "\t%s → %s", chain[i], chain[i+1])
+	}
+	fmt.Fprintln(w)
+
+	for _, result := range results {
+		fmt.Fprint(w, result.Project)
+		if result.ErrorMessage != "" {
+			fmt.Fprint(w, "\terror")
+		}
+		for _, pair := range result.Pairs {
+			fmt.Fprintf(w, "\t%s", statusCell(pair))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+
+	var problems []string
+	for _, result := range results {
+		if result.ErrorMessage != "" {
+			problems = append(problems, fmt.Sprintf("[%s] Error: %s", result.Project, result.ErrorMessage))
+		}
+		for _, pair := range result.Pairs {
+			if pair.ErrorMessage != "" {
+				problems = append(problems, fmt.Sprintf("[%s] %s → %s: %s", result.Project, pair.From, pair.To, pair.ErrorMessage))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintln(p.out)
+		for _, problem := range problems {
+			fmt.Fprintln(p.out, problem)
+		}
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "Summary:")
+	fmt.Fprintf(p.out, "  Total projects: %d\n", summary.Total)
+	fmt.Fprintf(p.out, "  Pending changes: %d\n", summary.Pending)
+	fmt.Fprintf(p.out, "  In sync: %d\n", summary.InSync)
+	if summary.Missing > 0 {
+		fmt.Fprintf(p.out, "  Missing branches: %d\n", summary.Missing)
+	}
+	fmt.Fprintf(p.out, "  Open MRs: %d\n", summary.MergeRequests)
+	if summary.Unauthorized > 0 {
+		fmt.Fprintf(p.out, "  Unauthorized: %d\n", summary.Unauthorized)
+	}
+	fmt.Fprintf(p.out, "  Errors: %d\n", summary.Errors)
+}
+
+func statusCell(pair divergence.Pair) string {
+	switch {
+	case len(pair.Missing) > 0:
+		return "no " + strings.Join(pair.Missing, ", ")
+	case pair.ErrorMessage != "":
+		return "error"
+	}
+
+	var parts []string
+	if pair.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", pair.Ahead))
+	}
+	if pair.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", pair.Behind))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "=")
+	}
+	for _, mr := range pair.MergeRequests {
+		parts = append(parts, fmt.Sprintf("!%d", mr.IID))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+type topicsReport struct {
+	Topics []gitlab.Topic `json:"topics"`
+}
+
+func (p *printer) topics(topics []gitlab.Topic) {
+	if !p.table() {
+		if topics == nil {
+			topics = []gitlab.Topic{}
+		}
+
+		rows := make([][]string, 0, len(topics))
+		for _, topic := range topics {
+			rows = append(rows, []string{
+				strconv.Itoa(topic.ID),
+				topic.Name,
+				topic.Title,
+				topic.Description,
+				strconv.Itoa(topic.TotalProjectsCount),
+			})
+		}
+
+		header := []string{"id", "name", "title", "description", "total_projects_count"}
+		p.document(topicsReport{Topics: topics}, header, rows)
+		return
+	}
+
+	fmt.Fprintf(p.out, "\n📚 %s\n\n", p.paint("1;35", "GitLab Topics"))
+
+	if len(topics) == 0 {
+		fmt.Fprintln(p.out, p.paint("2", "No topics found."))
+		return
+	}
+
+	for i, topic := range topics {
+		fmt.Fprintf(p.out, "%s %s", p.paint("1", fmt.Sprintf("%d.", i+1)), p.paint("1;35", topic.Name))
+
+		if topic.Title != "" && topic.Title != topic.Name {
+			fmt.Fprintf(p.out, " - %s", topic.Title)
+		}
+
+		fmt.Fprintln(p.out)
+
+		if topic.TotalProjectsCount > 0 {
+			fmt.Fprintf(p.out, "   %s\n", p.paint("35", fmt.Sprintf("📦 %d projects", topic.TotalProjectsCount)))
+		}
+
+		if topic.Description != "" && topic.Description != topic.Title {
+			fmt.Fprintf(p.out, "   %s\n", p.paint("2", truncateText(topic.Description, 80)))
+		}
+
+		fmt.Fprintln(p.out)
+	}
+}
+
+type projectsReport struct {
+	Topic    string           `json:"topic"`
+	Projects []gitlab.Project `json:"projects"`
+}
+
+func (p *printer) projects(topicName string, projects []gitlab.Project) {
+	if !p.table() {
+		if projects == nil {
+			projects = []gitlab.Project{}
+		}
+
+		rows := make([][]string, 0, len(projects))
+		for _, project := range projects {
+			rows = append(rows, []string{
+				strconv.Itoa(project.ID),
+				project.Name,
+				project.PathWithNamespace,
+				project.WebURL,
+				project.Description,
+				strings.Join(project.Topics, ";"),
+			})
+		}
+
+		header := []string{"id", "name", "path_with_namespace", "web_url", "description", "topics"}
+		p.document(projectsReport{Topic: topicName, Projects: projects}, header, rows)
+		return
+	}
+
+	fmt.Fprintf(p.out, "\n📁 %s\n\n", p.paint("1;35", "Projects in topic: "+topicName))
+
+	if len(projects) == 0 {
+		fmt.Fprintln(p.out, p.paint("2", "No projects found for this topic."))
+		return
+	}
+
+	for i, project := range projects {
+		fmt.Fprintf(p.out, "%s %s\n", p.paint("1", fmt.Sprintf("%d.", i+1)), p.paint("1", project.Name))
+		fmt.Fprintf(p.out, "   %s\n", p.paint("2", project.PathWithNamespace))
+
+		if project.Description != "" {
+			fmt.Fprintf(p.out, "   %s\n", p.paint("3;2", truncateText(project.Description, 80)))
+		}
+
+		if len(project.Topics) > 0 {
+			fmt.Fprint(p.out, "   ")
+			for _, t := range project.Topics {
+				if t != topicName {
+					fmt.Fprintf(p.out, "%s ", p.paint("45;37", " "+t+" "))
+				}
+			}
+			fmt.Fprintln(p.out)
+		}
+
+		fmt.Fprintf(p.out, "   %s\n", p.paint("4;32", project.WebURL))
+		fmt.Fprintln(p.out)
+	}
+}
+
+type mergeReport struct {
+	Results []merge.Result `json:"results"`
+	Summary merge.Summary  `json:"summary"`
+}
+
+const separator = "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
+
+func (p *printer) mergeCandidate(candidate merge.Candidate) {
+	mr := candidate.MergeRequest
+
+	p.statusf("%s\n", p.paint("36", separator))
+	p.statusf("%s %s\n", p.paint("1;36", "Project:"), candidate.Project.PathWithNamespace)
+	p.statusf("%s %s\n", p.paint("1;36", "MR Title:"), mr.Title)
+	p.statusf("%s %s → %s\n", p.paint("1;36", "Branches:"), mr.SourceBranch, mr.TargetBranch)
+	p.statusf("%s %s\n", p.paint("1;36", "URL:"), mr.WebURL)
+	p.statusf("%s %s\n", p.paint("1;36", "Pipeline:"), p.pipelineStatus(mr.HeadPipeline))
+	for _, job := range merge.FailedJobs(candidate.Jobs) {
+		p.statusf("  %s %s\n", p.paint("31", fmt.Sprintf("✗ %s (%s)", job.Name, job.Stage)), job.WebURL)
+	}
+	if candidate.NeedsRebase {
+		p.statusf("%s %s\n", p.paint("1;36", "Rebase:"), p.paint("33", fmt.Sprintf("behind %s, will be rebased and its new pipeline awaited before merging", mr.TargetBranch)))
+	}
+	p.statusf("%s\n", p.paint("36", separator))
+}
+
+func (p *printer) pipelineStatus(pipeline *gitlab.Pipeline) string {
+	if pipeline == nil {
+		return p.paint("2", "none")
+	}
+
+	code := "33"
+	switch {
+	case pipeline.Status == "success":
+		code = "32"
+	case !pipeline.IsActive():
+		code = "31"
+	}
+
+	return fmt.Sprintf("%s #%d %s", p.paint(code, pipeline.Status), pipeline.ID, pipeline.WebURL)
+}
+
+func (p *printer) mergeProgress(result merge.Result) {
+	switch {
+	case result.MergeRequestIID == 0:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("✗ Error for %s: %s", result.Project, result.ErrorMessage)))
+	case result.Status == merge.StatusSkipped && result.Reason != "declined":
+		// Skipped by the policy before any prompt was shown.
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⊘ %s !%d skipped: %s", result.Project, result.MergeRequestIID, result.Reason)))
+	case result.Status == merge.StatusConflict || result.Status == merge.StatusNotMergeable:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("⚠ %s !%d %s: %s", result.Project, result.MergeRequestIID, result.Status, result.Reason)))
+	case result.Status == merge.StatusBlocked:
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⏸ %s !%d blocked: %s", result.Project, result.MergeRequestIID, result.Reason)))
+	case result.Status == merge.StatusMerged:
+		p.statusf("%s\n\n", p.paint("32", "✓ Successfully merged as "+shortSHA(result.MergeCommitSHA)))
+	case result.Status == merge.StatusScheduled:
+		p.statusf("%s\n\n", p.paint("32", "⏱ Set to merge when the pipeline succeeds"))
+	case result.Status == merge.StatusQueued:
+		p.statusf("%s\n\n", p.paint("36", "🚂 Added to the merge train"))
+	case result.Status == merge.StatusSkipped:
+		p.statusf("%s\n\n", p.paint("33", "⊘ Skipped"))
+	default:
+		p.statusf("%s\n\n", p.paint("31", "✗ Failed to merge: "+result.ErrorMessage))
+	}
+}
+
+// mergeAutoProgress reports non-interactive runs, where no MR details were
+// shown before the outcome.
+func (p *printer) mergeAutoProgress(result merge.Result) {
+	if result.MergeRequestIID == 0 {
+		p.mergeProgress(result)
+		return
+	}
+
+	line := fmt.Sprintf("%s !%d %s: %s", result.Project, result.MergeRequestIID, result.Title, result.Status)
+	if result.Rebased {
+		line += " after rebase"
+	}
+	switch result.Status {
+	case merge.StatusMerged:
+		p.statusf("%s\n", p.paint("32", fmt.Sprintf("✓ %s (%s)", line, shortSHA(result.MergeCommitSHA))))
+	case merge.StatusScheduled:
+		p.statusf("%s\n", p.paint("32", fmt.Sprintf("⏱ %s (%s)", line, result.Reason)))
+	case merge.StatusQueued:
+		p.statusf("%s\n", p.paint("36", fmt.Sprintf("🚂 %s (%s)", line, result.Reason)))
+	case merge.StatusSkipped:
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⊘ %s (%s)", line, result.Reason)))
+	case merge.StatusBlocked:
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⏸ %s (%s)", line, result.Reason)))
+	case merge.StatusConflict, merge.StatusNotMergeable, merge.StatusDropped:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("⚠ %s (%s)", line, result.Reason)))
+	default:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("✗ %s (%s)", line, result.ErrorMessage)))
+	}
+}
+
+func (p *printer) mergeReport(results []merge.Result, summary merge.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []merge.Result{}
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				result.Project,
+				optionalInt(result.MergeRequestIID),
+				result.Title,
+				result.SourceBranch,
+				result.TargetBranch,
+				result.MergeRequestURL,
+				result.PipelineStatus,
+				strconv.FormatBool(result.Rebased),
+				string(result.Status),
+				result.MergeCommitSHA,
+				result.Reason,
+				result.ErrorMessage,
+			})
+		}
+
+		header := []string{"project", "merge_request_iid", "title", "source_branch", "target_branch", "merge_request_url", "pipeline_status", "rebased", "status", "merge_commit_sha", "reason", "error"}
+		p.document(mergeReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	fmt.Fprintln(p.out, p.paint("1;36", "📊 Summary"))
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	fmt.Fprintln(p.out, p.paint("32", fmt.Sprintf("✓ Merged:  %d", summary.Merged)))
+	for _, result := range results {
+		if result.Status == merge.StatusMerged {
+			fmt.Fprintf(p.out, "    %s !%d → %s\n", result.Project, result.MergeRequestIID, result.MergeCommitSHA)
+		}
+	}
+	if summary.Scheduled > 0 {
+		fmt.Fprintln(p.out, p.paint("32", fmt.Sprintf("⏱ Scheduled: %d", summary.Scheduled)))
+	}
+	if summary.Queued > 0 {
+		fmt.Fprintln(p.out, p.paint("36", fmt.Sprintf("🚂 Still queued: %d", summary.Queued)))
+	}
+	if summary.Dropped > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("⚠ Dropped: %d", summary.Dropped)))
+	}
+	fmt.Fprintln(p.out, p.paint("33", fmt.Sprintf("⊘ Skipped: %d", summary.Skipped)))
+	if summary.Blocked > 0 {
+		fmt.Fprintln(p.out, p.paint("33", fmt.Sprintf("⏸ Blocked: %d", summary.Blocked)))
+	}
+	if summary.Conflicts > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("⚠ Conflicts: %d", summary.Conflicts)))
+	}
+	if summary.NotMergeable > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("⚠ Not mergeable: %d", summary.NotMergeable)))
+	}
+	if summary.Unauthorized > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("⛔ Unauthorized: %d", summary.Unauthorized)))
+	}
+	if summary.Errors > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("✗ Errors:  %d", summary.Errors)))
+	}
+	fmt.Fprintln(p.out, p.paint("36", separator))
+}
+
+// mergeOrder shows the order projects are merged in and what each waits for.
+func (p *printer) mergeOrder(projects []gitlab.Project, dependencies merge.Dependencies) {
+	p.statusf("%s\n", p.paint("36", "🔗 Merge order:"))
+	for i, project := range projects {
+		line := fmt.Sprintf("  %d. %s", i+1, project.PathWithNamespace)
+		if upstreams := dependencies[project.PathWithNamespace]; len(upstreams) > 0 {
+			line += " (after " + strings.Join(upstreams, ", ") + ")"
+		}
+		p.statusf("%s\n", line)
+	}
+	p.statusf("\n")
+}
+
+// mergeTrainStart announces the wait for the merge trains, before the first
+// tracked MR is reported.
+func (p *printer) mergeTrainStart() {
+	p.statusf("\n%s\n", p.paint("36", "🚂 Waiting for the merge requests to leave their merge trains..."))
+}
+
+// mergeTrainReport prints, per project, what became of the MRs that were
+// added to a merge train.
+func (p *printer) mergeTrainReport(results []merge.Result) {
+	var projects []string
+	counts := make(map[string]map[merge.Status]int)
+	for _, result := range results {
+		switch result.Status {
+		case merge.StatusMerged, merge.StatusScheduled, merge.StatusQueued, merge.StatusDropped:
+		default:
+			continue
+		}
+		if counts[result.Project] == nil {
+			projects = append(projects, result.Project)
+			counts[result.Project] = make(map[merge.Status]int)
+		}
+		counts[result.Project][result.Status]++
+	}
+
+	if len(projects) == 0 {
+		return
+	}
+
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	fmt.Fprintln(p.out, p.paint("1;36", "🚂 Merge Trains"))
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	for _, project := range projects {
+		c := counts[project]
+		fmt.Fprintf(p.out, "%s: %d merged, %d dropped, %d still queued", project, c[merge.StatusMerged], c[merge.StatusDropped], c[merge.StatusQueued])
+		if c[merge.StatusScheduled] > 0 {
+			fmt.Fprintf(p.out, ", %d not on the train yet", c[merge.StatusScheduled])
+		}
+		fmt.Fprintln(p.out)
+		for _, result := range results {
+			if result.Project != project {
+				continue
+			}
+			switch result.Status {
+			case merge.StatusDropped, merge.StatusQueued, merge.StatusScheduled:
+				fmt.Fprintf(p.out, "    !%d %s: %s\n", result.MergeRequestIID, result.Title, result.Reason)
+			}
+		}
+	}
+}
+
+type approveReport struct {
+	Results []approve.Result `json:"results"`
+	Summary approve.Summary  `json:"summary"`
+}
+
+// approveCandidate shows an MR with its diffs, cut off after maxLines diff
+// lines in total (0 = no limit).
+func (p *printer) approveCandidate(candidate approve.Candidate, maxLines int) {
+	mr := candidate.MergeRequest
+
+	p.statusf("%s\n", p.paint("36", separator))
+	p.statusf("%s %s\n", p.paint("1;36", "Project:"), candidate.Project.PathWithNamespace)
+	p.statusf("%s %s\n", p.paint("1;36", "MR Title:"), mr.Title)
+	p.statusf("%s %s → %s\n", p.paint("1;36", "Branches:"), mr.SourceBranch, mr.TargetBranch)
+	p.statusf("%s %s\n", p.paint("1;36", "URL:"), mr.WebURL)
+	p.statusf("%s %d of %d left\n", p.paint("1;36", "Approvals:"), candidate.Approvals.ApprovalsLeft, candidate.Approvals.ApprovalsRequired)
+	p.statusf("%s\n", p.paint("36", separator))
+
+	shown, hidden := 0, 0
+	for _, diff := range candidate.Diffs {
+		added, deleted := diff.LineChanges()
+		p.statusf("%s %s\n", p.paint("1", diff.NewPath), p.paint("2", fmt.Sprintf("(+%d -%d)", added, deleted)))
+
+		for _, line := range strings.Split(strings.TrimRight(diff.Diff, "\n"), "\n") {
+			if maxLines > 0 && shown >= maxLines {
+				hidden++
+				continue
+			}
+			shown++
+
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				line = p.paint("36", line)
+			case strings.HasPrefix(line, "+"):
+				line = p.paint("32", line)
+			case strings.HasPrefix(line, "-"):
+				line = p.paint("31", line)
+			}
+			p.statusf("%s\n", line)
+		}
+	}
+
+	if hidden > 0 {
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("… %d more diff line(s), see %s", hidden, mr.WebURL)))
+	}
+	p.statusf("%s\n", p.paint("36", separator))
+}
+
+func (p *printer) approveProgress(result approve.Result) {
+	switch {
+	case result.MergeRequestIID == 0:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("✗ Error for %s: %s", result.Project, result.ErrorMessage)))
+	case result.Status == approve.StatusSkipped && result.Reason != "declined":
+		// Skipped before any diff was shown.
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⊘ %s !%d skipped: %s", result.Project, result.MergeRequestIID, result.Reason)))
+	case result.Status == approve.StatusApproved:
+		p.statusf("%s\n\n", p.paint("32", fmt.Sprintf("✓ Approved (%d approval(s) left)", result.ApprovalsLeft)))
+	case result.Status == approve.StatusSkipped:
+		p.statusf("%s\n\n", p.paint("33", "⊘ Skipped"))
+	default:
+		p.statusf("%s\n\n", p.paint("31", "✗ Failed to approve: "+result.ErrorMessage))
+	}
+}
+
+// approveAutoProgress reports runs without prompts.
+func (p *printer) approveAutoProgress(result approve.Result) {
+	if result.MergeRequestIID == 0 {
+		p.approveProgress(result)
+		return
+	}
+
+	line := fmt.Sprintf("%s !%d %s: %s", result.Project, result.MergeRequestIID, result.Title, result.Status)
+	switch result.Status {
+	case approve.StatusApproved, approve.StatusUnapproved:
+		p.statusf("%s\n", p.paint("32", fmt.Sprintf("✓ %s (%d approval(s) left)", line, result.ApprovalsLeft)))
+	case approve.StatusSkipped:
+		p.statusf("%s\n", p.paint("33", fmt.Sprintf("⊘ %s (%s)", line, result.Reason)))
+	default:
+		p.statusf("%s\n", p.paint("31", fmt.Sprintf("✗ %s (%s)", line, result.ErrorMessage)))
+	}
+}
+
+func (p *printer) approveReport(results []approve.Result, summary approve.Summary) {
+	if !p.table() {
+		if results == nil {
+			results = []approve.Result{}
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				result.Project,
+				optionalInt(result.MergeRequestIID),
+				result.Title,
+				result.SourceBranch,
+				result.TargetBranch,
+				result.MergeRequestURL,
+				strconv.Itoa(result.ApprovalsLeft),
+				string(result.Status),
+				result.Reason,
+				result.ErrorMessage,
+			})
+		}
+
+		header := []string{"project", "merge_request_iid", "title", "source_branch", "target_branch", "merge_request_url", "approvals_left", "status", "reason", "error"}
+		p.document(approveReport{Results: results, Summary: summary}, header, rows)
+		return
+	}
+
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	fmt.Fprintln(p.out, p.paint("1;36", "📊 Summary"))
+	fmt.Fprintln(p.out, p.paint("36", separator))
+	if summary.Unapproved > 0 {
+		fmt.Fprintln(p.out, p.paint("32", fmt.Sprintf("✓ Unapproved: %d", summary.Unapproved)))
+	} else {
+		fmt.Fprintln(p.out, p.paint("32", fmt.Sprintf("✓ Approved: %d", summary.Approved)))
+	}
+	fmt.Fprintln(p.out, p.paint("33", fmt.Sprintf("⊘ Skipped:  %d", summary.Skipped)))
+	if summary.Unauthorized > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("⛔ Unauthorized: %d", summary.Unauthorized)))
+	}
+	if summary.Errors > 0 {
+		fmt.Fprintln(p.out, p.paint("31", fmt.Sprintf("✗ Errors:   %d", summary.Errors)))
+	}
+	fmt.Fprintln(p.out, p.paint("36", separator))
+}
+
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+func truncateText(text string, max int) string {
+	if len(text) > max {
+		return text[:max-3] + "..."
+	}
+	return text
+}
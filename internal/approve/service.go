@@ -0,0 +1,255 @@
+package approve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type GitLabClient interface {
+	ListOpenMergeRequestsByTarget(ctx context.Context, projectID int, targetBranch string) ([]gitlab.MergeRequest, error)
+	GetMergeRequestApprovals(ctx context.Context, projectID int, mrIID int) (*gitlab.Approvals, error)
+	ListMergeRequestDiffs(ctx context.Context, projectID int, mrIID int) ([]gitlab.Diff, error)
+	ApproveMergeRequest(ctx context.Context, projectID int, mrIID int, sha string) (*gitlab.Approvals, error)
+	UnapproveMergeRequest(ctx context.Context, projectID int, mrIID int) error
+}
+
+type Config struct {
+	TargetBranch string
+	// SourceBranch, if set, limits the run to MRs from this branch.
+	SourceBranch string
+	Projects     []gitlab.Project
+	// Unapprove revokes the token user's approval instead of giving it.
+	Unapprove bool
+	// Confirm is asked, with the MR's diffs, before every approval. Returning
+	// an error stops the run. Without Confirm every open MR is approved.
+	Confirm func(ctx context.Context, candidate Candidate) (bool, error)
+	// Progress, if set, is called with every result as soon as it is known.
+	Progress func(result Result)
+}
+
+type Candidate struct {
+	Project      gitlab.Project
+	MergeRequest gitlab.MergeRequest
+	Approvals    gitlab.Approvals
+	Diffs        []gitlab.Diff
+}
+
+type Status string
+
+const (
+	StatusApproved     Status = "APPROVED"
+	StatusUnapproved   Status = "UNAPPROVED"
+	StatusSkipped      Status = "SKIPPED"
+	StatusUnauthorized Status = "UNAUTHORIZED"
+	StatusError        Status = "ERROR"
+)
+
+type Result struct {
+	Project         string `json:"project"`
+	MergeRequestIID int    `json:"merge_request_iid,omitempty"`
+	Title           string `json:"title,omitempty"`
+	SourceBranch    string `json:"source_branch,omitempty"`
+	TargetBranch    string `json:"target_branch"`
+	MergeRequestURL string `json:"merge_request_url,omitempty"`
+	ApprovalsLeft   int    `json:"approvals_left"`
+	Status          Status `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+	ErrorMessage    string `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total        int `json:"total"`
+	Approved     int `json:"approved"`
+	Unapproved   int `json:"unapproved"`
+	Skipped      int `json:"skipped"`
+	Unauthorized int `json:"unauthorized"`
+	Errors       int `json:"errors"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Run walks the open MRs targeting the branch in every project, in order.
+// Like merging, it is sequential because each MR may wait for a confirmation.
+func (s *Service) Run(ctx context.Context) ([]Result, Summary) {
+	var results []Result
+
+	record := func(result Result) {
+		results = append(results, result)
+		if s.config.Progress != nil {
+			s.config.Progress(result)
+		}
+	}
+
+	for _, project := range s.config.Projects {
+		if ctx.Err() != nil {
+			break
+		}
+
+		mrs, err := s.client.ListOpenMergeRequestsByTarget(ctx, project.ID, s.config.TargetBranch)
+		if err != nil {
+			record(failed(Result{
+				Project:      project.PathWithNamespace,
+				TargetBranch: s.config.TargetBranch,
+			}, err, fmt.Sprintf("failed to fetch merge requests: %v", err)))
+			continue
+		}
+
+		for _, mr := range mrs {
+			if mr.IsDraft() {
+				continue
+			}
+			if s.config.SourceBranch != "" && mr.SourceBranch != s.config.SourceBranch {
+				continue
+			}
+
+			result, err := s.processMergeRequest(ctx, project, mr)
+			if err != nil {
+				return results, summarize(results)
+			}
+			record(result)
+		}
+	}
+
+	return results, summarize(results)
+}
+
+// processMergeRequest approves (or unapproves) a single MR. An error is only
+// returned when Confirm asked to stop the run.
+func (s *Service) processMergeRequest(ctx context.Context, project gitlab.Project, mr gitlab.MergeRequest) (Result, error) {
+	result := Result{
+		Project:         project.PathWithNamespace,
+		MergeRequestIID: mr.IID,
+		Title:           mr.Title,
+		SourceBranch:    mr.SourceBranch,
+		TargetBranch:    mr.TargetBranch,
+		MergeRequestURL: mr.WebURL,
+	}
+
+	approvals, err := s.client.GetMergeRequestApprovals(ctx, project.ID, mr.IID)
+	if err != nil {
+		return failed(result, err, fmt.Sprintf("failed to get approvals: %v", err)), nil
+	}
+	result.ApprovalsLeft = approvals.ApprovalsLeft
+
+	if s.config.Unapprove {
+		return s.unapprove(ctx, result, project, mr, approvals), nil
+	}
+
+	switch {
+	case approvals.UserHasApproved:
+		result.Status = StatusSkipped
+		result.Reason = "already approved"
+		return result, nil
+	case !approvals.UserCanApprove:
+		result.Status = StatusSkipped
+		result.Reason = "token user is not allowed to approve this MR"
+		return result, nil
+	}
+
+	if s.config.Confirm != nil {
+		diffs, err := s.client.ListMergeRequestDiffs(ctx, project.ID, mr.IID)
+		if err != nil {
+			return failed(result, err, fmt.Sprintf("failed to fetch diffs: %v", err)), nil
+		}
+
+		ok, err := s.config.Confirm(ctx, Candidate{
+			Project:      project,
+			MergeRequest: mr,
+			Approvals:    *approvals,
+			Diffs:        diffs,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		if !ok {
+			result.Status = StatusSkipped
+			result.Reason = "declined"
+			return result, nil
+		}
+	}
+
+	// The SHA from the listing is the one whose diff was shown, so a push in
+	// the meantime makes GitLab refuse the approval.
+	updated, err := s.client.ApproveMergeRequest(ctx, project.ID, mr.IID, mr.SHA)
+	if err != nil {
+		return failed(result, err, failureReason(err)), nil
+	}
+
+	result.Status = StatusApproved
+	result.ApprovalsLeft = updated.ApprovalsLeft
+
+	return result, nil
+}
+
+func (s *Service) unapprove(ctx context.Context, result Result, project gitlab.Project, mr gitlab.MergeRequest, approvals *gitlab.Approvals) Result {
+	if !approvals.UserHasApproved {
+		result.Status = StatusSkipped
+		result.Reason = "not approved by the token user"
+		return result
+	}
+
+	if err := s.client.UnapproveMergeRequest(ctx, project.ID, mr.IID); err != nil {
+		return failed(result, err, failureReason(err))
+	}
+
+	result.Status = StatusUnapproved
+	if updated, err := s.client.GetMergeRequestApprovals(ctx, project.ID, mr.IID); err == nil {
+		result.ApprovalsLeft = updated.ApprovalsLeft
+	}
+
+	return result
+}
+
+func summarize(results []Result) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusApproved:
+			summary.Approved++
+		case StatusUnapproved:
+			summary.Unapproved++
+		case StatusSkipped:
+			summary.Skipped++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		}
+	}
+
+	return summary
+}
+
+func failed(result Result, err error, message string) Result {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+	result.ErrorMessage = message
+	return result
+}
+
+func failureReason(err error) string {
+	switch {
+	case gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err):
+		return fmt.Sprintf("token is not allowed to approve this MR (%v)", err)
+	case gitlab.IsConflict(err):
+		return fmt.Sprintf("source branch changed since its diff was shown (%v)", err)
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,156 @@
+package approve
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	mergeRequests map[int][]gitlab.MergeRequest
+	approvals     map[int]gitlab.Approvals
+	approveErrors map[int]error
+	diffs         map[int][]gitlab.Diff
+	approved      map[int]string
+	unapproved    []int
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		mergeRequests: make(map[int][]gitlab.MergeRequest),
+		approvals:     make(map[int]gitlab.Approvals),
+		approveErrors: make(map[int]error),
+		diffs:         make(map[int][]gitlab.Diff),
+		approved:      make(map[int]string),
+	}
+}
+
+func (m *mockGitLabClient) ListOpenMergeRequestsByTarget(ctx context.Context, projectID int, targetBranch string) ([]gitlab.MergeRequest, error) {
+	return m.mergeRequests[projectID], nil
+}
+
+func (m *mockGitLabClient) GetMergeRequestApprovals(ctx context.Context, projectID int, mrIID int) (*gitlab.Approvals, error) {
+	approvals, ok := m.approvals[mrIID]
+	if !ok {
+		approvals = gitlab.Approvals{ApprovalsRequired: 1, ApprovalsLeft: 1, UserCanApprove: true}
+	}
+	return &approvals, nil
+}
+
+func (m *mockGitLabClient) ListMergeRequestDiffs(ctx context.Context, projectID int, mrIID int) ([]gitlab.Diff, error) {
+	return m.diffs[mrIID], nil
+}
+
+func (m *mockGitLabClient) ApproveMergeRequest(ctx context.Context, projectID int, mrIID int, sha string) (*gitlab.Approvals, error) {
+	if err := m.approveErrors[mrIID]; err != nil {
+		return nil, err
+	}
+	m.approved[mrIID] = sha
+	return &gitlab.Approvals{Approved: true, ApprovalsRequired: 1}, nil
+}
+
+func (m *mockGitLabClient) UnapproveMergeRequest(ctx context.Context, projectID int, mrIID int) error {
+	m.unapproved = append(m.unapproved, mrIID)
+	return nil
+}
+
+func TestRun_Approves(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{
+		{IID: 1, SourceBranch: "stage", SHA: "aaa"},
+		{IID: 2, SourceBranch: "stage", Draft: true},
+		{IID: 3, SourceBranch: "feature/x"},
+		{IID: 4, SourceBranch: "stage", SHA: "bbb"},
+		{IID: 5, SourceBranch: "stage"},
+		{IID: 6, SourceBranch: "stage"},
+		{IID: 7, SourceBranch: "stage"},
+	}
+	client.approvals[4] = gitlab.Approvals{UserHasApproved: true}
+	client.approvals[5] = gitlab.Approvals{ApprovalsLeft: 1}
+	client.approveErrors[6] = &gitlab.APIError{StatusCode: http.StatusConflict}
+	client.approveErrors[7] = &gitlab.APIError{StatusCode: http.StatusForbidden}
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		SourceBranch: "stage",
+		Projects:     []gitlab.Project{{ID: 1, PathWithNamespace: "group/repo"}},
+	})
+
+	results, summary := service.Run(context.Background())
+
+	expected := []struct {
+		iid    int
+		status Status
+		reason string
+	}{
+		{1, StatusApproved, ""},
+		{4, StatusSkipped, "already approved"},
+		{5, StatusSkipped, "token user is not allowed to approve this MR"},
+		{6, StatusError, ""},
+		{7, StatusUnauthorized, ""},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.MergeRequestIID != want.iid || got.Status != want.status || got.Reason != want.reason {
+			t.Errorf("result %d: expected !%d %s (%q), got !%d %s (%q)", i, want.iid, want.status, want.reason, got.MergeRequestIID, got.Status, got.Reason)
+		}
+	}
+	if client.approved[1] != "aaa" || len(client.approved) != 1 {
+		t.Errorf("expected only !1 approved at its SHA, got %v", client.approved)
+	}
+	if summary != (Summary{Total: 5, Approved: 1, Skipped: 2, Unauthorized: 1, Errors: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestRun_ConfirmShowsDiffs(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}, {IID: 2}}
+	client.diffs[1] = []gitlab.Diff{{NewPath: "main.go", Diff: "@@ -1 +1 @@\n-a\n+b\n"}}
+
+	var shown int
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     []gitlab.Project{{ID: 1, PathWithNamespace: "group/repo"}},
+		Confirm: func(ctx context.Context, candidate Candidate) (bool, error) {
+			shown += len(candidate.Diffs)
+			return candidate.MergeRequest.IID == 1, nil
+		},
+	})
+
+	results, _ := service.Run(context.Background())
+
+	if shown != 1 {
+		t.Errorf("expected the diff of !1 to be shown, got %d diffs", shown)
+	}
+	if results[0].Status != StatusApproved || results[1].Status != StatusSkipped || results[1].Reason != "declined" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestRun_Unapprove(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}, {IID: 2}}
+	client.approvals[1] = gitlab.Approvals{UserHasApproved: true}
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     []gitlab.Project{{ID: 1, PathWithNamespace: "group/repo"}},
+		Unapprove:    true,
+	})
+
+	results, summary := service.Run(context.Background())
+
+	if results[0].Status != StatusUnapproved || results[1].Status != StatusSkipped {
+		t.Errorf("unexpected statuses: %s, %s", results[0].Status, results[1].Status)
+	}
+	if len(client.unapproved) != 1 || client.unapproved[0] != 1 || summary.Unapproved != 1 {
+		t.Errorf("expected only !1 unapproved, got %v", client.unapproved)
+	}
+}
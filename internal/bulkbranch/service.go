@@ -0,0 +1,229 @@
+package bulkbranch
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
+)
+
+type GitLabClient interface {
+	GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error)
+	GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error)
+	GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error)
+	CreateBranch(ctx context.Context, projectID int, branch, ref string) (*gitlab.Branch, error)
+}
+
+type Config struct {
+	// Ref is the branch, tag or SHA the new branch starts from.
+	Ref         string
+	Branch      string
+	Projects    []string
+	Verbose     bool
+	Concurrency int
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectResult)
+}
+
+type ResultStatus string
+
+const (
+	StatusCreated       ResultStatus = "CREATED"
+	StatusSkippedExists ResultStatus = "SKIPPED_EXISTS"
+	StatusSkippedNoRef  ResultStatus = "SKIPPED_NO_REF"
+	StatusConflict      ResultStatus = "CONFLICT"
+	StatusNotFound      ResultStatus = "NOT_FOUND"
+	StatusUnauthorized  ResultStatus = "UNAUTHORIZED"
+	StatusError         ResultStatus = "ERROR"
+	StatusCanceled      ResultStatus = "CANCELED"
+)
+
+type ProjectResult struct {
+	Project      string       `json:"project"`
+	Status       ResultStatus `json:"status"`
+	Branch       string       `json:"branch"`
+	SHA          string       `json:"sha,omitempty"`
+	BranchURL    string       `json:"branch_url,omitempty"`
+	Details      string       `json:"details,omitempty"`
+	ErrorMessage string       `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total         int `json:"total"`
+	Created       int `json:"created"`
+	SkippedExists int `json:"skipped_exists"`
+	SkippedNoRef  int `json:"skipped_no_ref"`
+	Conflicts     int `json:"conflicts"`
+	NotFound      int `json:"not_found"`
+	Unauthorized  int `json:"unauthorized"`
+	Errors        int `json:"errors"`
+	Canceled      int `json:"canceled"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Create creates the branch in every project. It is idempotent: a project
+// that already has the branch at the ref's commit is skipped, and one that
+// has it elsewhere is left alone and reported as a conflict.
+func (s *Service) Create(ctx context.Context) ([]ProjectResult, Summary) {
+	projects := s.config.Projects
+	n := len(projects)
+
+	var progress func(finished int, result ProjectResult)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectResult) {
+			s.config.Progress(finished, n, result)
+		}
+	}
+
+	results := workpool.Run(n, s.config.Concurrency, func(i int) ProjectResult {
+		if ctx.Err() != nil {
+			return ProjectResult{
+				Project: projects[i],
+				Branch:  s.config.Branch,
+				Status:  StatusCanceled,
+				Details: "Not processed: run was canceled",
+			}
+		}
+
+		result := s.createBranch(ctx, projects[i])
+		if result.Status == StatusError && ctx.Err() != nil {
+			result.Status = StatusCanceled
+		}
+		return result
+	}, progress)
+
+	return results, summarize(results)
+}
+
+func (s *Service) createBranch(ctx context.Context, projectPath string) ProjectResult {
+	result := ProjectResult{
+		Project: projectPath,
+		Branch:  s.config.Branch,
+	}
+
+	project, err := s.client.GetProject(ctx, projectPath)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusNotFound
+			result.Details = fmt.Sprintf("Project '%s' does not exist or is not visible to this token", projectPath)
+			return result
+		}
+		return failed(result, "", err)
+	}
+
+	commit, err := s.client.GetCommit(ctx, project.ID, s.config.Ref)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusSkippedNoRef
+			result.Details = fmt.Sprintf("Ref '%s' does not exist", s.config.Ref)
+			return result
+		}
+		return failed(result, "failed to resolve ref", err)
+	}
+	result.SHA = commit.ID
+
+	existing, err := s.client.GetBranch(ctx, project.ID, s.config.Branch)
+	switch {
+	case err == nil:
+		return existingResult(result, existing, s.config.Ref)
+	case !gitlab.IsNotFound(err):
+		return failed(result, "failed to check branch", err)
+	}
+
+	if s.config.Verbose {
+		log.Printf("[%s] Creating %s at %s (%s)...", projectPath, s.config.Branch, s.config.Ref, shortSHA(commit.ID))
+	}
+
+	// The resolved SHA is used instead of the ref, so the branch starts
+	// where it was checked even if the ref moves in the meantime.
+	branch, err := s.client.CreateBranch(ctx, project.ID, s.config.Branch, commit.ID)
+	if err != nil {
+		// Another run may have created the branch since it was checked.
+		if existing, getErr := s.client.GetBranch(ctx, project.ID, s.config.Branch); getErr == nil {
+			return existingResult(result, existing, s.config.Ref)
+		}
+		return failed(result, "failed to create branch", err)
+	}
+
+	result.Status = StatusCreated
+	result.BranchURL = branch.WebURL
+	result.Details = fmt.Sprintf("Created %s from %s at %s", s.config.Branch, s.config.Ref, shortSHA(commit.ID))
+	return result
+}
+
+func existingResult(result ProjectResult, branch *gitlab.Branch, ref string) ProjectResult {
+	result.BranchURL = branch.WebURL
+
+	if branch.Commit.ID == result.SHA {
+		result.Status = StatusSkippedExists
+		result.Details = fmt.Sprintf("Branch '%s' already exists at %s", branch.Name, shortSHA(result.SHA))
+		return result
+	}
+
+	result.Status = StatusConflict
+	result.Details = fmt.Sprintf("Branch '%s' already exists at %s, but '%s' is at %s", branch.Name, shortSHA(branch.Commit.ID), ref, shortSHA(result.SHA))
+	result.SHA = branch.Commit.ID
+	return result
+}
+
+func summarize(results []ProjectResult) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusCreated:
+			summary.Created++
+		case StatusSkippedExists:
+			summary.SkippedExists++
+		case StatusSkippedNoRef:
+			summary.SkippedNoRef++
+		case StatusConflict:
+			summary.Conflicts++
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		case StatusCanceled:
+			summary.Canceled++
+		}
+	}
+
+	return summary
+}
+
+func failed(result ProjectResult, context string, err error) ProjectResult {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+
+	result.ErrorMessage = err.Error()
+	if context != "" {
+		result.ErrorMessage = fmt.Sprintf("%s: %v", context, err)
+	}
+
+	return result
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,147 @@
+package bulkbranch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	projects     map[string]int
+	refs         map[int]string
+	branches     map[int]string
+	createErrors map[int]error
+	// raced creates the branch behind the service's back on the first
+	// attempt, as another run would.
+	raced map[int]bool
+
+	mu      sync.Mutex
+	created map[int]string
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		projects:     make(map[string]int),
+		refs:         make(map[int]string),
+		branches:     make(map[int]string),
+		createErrors: make(map[int]error),
+		raced:        make(map[int]bool),
+		created:      make(map[int]string),
+	}
+}
+
+func notFound() error {
+	return &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Not Found"}
+}
+
+func (m *mockGitLabClient) GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error) {
+	id, ok := m.projects[projectPath]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Project{ID: id, PathWithNamespace: projectPath}, nil
+}
+
+func (m *mockGitLabClient) GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error) {
+	sha, ok := m.refs[projectID]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Commit{ID: sha}, nil
+}
+
+func (m *mockGitLabClient) GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sha, ok := m.branches[projectID]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Branch{Name: branch, Commit: gitlab.Commit{ID: sha}}, nil
+}
+
+func (m *mockGitLabClient) CreateBranch(ctx context.Context, projectID int, branch, ref string) (*gitlab.Branch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.raced[projectID] {
+		m.branches[projectID] = ref
+		return nil, &gitlab.APIError{StatusCode: http.StatusBadRequest, Message: "Branch already exists"}
+	}
+	if err := m.createErrors[projectID]; err != nil {
+		return nil, err
+	}
+	m.created[projectID] = ref
+	m.branches[projectID] = ref
+	return &gitlab.Branch{Name: branch, Commit: gitlab.Commit{ID: ref}, WebURL: "https://gitlab.example.com/branch"}, nil
+}
+
+func TestCreate(t *testing.T) {
+	client := newMockClient()
+	for i, path := range []string{"group/new", "group/same", "group/moved", "group/no-ref", "group/denied", "group/raced"} {
+		client.projects[path] = i + 1
+		client.refs[i+1] = "aaaaaaaaaa"
+	}
+	client.branches[2] = "aaaaaaaaaa"
+	client.branches[3] = "bbbbbbbbbb"
+	delete(client.refs, 4)
+	client.createErrors[5] = &gitlab.APIError{StatusCode: http.StatusForbidden}
+	client.raced[6] = true
+
+	var progressed int
+	service := NewService(client, Config{
+		Ref:         "op-stage",
+		Branch:      "op-rc-2026.10",
+		Projects:    []string{"group/new", "group/same", "group/moved", "group/no-ref", "group/denied", "group/raced", "group/missing"},
+		Concurrency: 3,
+		Progress:    func(done, total int, result ProjectResult) { progressed++ },
+	})
+
+	results, summary := service.Create(context.Background())
+
+	expected := []struct {
+		status  ResultStatus
+		sha     string
+		details string
+	}{
+		{StatusCreated, "aaaaaaaaaa", "Created op-rc-2026.10 from op-stage at aaaaaaaa"},
+		{StatusSkippedExists, "aaaaaaaaaa", "Branch 'op-rc-2026.10' already exists at aaaaaaaa"},
+		{StatusConflict, "bbbbbbbbbb", "Branch 'op-rc-2026.10' already exists at bbbbbbbb, but 'op-stage' is at aaaaaaaa"},
+		{StatusSkippedNoRef, "", "Ref 'op-stage' does not exist"},
+		{StatusUnauthorized, "aaaaaaaaaa", ""},
+		{StatusSkippedExists, "aaaaaaaaaa", "Branch 'op-rc-2026.10' already exists at aaaaaaaa"},
+		{StatusNotFound, "", "Project 'group/missing' does not exist or is not visible to this token"},
+	}
+
+	if len(results) != len(expected) || progressed != len(expected) {
+		t.Fatalf("expected %d results and progress calls, got %d and %d", len(expected), len(results), progressed)
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.Status != want.status || got.SHA != want.sha || got.Details != want.details {
+			t.Errorf("%s: expected %s (%s, %q), got %s (%s, %q)", got.Project, want.status, want.sha, want.details, got.Status, got.SHA, got.Details)
+		}
+	}
+	if len(client.created) != 1 || client.created[1] != "aaaaaaaaaa" {
+		t.Errorf("expected only group/new to be created from the resolved SHA, got %v", client.created)
+	}
+	if summary != (Summary{Total: 7, Created: 1, SkippedExists: 2, SkippedNoRef: 1, Conflicts: 1, NotFound: 1, Unauthorized: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestCreate_Canceled(t *testing.T) {
+	client := newMockClient()
+	client.projects["group/a"] = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, summary := NewService(client, Config{Ref: "main", Branch: "rc", Projects: []string{"group/a"}}).Create(ctx)
+
+	if results[0].Status != StatusCanceled || summary.Canceled != 1 {
+		t.Errorf("expected a canceled result, got %+v", results[0])
+	}
+}
@@ -0,0 +1,148 @@
+package bulkmr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/codeowners"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func (s *Service) createOptions(ctx context.Context, project *gitlab.Project, compare *gitlab.Compare, title, description string) (gitlab.CreateMergeRequestOptions, error) {
+	opts := gitlab.CreateMergeRequestOptions{
+		SourceBranch: s.config.OriginBranch,
+		TargetBranch: s.config.TargetBranch,
+		Title:        title,
+		Description:  description,
+		Labels:       s.config.Labels,
+	}
+
+	for _, username := range s.config.Assignees {
+		id, err := s.userID(ctx, username)
+		if err != nil {
+			return opts, err
+		}
+		if id == 0 {
+			return opts, fmt.Errorf("assignee %s not found", username)
+		}
+		opts.AssigneeIDs = append(opts.AssigneeIDs, id)
+	}
+
+	seen := make(map[int]bool)
+	for _, username := range s.config.Reviewers {
+		id, err := s.userID(ctx, username)
+		if err != nil {
+			return opts, err
+		}
+		if id == 0 {
+			return opts, fmt.Errorf("reviewer %s not found", username)
+		}
+		if !seen[id] {
+			seen[id] = true
+			opts.ReviewerIDs = append(opts.ReviewerIDs, id)
+		}
+	}
+
+	if s.config.ReviewersFromCodeowners {
+		owners, err := s.codeOwnerUsernames(ctx, project.ID, compare.Diffs)
+		if err != nil {
+			return opts, err
+		}
+
+		// Code owners are best effort: names that are not users (or have
+		// left the instance) are skipped rather than failing the project.
+		for _, username := range owners {
+			id, err := s.userID(ctx, username)
+			if err != nil {
+				return opts, err
+			}
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				opts.ReviewerIDs = append(opts.ReviewerIDs, id)
+			}
+		}
+	}
+
+	if s.config.Milestone != "" {
+		milestone, err := s.client.FindMilestone(ctx, project.ID, s.config.Milestone)
+		if err != nil {
+			return opts, err
+		}
+		opts.MilestoneID = milestone.ID
+	}
+
+	return opts, nil
+}
+
+// codeOwnerUsernames returns the usernames owning any changed file, according to the
+// CODEOWNERS file on the target branch.
+func (s *Service) codeOwnerUsernames(ctx context.Context, projectID int, diffs []gitlab.Diff) ([]string, error) {
+	var content []byte
+	for _, location := range codeowners.Locations {
+		data, err := s.client.GetRawFile(ctx, projectID, location, s.config.TargetBranch)
+		if gitlab.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", location, err)
+		}
+		content = data
+		break
+	}
+
+	if content == nil {
+		return nil, nil
+	}
+
+	file := codeowners.Parse(string(content))
+
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, diff := range diffs {
+		for _, path := range []string{diff.NewPath, diff.OldPath} {
+			if path == "" {
+				continue
+			}
+			for _, username := range codeowners.Usernames(file.Owners(path)) {
+				if !seen[username] {
+					seen[username] = true
+					usernames = append(usernames, username)
+				}
+			}
+		}
+	}
+
+	return usernames, nil
+}
+
+// userID resolves a username to its ID, returning 0 for unknown users.
+// Lookups are cached for the whole run since the same people are usually
+// assigned across every project.
+func (s *Service) userID(ctx context.Context, username string) (int, error) {
+	username = strings.TrimPrefix(username, "@")
+
+	s.usersMu.Lock()
+	id, ok := s.userIDs[username]
+	s.usersMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	user, err := s.client.GetUserByUsername(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	if user != nil {
+		id = user.ID
+	}
+
+	s.usersMu.Lock()
+	if s.userIDs == nil {
+		s.userIDs = make(map[string]int)
+	}
+	s.userIDs[username] = id
+	s.usersMu.Unlock()
+
+	return id, nil
+}
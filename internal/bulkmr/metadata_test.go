@@ -0,0 +1,134 @@
+package bulkmr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func newMetadataClient() *mockGitLabClient {
+	client := newMockClient()
+	client.addProject("group/repo-a", 1)
+	client.addBranch(1, "op-stage")
+	client.addBranch(1, "op-rc")
+	client.users["alice"] = 10
+	client.users["bob"] = 11
+	client.users["carol"] = 12
+	client.milestones["v1.2"] = 7
+	return client
+}
+
+func TestProcessProjects_SetsMetadata(t *testing.T) {
+	client := newMetadataClient()
+
+	service := NewService(client, Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Projects:     []string{"group/repo-a"},
+		Labels:       []string{"release", "automated"},
+		Assignees:    []string{"@alice"},
+		Reviewers:    []string{"bob", "alice"},
+		Milestone:    "v1.2",
+	})
+
+	results, _ := service.ProcessProjects(context.Background())
+	if results[0].Status != StatusCreated {
+		t.Fatalf("expected CREATED, got %s: %s", results[0].Status, results[0].ErrorMessage)
+	}
+
+	opts := client.created[1]
+	if !reflect.DeepEqual(opts.Labels, []string{"release", "automated"}) {
+		t.Errorf("unexpected labels: %v", opts.Labels)
+	}
+	if !reflect.DeepEqual(opts.AssigneeIDs, []int{10}) {
+		t.Errorf("unexpected assignees: %v", opts.AssigneeIDs)
+	}
+	if !reflect.DeepEqual(opts.ReviewerIDs, []int{11, 10}) {
+		t.Errorf("unexpected reviewers: %v", opts.ReviewerIDs)
+	}
+	if opts.MilestoneID != 7 {
+		t.Errorf("expected milestone 7, got %d", opts.MilestoneID)
+	}
+	if client.userLookups != 2 {
+		t.Errorf("expected user lookups to be cached, got %d lookups", client.userLookups)
+	}
+}
+
+func TestProcessProjects_ReviewersFromCodeowners(t *testing.T) {
+	client := newMetadataClient()
+	client.files[".gitlab/CODEOWNERS"] = `
+* @alice
+/docs/ @carol docs@example.com
+
+[Database]
+*.sql @bob @group/dba @ghost
+`
+	client.diffs = []gitlab.Diff{
+		{OldPath: "docs/setup.md", NewPath: "docs/setup.md"},
+		{OldPath: "db/001.sql", NewPath: "db/001.sql"},
+	}
+
+	service := NewService(client, Config{
+		OriginBranch:            "op-stage",
+		TargetBranch:            "op-rc",
+		Projects:                []string{"group/repo-a"},
+		Reviewers:               []string{"carol"},
+		ReviewersFromCodeowners: true,
+	})
+
+	results, _ := service.ProcessProjects(context.Background())
+	if results[0].Status != StatusCreated {
+		t.Fatalf("expected CREATED, got %s: %s", results[0].Status, results[0].ErrorMessage)
+	}
+
+	// carol is explicit, alice owns the .sql file via the default section,
+	// bob via [Database]; the group and the unknown user are skipped.
+	expected := []int{12, 10, 11}
+	if got := client.created[1].ReviewerIDs; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected reviewers %v, got %v", expected, got)
+	}
+}
+
+func TestProcessProjects_MetadataErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "unknown assignee",
+			config: Config{Assignees: []string{"nobody"}},
+		},
+		{
+			name:   "unknown reviewer",
+			config: Config{Reviewers: []string{"nobody"}},
+		},
+		{
+			name:   "unknown milestone",
+			config: Config{Milestone: "v9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMetadataClient()
+
+			config := tt.config
+			config.OriginBranch = "op-stage"
+			config.TargetBranch = "op-rc"
+			config.Projects = []string{"group/repo-a"}
+
+			results, summary := NewService(client, config).ProcessProjects(context.Background())
+			if results[0].Status != StatusError {
+				t.Errorf("expected ERROR, got %s", results[0].Status)
+			}
+			if summary.Errors != 1 {
+				t.Errorf("expected 1 error, got %d", summary.Errors)
+			}
+			if len(client.created) != 0 {
+				t.Error("expected no merge request to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package bulkmr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+const planVersion = 1
+
+// Plan is the outcome of a dry run: every merge request that would have been
+// created, with its title, description and metadata already resolved, so it
+// can be reviewed and later applied without re-rendering anything.
+type Plan struct {
+	Version      int         `json:"version"`
+	CreatedAt    time.Time   `json:"created_at"`
+	OriginBranch string      `json:"origin_branch"`
+	TargetBranch string      `json:"target_branch"`
+	Entries      []PlanEntry `json:"entries"`
+}
+
+type PlanEntry struct {
+	Project   string `json:"project"`
+	ProjectID int    `json:"project_id"`
+	// SourceSHA is the origin branch head the plan was made against.
+	SourceSHA    string                           `json:"source_sha"`
+	Commits      int                              `json:"commits"`
+	MergeRequest gitlab.CreateMergeRequestOptions `json:"merge_request"`
+}
+
+// Plan collects the planned merge requests of a dry run's results.
+func (s *Service) Plan(results []ProjectResult) *Plan {
+	plan := &Plan{
+		Version:      planVersion,
+		CreatedAt:    s.config.Run.StartedAt,
+		OriginBranch: s.config.OriginBranch,
+		TargetBranch: s.config.TargetBranch,
+		Entries:      []PlanEntry{},
+	}
+
+	for _, result := range results {
+		if result.Plan != nil {
+			plan.Entries = append(plan.Entries, *result.Plan)
+		}
+	}
+
+	return plan
+}
+
+func SavePlan(path string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	return nil
+}
+
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s", plan.Version, path)
+	}
+
+	return &plan, nil
+}
+
+// ApplyPlan creates the merge requests of a plan exactly as planned. A
+// project is not touched if an MR for the branch pair has been opened since,
+// or if its origin branch moved and the plan no longer describes what would
+// be merged.
+func (s *Service) ApplyPlan(ctx context.Context, plan *Plan) ([]ProjectResult, Summary) {
+	results := s.run(ctx, len(plan.Entries), func(i int) ProjectResult {
+		entry := plan.Entries[i]
+		if ctx.Err() != nil {
+			return canceledResult(entry.Project)
+		}
+		return s.applyEntry(ctx, entry)
+	})
+
+	return results, summarize(results)
+}
+
+func (s *Service) applyEntry(ctx context.Context, entry PlanEntry) ProjectResult {
+	result := ProjectResult{
+		Project: entry.Project,
+		Commits: entry.Commits,
+	}
+	opts := entry.MergeRequest
+
+	existingMRs, err := s.client.FindOpenMergeRequests(ctx, entry.ProjectID, opts.SourceBranch, opts.TargetBranch)
+	if err != nil {
+		return failed(result, "failed to find existing merge requests", err)
+	}
+
+	if len(existingMRs) > 0 {
+		return existingResult(result, existingMRs)
+	}
+
+	compare, err := s.client.CompareBranches(ctx, entry.ProjectID, opts.SourceBranch, opts.TargetBranch)
+	if err != nil {
+		return failed(result, "failed to compare branches", err)
+	}
+
+	// The target may have been merged into since the plan was made, which
+	// leaves nothing to compare a head against.
+	if !compare.HasChanges() {
+		result.Status = StatusSkippedNoChange
+		result.Details = fmt.Sprintf("No changes between %s and %s anymore", opts.SourceBranch, opts.TargetBranch)
+		return result
+	}
+
+	if sha := headSHA(compare); sha != entry.SourceSHA {
+		result.Status = StatusError
+		result.ErrorMessage = fmt.Sprintf("origin branch %s moved since the plan was made (planned %s, now %s); re-run the dry run",
+			opts.SourceBranch, shortSHA(entry.SourceSHA), shortSHA(sha))
+		return result
+	}
+
+	if s.config.Verbose {
+		log.Printf("[%s] Applying plan, creating merge request...", entry.Project)
+	}
+
+	return s.create(ctx, result, entry.ProjectID, opts)
+}
+
+func headSHA(compare *gitlab.Compare) string {
+	if compare.Commit.ID != "" {
+		return compare.Commit.ID
+	}
+	if len(compare.Commits) > 0 {
+		return compare.Commits[len(compare.Commits)-1].ID
+	}
+	return ""
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,158 @@
+package bulkmr
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func newPlanClient() *mockGitLabClient {
+	client := newMockClient()
+	client.addProject("group/repo-a", 1)
+	client.addBranch(1, "op-stage")
+	client.addBranch(1, "op-rc")
+	client.addProject("group/repo-b", 2)
+	client.addBranch(2, "op-stage")
+	client.users["alice"] = 10
+	return client
+}
+
+func TestProcessProjects_DryRunDoesNotCreate(t *testing.T) {
+	client := newPlanClient()
+
+	service := NewService(client, Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Projects:     []string{"group/repo-a", "group/repo-b"},
+		Labels:       []string{"release"},
+		Assignees:    []string{"alice"},
+		DryRun:       true,
+	})
+
+	results, summary := service.ProcessProjects(context.Background())
+
+	if len(client.created) != 0 {
+		t.Fatalf("dry run created %d merge request(s)", len(client.created))
+	}
+	if results[0].Status != StatusWouldCreate || results[0].Commits != 1 {
+		t.Errorf("expected WOULD_CREATE with 1 commit, got %s with %d", results[0].Status, results[0].Commits)
+	}
+	if results[1].Status != StatusSkippedBranch {
+		t.Errorf("expected SKIPPED_NO_BRANCH for repo-b, got %s", results[1].Status)
+	}
+	if summary.WouldCreate != 1 || summary.Created != 0 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	entry := results[0].Plan
+	if entry == nil {
+		t.Fatal("expected a plan entry")
+	}
+	if entry.ProjectID != 1 || entry.SourceSHA != "abc123" {
+		t.Errorf("unexpected plan entry: %+v", entry)
+	}
+	if entry.MergeRequest.Title != "Merge op-stage into op-rc" || !reflect.DeepEqual(entry.MergeRequest.AssigneeIDs, []int{10}) {
+		t.Errorf("unexpected planned merge request: %+v", entry.MergeRequest)
+	}
+}
+
+func TestPlan_SaveLoadApply(t *testing.T) {
+	client := newPlanClient()
+	config := Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Projects:     []string{"group/repo-a", "group/repo-b"},
+		Labels:       []string{"release"},
+		DryRun:       true,
+	}
+
+	service := NewService(client, config)
+	results, _ := service.ProcessProjects(context.Background())
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := SavePlan(path, service.Plan(results)); err != nil {
+		t.Fatalf("SavePlan: %v", err)
+	}
+
+	plan, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan: %v", err)
+	}
+	if len(plan.Entries) != 1 || plan.OriginBranch != "op-stage" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	config.DryRun = false
+	applied, summary := NewService(client, config).ApplyPlan(context.Background(), plan)
+
+	if applied[0].Status != StatusCreated || summary.Created != 1 {
+		t.Fatalf("expected CREATED, got %s: %s", applied[0].Status, applied[0].ErrorMessage)
+	}
+	if !reflect.DeepEqual(client.created[1], plan.Entries[0].MergeRequest) {
+		t.Errorf("created %+v, planned %+v", client.created[1], plan.Entries[0].MergeRequest)
+	}
+}
+
+func TestApplyPlan_Skips(t *testing.T) {
+	planned := gitlab.CreateMergeRequestOptions{
+		SourceBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Title:        "Merge op-stage into op-rc",
+	}
+
+	tests := []struct {
+		name      string
+		sourceSHA string
+		existing  []gitlab.MergeRequest
+		caughtUp  bool
+		expected  ResultStatus
+	}{
+		{
+			name:      "unchanged",
+			sourceSHA: "abc123",
+			expected:  StatusCreated,
+		},
+		{
+			name:      "mr opened since plan",
+			sourceSHA: "abc123",
+			existing:  []gitlab.MergeRequest{{ID: 5, IID: 3, Title: "Manual"}},
+			expected:  StatusSkippedExists,
+		},
+		{
+			name:      "origin moved",
+			sourceSHA: "def456",
+			expected:  StatusError,
+		},
+		{
+			name:      "target caught up since plan",
+			sourceSHA: "abc123",
+			caughtUp:  true,
+			expected:  StatusSkippedNoChange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newPlanClient()
+			for _, mr := range tt.existing {
+				client.addMergeRequest(1, mr)
+			}
+			client.caughtUp[1] = tt.caughtUp
+
+			plan := &Plan{Version: planVersion, Entries: []PlanEntry{
+				{Project: "group/repo-a", ProjectID: 1, SourceSHA: tt.sourceSHA, Commits: 1, MergeRequest: planned},
+			}}
+
+			results, _ := NewService(client, Config{}).ApplyPlan(context.Background(), plan)
+			if results[0].Status != tt.expected {
+				t.Errorf("expected %s, got %s (%s)", tt.expected, results[0].Status, results[0].ErrorMessage)
+			}
+			if created := len(client.created) == 1; created != (tt.expected == StatusCreated) {
+				t.Errorf("unexpected create call: %v", client.created)
+			}
+		})
+	}
+}
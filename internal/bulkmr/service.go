@@ -1,18 +1,27 @@
 package bulkmr
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"sync"
 
 	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
 )
 
 type GitLabClient interface {
-	GetProject(projectPath string) (*gitlab.Project, error)
-	BranchExists(projectID int, branch string) (bool, error)
-	CompareBranches(projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error)
-	FindOpenMergeRequests(projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error)
-	CreateMergeRequest(projectID int, sourceBranch, targetBranch, title, description string) (*gitlab.MergeRequest, error)
+	GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error)
+	BranchExists(ctx context.Context, projectID int, branch string) (bool, error)
+	CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error)
+	FindOpenMergeRequests(ctx context.Context, projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error)
+	CreateMergeRequest(ctx context.Context, projectID int, opts gitlab.CreateMergeRequestOptions) (*gitlab.MergeRequest, error)
+	GetUserByUsername(ctx context.Context, username string) (*gitlab.User, error)
+	FindMilestone(ctx context.Context, projectID int, title string) (*gitlab.Milestone, error)
+	GetRawFile(ctx context.Context, projectID int, filePath, ref string) ([]byte, error)
+	WaitForMergeStatus(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error)
 }
 
 type Config struct {
@@ -20,42 +29,82 @@ type Config struct {
 	TargetBranch string
 	Projects     []string
 	Verbose      bool
+	Concurrency  int
+	Templates    *Templates
+	Run          RunInfo
+	Changelog    bool
+	IssuePattern *regexp.Regexp
+	Labels       []string
+	Assignees    []string
+	Reviewers    []string
+	Milestone    string
+	// ReviewersFromCodeowners adds the CODEOWNERS (read from the target
+	// branch) of every changed file as reviewers.
+	ReviewersFromCodeowners bool
+	// DryRun runs every read-only check but reports StatusWouldCreate, with
+	// the planned merge request in ProjectResult.Plan, instead of creating it.
+	DryRun bool
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectResult)
 }
 
 type ResultStatus string
 
 const (
 	StatusCreated         ResultStatus = "CREATED"
+	StatusWouldCreate     ResultStatus = "WOULD_CREATE"
 	StatusSkippedExists   ResultStatus = "SKIPPED_EXISTS"
 	StatusSkippedDraft    ResultStatus = "SKIPPED_DRAFT"
 	StatusSkippedBranch   ResultStatus = "SKIPPED_NO_BRANCH"
 	StatusSkippedNoChange ResultStatus = "SKIPPED_NO_CHANGE"
+	StatusConflict        ResultStatus = "CONFLICT"
+	StatusNotMergeable    ResultStatus = "NOT_MERGEABLE"
+	StatusNotFound        ResultStatus = "NOT_FOUND"
+	StatusUnauthorized    ResultStatus = "UNAUTHORIZED"
 	StatusError           ResultStatus = "ERROR"
+	StatusCanceled        ResultStatus = "CANCELED"
 )
 
 type ProjectResult struct {
-	Project         string
-	Status          ResultStatus
-	MergeRequestID  int
-	MergeRequestIID int
-	MergeRequestURL string
-	ErrorMessage    string
-	Details         string
+	Project         string       `json:"project"`
+	Status          ResultStatus `json:"status"`
+	MergeRequestID  int          `json:"merge_request_id,omitempty"`
+	MergeRequestIID int          `json:"merge_request_iid,omitempty"`
+	MergeRequestURL string       `json:"merge_request_url,omitempty"`
+	ErrorMessage    string       `json:"error,omitempty"`
+	Details         string       `json:"details,omitempty"`
+	Commits         int          `json:"commits,omitempty"`
+	// Blocker says why the MR cannot be merged as it is, such as conflicts
+	// with the target. A created MR keeps StatusCreated.
+	Blocker string     `json:"blocker,omitempty"`
+	Plan    *PlanEntry `json:"plan,omitempty"`
 }
 
 type Summary struct {
-	Total           int
-	Created         int
-	SkippedExists   int
-	SkippedDraft    int
-	SkippedBranch   int
-	SkippedNoChange int
-	Errors          int
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	// Blocked counts the created MRs that cannot be merged as they are.
+	Blocked         int `json:"blocked"`
+	WouldCreate     int `json:"would_create"`
+	SkippedExists   int `json:"skipped_exists"`
+	SkippedDraft    int `json:"skipped_draft"`
+	SkippedBranch   int `json:"skipped_no_branch"`
+	SkippedNoChange int `json:"skipped_no_change"`
+	Conflicts       int `json:"conflicts"`
+	NotMergeable    int `json:"not_mergeable"`
+	NotFound        int `json:"not_found"`
+	Unauthorized    int `json:"unauthorized"`
+	Errors          int `json:"errors"`
+	Canceled        int `json:"canceled"`
 }
 
 type Service struct {
 	client GitLabClient
 	config Config
+
+	usersMu sync.Mutex
+	userIDs map[string]int
 }
 
 func NewService(client GitLabClient, config Config) *Service {
@@ -65,17 +114,49 @@ func NewService(client GitLabClient, config Config) *Service {
 	}
 }
 
-func (s *Service) ProcessProjects() ([]ProjectResult, Summary) {
-	results := make([]ProjectResult, 0, len(s.config.Projects))
-	summary := Summary{Total: len(s.config.Projects)}
+func (s *Service) ProcessProjects(ctx context.Context) ([]ProjectResult, Summary) {
+	projects := s.config.Projects
+	results := s.run(ctx, len(projects), func(i int) ProjectResult {
+		if ctx.Err() != nil {
+			return canceledResult(projects[i])
+		}
+		return s.processProject(ctx, projects[i])
+	})
+
+	return results, summarize(results)
+}
+
+// run processes n items on the worker pool and returns their results in
+// input order.
+func (s *Service) run(ctx context.Context, n int, process func(i int) ProjectResult) []ProjectResult {
+	var progress func(finished int, result ProjectResult)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectResult) {
+			s.config.Progress(finished, n, result)
+		}
+	}
 
-	for _, projectPath := range s.config.Projects {
-		result := s.processProject(projectPath)
-		results = append(results, result)
+	return workpool.Run(n, s.config.Concurrency, func(i int) ProjectResult {
+		result := process(i)
+		if result.Status == StatusError && ctx.Err() != nil {
+			result.Status = StatusCanceled
+		}
+		return result
+	}, progress)
+}
 
+func summarize(results []ProjectResult) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
 		switch result.Status {
 		case StatusCreated:
 			summary.Created++
+			if result.Blocker != "" {
+				summary.Blocked++
+			}
+		case StatusWouldCreate:
+			summary.WouldCreate++
 		case StatusSkippedExists:
 			summary.SkippedExists++
 		case StatusSkippedDraft:
@@ -84,35 +165,54 @@ func (s *Service) ProcessProjects() ([]ProjectResult, Summary) {
 			summary.SkippedBranch++
 		case StatusSkippedNoChange:
 			summary.SkippedNoChange++
+		case StatusConflict:
+			summary.Conflicts++
+		case StatusNotMergeable:
+			summary.NotMergeable++
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusUnauthorized:
+			summary.Unauthorized++
 		case StatusError:
 			summary.Errors++
+		case StatusCanceled:
+			summary.Canceled++
 		}
 	}
 
-	return results, summary
+	return summary
 }
 
-func (s *Service) processProject(projectPath string) ProjectResult {
+func canceledResult(projectPath string) ProjectResult {
+	return ProjectResult{
+		Project: projectPath,
+		Status:  StatusCanceled,
+		Details: "Not processed: run was canceled",
+	}
+}
+
+func (s *Service) processProject(ctx context.Context, projectPath string) ProjectResult {
 	result := ProjectResult{
 		Project: projectPath,
 	}
 
-	project, err := s.client.GetProject(projectPath)
+	project, err := s.client.GetProject(ctx, projectPath)
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = err.Error()
-		return result
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusNotFound
+			result.Details = fmt.Sprintf("Project '%s' does not exist or is not visible to this token", projectPath)
+			return result
+		}
+		return failed(result, "", err)
 	}
 
 	if s.config.Verbose {
 		log.Printf("[%s] Checking branches...", projectPath)
 	}
 
-	originExists, err := s.client.BranchExists(project.ID, s.config.OriginBranch)
+	originExists, err := s.client.BranchExists(ctx, project.ID, s.config.OriginBranch)
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = fmt.Sprintf("failed to check origin branch: %v", err)
-		return result
+		return failed(result, "failed to check origin branch", err)
 	}
 
 	if !originExists {
@@ -121,11 +221,9 @@ func (s *Service) processProject(projectPath string) ProjectResult {
 		return result
 	}
 
-	targetExists, err := s.client.BranchExists(project.ID, s.config.TargetBranch)
+	targetExists, err := s.client.BranchExists(ctx, project.ID, s.config.TargetBranch)
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = fmt.Sprintf("failed to check target branch: %v", err)
-		return result
+		return failed(result, "failed to check target branch", err)
 	}
 
 	if !targetExists {
@@ -138,52 +236,22 @@ func (s *Service) processProject(projectPath string) ProjectResult {
 		log.Printf("[%s] Checking existing merge requests...", projectPath)
 	}
 
-	existingMRs, err := s.client.FindOpenMergeRequests(project.ID, s.config.OriginBranch, s.config.TargetBranch)
+	existingMRs, err := s.client.FindOpenMergeRequests(ctx, project.ID, s.config.OriginBranch, s.config.TargetBranch)
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = fmt.Sprintf("failed to find existing merge requests: %v", err)
-		return result
+		return failed(result, "failed to find existing merge requests", err)
 	}
 
 	if len(existingMRs) > 0 {
-		hasNonDraft := false
-		var draftMR *gitlab.MergeRequest
-
-		for i := range existingMRs {
-			mr := &existingMRs[i]
-			if !mr.IsDraft() {
-				hasNonDraft = true
-				result.Status = StatusSkippedExists
-				result.MergeRequestID = mr.ID
-				result.MergeRequestIID = mr.IID
-				result.MergeRequestURL = mr.WebURL
-				result.Details = fmt.Sprintf("Open MR already exists: !%d", mr.IID)
-				break
-			} else {
-				draftMR = mr
-			}
-		}
-
-		if !hasNonDraft && draftMR != nil {
-			result.Status = StatusSkippedDraft
-			result.MergeRequestID = draftMR.ID
-			result.MergeRequestIID = draftMR.IID
-			result.MergeRequestURL = draftMR.WebURL
-			result.Details = fmt.Sprintf("Draft MR exists: !%d (%s)", draftMR.IID, draftMR.Title)
-		}
-
-		return result
+		return existingResult(result, existingMRs)
 	}
 
 	if s.config.Verbose {
 		log.Printf("[%s] Comparing branches...", projectPath)
 	}
 
-	compare, err := s.client.CompareBranches(project.ID, s.config.OriginBranch, s.config.TargetBranch)
+	compare, err := s.client.CompareBranches(ctx, project.ID, s.config.OriginBranch, s.config.TargetBranch)
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = fmt.Sprintf("failed to compare branches: %v", err)
-		return result
+		return failed(result, "failed to compare branches", err)
 	}
 
 	if !compare.HasChanges() {
@@ -196,22 +264,148 @@ func (s *Service) processProject(projectPath string) ProjectResult {
 		log.Printf("[%s] Found %d commit(s) with changes, creating merge request...", projectPath, len(compare.Commits))
 	}
 
-	title := fmt.Sprintf("Merge %s into %s", s.config.OriginBranch, s.config.TargetBranch)
-	description := fmt.Sprintf("This merge request was created automatically by gitlab-tools.\n\n**Source Branch**: `%s`\n**Target Branch**: `%s`",
-		s.config.OriginBranch, s.config.TargetBranch)
+	templates := s.config.Templates
+	if templates == nil {
+		templates = defaultTemplates()
+	}
 
-	mr, err := s.client.CreateMergeRequest(project.ID, s.config.OriginBranch, s.config.TargetBranch, title, description)
+	title, description, err := templates.Render(newTemplateData(project, s.config, compare))
 	if err != nil {
-		result.Status = StatusError
-		result.ErrorMessage = fmt.Sprintf("failed to create merge request: %v", err)
+		return failed(result, "failed to render merge request template", err)
+	}
+
+	opts, err := s.createOptions(ctx, project, compare, title, description)
+	if err != nil {
+		return failed(result, "failed to resolve merge request metadata", err)
+	}
+
+	result.Commits = len(compare.Commits)
+
+	if s.config.DryRun {
+		result.Status = StatusWouldCreate
+		result.Details = fmt.Sprintf("Would create MR with %d commit(s): %s", result.Commits, title)
+		result.Plan = &PlanEntry{
+			Project:      projectPath,
+			ProjectID:    project.ID,
+			SourceSHA:    headSHA(compare),
+			Commits:      result.Commits,
+			MergeRequest: opts,
+		}
 		return result
 	}
 
+	return s.create(ctx, result, project.ID, opts)
+}
+
+func (s *Service) create(ctx context.Context, result ProjectResult, projectID int, opts gitlab.CreateMergeRequestOptions) ProjectResult {
+	mr, err := s.client.CreateMergeRequest(ctx, projectID, opts)
+	if err != nil {
+		// GitLab answers 409 when another open MR for the same branch pair
+		// appeared after our lookup, which is the outcome we wanted anyway.
+		if gitlab.IsConflict(err) {
+			result.Status = StatusSkippedExists
+			result.Details = fmt.Sprintf("Open MR already exists: %s", apiMessage(err))
+			return result
+		}
+		return failed(result, "failed to create merge request", err)
+	}
+
 	result.Status = StatusCreated
 	result.MergeRequestID = mr.ID
 	result.MergeRequestIID = mr.IID
 	result.MergeRequestURL = mr.WebURL
 	result.Details = fmt.Sprintf("MR !%d: %s", mr.IID, mr.WebURL)
 
+	// GitLab computes mergeability in the background after creation. The MR
+	// exists either way, so the result stays CREATED and only notes what
+	// blocks it.
+	checked, err := s.client.WaitForMergeStatus(ctx, projectID, mr.IID)
+	if err != nil {
+		if s.config.Verbose {
+			log.Printf("[%s] Could not check mergeability of !%d: %v", result.Project, mr.IID, err)
+		}
+		return result
+	}
+
+	result.Blocker = mergeBlocker(checked)
+	return result
+}
+
+// mergeBlocker returns why mr can never be merged as it is, or "".
+func mergeBlocker(mr *gitlab.MergeRequest) string {
+	switch {
+	case mr.IsConflicting():
+		return fmt.Sprintf("has conflicts with %s", mr.TargetBranch)
+	default:
+		return mr.Blocker()
+	}
+}
+
+// mergeabilityResult turns the result for an existing MR into CONFLICT or
+// NOT_MERGEABLE when it can never be merged as it is.
+func mergeabilityResult(result ProjectResult, mr *gitlab.MergeRequest) ProjectResult {
+	result.Blocker = mergeBlocker(mr)
+	switch {
+	case mr.IsConflicting():
+		result.Status = StatusConflict
+		result.Details = fmt.Sprintf("MR !%d has conflicts with %s: %s", mr.IID, mr.TargetBranch, mr.WebURL)
+	case result.Blocker != "":
+		result.Status = StatusNotMergeable
+		result.Details = fmt.Sprintf("MR !%d cannot be merged (%s): %s", mr.IID, result.Blocker, mr.WebURL)
+	}
+	return result
+}
+
+// existingResult reports an open MR for the branch pair. A non-draft MR
+// takes precedence over drafts.
+func existingResult(result ProjectResult, existingMRs []gitlab.MergeRequest) ProjectResult {
+	var draftMR *gitlab.MergeRequest
+
+	for i := range existingMRs {
+		mr := &existingMRs[i]
+		if mr.IsDraft() {
+			draftMR = mr
+			continue
+		}
+
+		result.Status = StatusSkippedExists
+		result.MergeRequestID = mr.ID
+		result.MergeRequestIID = mr.IID
+		result.MergeRequestURL = mr.WebURL
+		result.Details = fmt.Sprintf("Open MR already exists: !%d", mr.IID)
+
+		// The listing carries the last computed mergeability; it is not
+		// rechecked so reruns stay cheap.
+		return mergeabilityResult(result, mr)
+	}
+
+	result.Status = StatusSkippedDraft
+	result.MergeRequestID = draftMR.ID
+	result.MergeRequestIID = draftMR.IID
+	result.MergeRequestURL = draftMR.WebURL
+	result.Details = fmt.Sprintf("Draft MR exists: !%d (%s)", draftMR.IID, draftMR.Title)
+
+	return result
+}
+
+func failed(result ProjectResult, context string, err error) ProjectResult {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+
+	result.ErrorMessage = err.Error()
+	if context != "" {
+		result.ErrorMessage = fmt.Sprintf("%s: %v", context, err)
+	}
+
 	return result
 }
+
+func apiMessage(err error) string {
+	var apiErr *gitlab.APIError
+	if errors.As(err, &apiErr) && apiErr.Message != "" {
+		return apiErr.Message
+	}
+	return err.Error()
+}
@@ -1,6 +1,10 @@
 package bulkmr
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
 	"testing"
 
 	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
@@ -11,6 +15,17 @@ type mockGitLabClient struct {
 	branches      map[int]map[string]bool
 	mergeRequests map[int][]gitlab.MergeRequest
 	createError   error
+	diffs         []gitlab.Diff
+	users         map[string]int
+	milestones    map[string]int
+	files         map[string]string
+	mergeStatuses map[int]string
+	// caughtUp holds projects whose target already has every origin commit.
+	caughtUp map[int]bool
+
+	mu          sync.Mutex
+	created     map[int]gitlab.CreateMergeRequestOptions
+	userLookups int
 }
 
 func newMockClient() *mockGitLabClient {
@@ -18,6 +33,12 @@ func newMockClient() *mockGitLabClient {
 		projects:      make(map[string]*gitlab.Project),
 		branches:      make(map[int]map[string]bool),
 		mergeRequests: make(map[int][]gitlab.MergeRequest),
+		users:         make(map[string]int),
+		milestones:    make(map[string]int),
+		files:         make(map[string]string),
+		mergeStatuses: make(map[int]string),
+		caughtUp:      make(map[int]bool),
+		created:       make(map[int]gitlab.CreateMergeRequestOptions),
 	}
 }
 
@@ -42,15 +63,15 @@ func (m *mockGitLabClient) addMergeRequest(projectID int, mr gitlab.MergeRequest
 	m.mergeRequests[projectID] = append(m.mergeRequests[projectID], mr)
 }
 
-func (m *mockGitLabClient) GetProject(projectPath string) (*gitlab.Project, error) {
+func (m *mockGitLabClient) GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error) {
 	project, ok := m.projects[projectPath]
 	if !ok {
-		return nil, nil
+		return nil, &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Project Not Found"}
 	}
 	return project, nil
 }
 
-func (m *mockGitLabClient) BranchExists(projectID int, branch string) (bool, error) {
+func (m *mockGitLabClient) BranchExists(ctx context.Context, projectID int, branch string) (bool, error) {
 	branches, ok := m.branches[projectID]
 	if !ok {
 		return false, nil
@@ -58,15 +79,19 @@ func (m *mockGitLabClient) BranchExists(projectID int, branch string) (bool, err
 	return branches[branch], nil
 }
 
-func (m *mockGitLabClient) CompareBranches(projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error) {
+func (m *mockGitLabClient) CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error) {
+	if m.caughtUp[projectID] {
+		return &gitlab.Compare{}, nil
+	}
 	return &gitlab.Compare{
 		Commits: []gitlab.Commit{
 			{ID: "abc123", ShortID: "abc123", Title: "Test commit"},
 		},
+		Diffs: m.diffs,
 	}, nil
 }
 
-func (m *mockGitLabClient) FindOpenMergeRequests(projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error) {
+func (m *mockGitLabClient) FindOpenMergeRequests(ctx context.Context, projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error) {
 	mrs, ok := m.mergeRequests[projectID]
 	if !ok {
 		return []gitlab.MergeRequest{}, nil
@@ -74,21 +99,63 @@ func (m *mockGitLabClient) FindOpenMergeRequests(projectID int, sourceBranch, ta
 	return mrs, nil
 }
 
-func (m *mockGitLabClient) CreateMergeRequest(projectID int, sourceBranch, targetBranch, title, description string) (*gitlab.MergeRequest, error) {
+func (m *mockGitLabClient) CreateMergeRequest(ctx context.Context, projectID int, opts gitlab.CreateMergeRequestOptions) (*gitlab.MergeRequest, error) {
 	if m.createError != nil {
 		return nil, m.createError
 	}
 	mr := &gitlab.MergeRequest{
 		ID:     999,
 		IID:    99,
-		Title:  title,
+		Title:  opts.Title,
 		WebURL: "https://gitlab.example.com/merge_requests/99",
 		State:  "opened",
 		Draft:  false,
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created[projectID] = opts
+
 	return mr, nil
 }
 
+func (m *mockGitLabClient) WaitForMergeStatus(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error) {
+	return &gitlab.MergeRequest{
+		IID:                 mrIID,
+		TargetBranch:        "op-rc",
+		WebURL:              "https://gitlab.example.com/merge_requests/99",
+		DetailedMergeStatus: m.mergeStatuses[projectID],
+	}, nil
+}
+
+func (m *mockGitLabClient) GetUserByUsername(ctx context.Context, username string) (*gitlab.User, error) {
+	m.mu.Lock()
+	m.userLookups++
+	m.mu.Unlock()
+
+	id, ok := m.users[username]
+	if !ok {
+		return nil, nil
+	}
+	return &gitlab.User{ID: id, Username: username}, nil
+}
+
+func (m *mockGitLabClient) FindMilestone(ctx context.Context, projectID int, title string) (*gitlab.Milestone, error) {
+	id, ok := m.milestones[title]
+	if !ok {
+		return nil, fmt.Errorf("milestone %s not found", title)
+	}
+	return &gitlab.Milestone{ID: id, Title: title}, nil
+}
+
+func (m *mockGitLabClient) GetRawFile(ctx context.Context, projectID int, filePath, ref string) ([]byte, error) {
+	content, ok := m.files[filePath]
+	if !ok {
+		return nil, &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 File Not Found"}
+	}
+	return []byte(content), nil
+}
+
 func TestProcessProject_BothBranchesExist_NoExistingMR_CreatesMR(t *testing.T) {
 	client := newMockClient()
 	client.addProject("group/repo-a", 1)
@@ -107,7 +174,7 @@ func TestProcessProject_BothBranchesExist_NoExistingMR_CreatesMR(t *testing.T) {
 		config: config,
 	}
 
-	result := service.processProject("group/repo-a")
+	result := service.processProject(context.Background(), "group/repo-a")
 
 	if result.Status != StatusCreated {
 		t.Errorf("Expected status CREATED, got %s", result.Status)
@@ -138,7 +205,7 @@ func TestProcessProject_ExistingNonDraftMR_Skips(t *testing.T) {
 		config: config,
 	}
 
-	result := service.processProject("group/repo-b")
+	result := service.processProject(context.Background(), "group/repo-b")
 
 	if result.Status != StatusSkippedExists {
 		t.Errorf("Expected status SKIPPED_EXISTS, got %s", result.Status)
@@ -169,7 +236,7 @@ func TestProcessProject_ExistingDraftMR_Skips(t *testing.T) {
 		config: config,
 	}
 
-	result := service.processProject("group/repo-c")
+	result := service.processProject(context.Background(), "group/repo-c")
 
 	if result.Status != StatusSkippedDraft {
 		t.Errorf("Expected status SKIPPED_DRAFT, got %s", result.Status)
@@ -193,9 +260,223 @@ func TestProcessProject_MissingOriginBranch_Skips(t *testing.T) {
 		config: config,
 	}
 
-	result := service.processProject("group/repo-d")
+	result := service.processProject(context.Background(), "group/repo-d")
 
 	if result.Status != StatusSkippedBranch {
 		t.Errorf("Expected status SKIPPED_NO_BRANCH, got %s", result.Status)
 	}
 }
+
+func TestProcessProjects_CanceledContext_MarksRemainingProjects(t *testing.T) {
+	client := newMockClient()
+	client.addProject("group/repo-e", 5)
+	client.addBranch(5, "op-stage")
+	client.addBranch(5, "op-rc")
+
+	config := Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Projects:     []string{"group/repo-e", "group/repo-f"},
+		Verbose:      false,
+	}
+
+	service := NewService(client, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, summary := service.ProcessProjects(ctx)
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	for _, result := range results {
+		if result.Status != StatusCanceled {
+			t.Errorf("Expected status CANCELED for %s, got %s", result.Project, result.Status)
+		}
+	}
+
+	if summary.Canceled != 2 || summary.Created != 0 {
+		t.Errorf("Expected 2 canceled and 0 created, got %+v", summary)
+	}
+}
+
+func TestProcessProjects_Concurrent_PreservesOrder(t *testing.T) {
+	client := newMockClient()
+
+	var projects []string
+	for i := 1; i <= 20; i++ {
+		path := fmt.Sprintf("group/repo-%02d", i)
+		client.addProject(path, i)
+		if i%2 == 0 {
+			client.addBranch(i, "op-stage")
+			client.addBranch(i, "op-rc")
+		}
+		projects = append(projects, path)
+	}
+
+	var progressCalls []int
+	config := Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Projects:     projects,
+		Concurrency:  4,
+		Progress: func(done, total int, result ProjectResult) {
+			progressCalls = append(progressCalls, done)
+		},
+	}
+
+	service := NewService(client, config)
+	results, summary := service.ProcessProjects(context.Background())
+
+	for i, result := range results {
+		if result.Project != projects[i] {
+			t.Errorf("Result %d: expected project %s, got %s", i, projects[i], result.Project)
+		}
+	}
+
+	if summary.Total != 20 || summary.Created != 10 || summary.SkippedBranch != 10 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	if len(progressCalls) != 20 || progressCalls[19] != 20 {
+		t.Errorf("Expected 20 sequential progress calls, got %v", progressCalls)
+	}
+}
+
+func TestProcessProject_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		createError error
+		expected    ResultStatus
+	}{
+		{
+			name:     "unknown project",
+			project:  "group/missing",
+			expected: StatusNotFound,
+		},
+		{
+			name:        "conflict on create means MR exists",
+			project:     "group/repo-g",
+			createError: &gitlab.APIError{StatusCode: http.StatusConflict, Message: "Another open merge request already exists"},
+			expected:    StatusSkippedExists,
+		},
+		{
+			name:        "forbidden on create",
+			project:     "group/repo-g",
+			createError: &gitlab.APIError{StatusCode: http.StatusForbidden, Message: "403 Forbidden"},
+			expected:    StatusUnauthorized,
+		},
+		{
+			name:        "server error on create",
+			project:     "group/repo-g",
+			createError: &gitlab.APIError{StatusCode: http.StatusInternalServerError},
+			expected:    StatusError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.addProject("group/repo-g", 7)
+			client.addBranch(7, "op-stage")
+			client.addBranch(7, "op-rc")
+			client.createError = tt.createError
+
+			service := NewService(client, Config{
+				OriginBranch: "op-stage",
+				TargetBranch: "op-rc",
+			})
+
+			result := service.processProject(context.Background(), tt.project)
+			if result.Status != tt.expected {
+				t.Errorf("Expected status %s, got %s", tt.expected, result.Status)
+			}
+		})
+	}
+}
+
+func TestProcessProject_Mergeability(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *gitlab.MergeRequest
+		status   string
+		expected ResultStatus
+		details  string
+		blocker  string
+	}{
+		{
+			name:     "created and mergeable",
+			status:   "mergeable",
+			expected: StatusCreated,
+			details:  "MR !99: https://gitlab.example.com/merge_requests/99",
+		},
+		{
+			name:     "created with conflicts",
+			status:   "conflict",
+			expected: StatusCreated,
+			details:  "MR !99: https://gitlab.example.com/merge_requests/99",
+			blocker:  "has conflicts with op-rc",
+		},
+		{
+			name:     "created but needs rebase",
+			status:   "need_rebase",
+			expected: StatusCreated,
+			details:  "MR !99: https://gitlab.example.com/merge_requests/99",
+			blocker:  "source branch must be rebased",
+		},
+		{
+			name:     "existing with conflicts",
+			existing: &gitlab.MergeRequest{ID: 100, IID: 10, TargetBranch: "op-rc", HasConflicts: true, WebURL: "https://gitlab.example.com/merge_requests/10"},
+			expected: StatusConflict,
+			details:  "MR !10 has conflicts with op-rc: https://gitlab.example.com/merge_requests/10",
+			blocker:  "has conflicts with op-rc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.addProject("group/repo-a", 1)
+			client.addBranch(1, "op-stage")
+			client.addBranch(1, "op-rc")
+			client.mergeStatuses[1] = tt.status
+			if tt.existing != nil {
+				client.addMergeRequest(1, *tt.existing)
+			}
+
+			service := NewService(client, Config{
+				OriginBranch: "op-stage",
+				TargetBranch: "op-rc",
+				Projects:     []string{"group/repo-a"},
+			})
+
+			results, summary := service.ProcessProjects(context.Background())
+
+			if results[0].Status != tt.expected || results[0].Details != tt.details {
+				t.Errorf("expected %s (%q), got %s (%q)", tt.expected, tt.details, results[0].Status, results[0].Details)
+			}
+			if results[0].Blocker != tt.blocker {
+				t.Errorf("expected blocker %q, got %q", tt.blocker, results[0].Blocker)
+			}
+			if results[0].MergeRequestURL == "" {
+				t.Error("expected the MR URL to be kept")
+			}
+			if tt.expected == StatusConflict && summary.Conflicts != 1 {
+				t.Errorf("expected 1 conflict in summary, got %+v", summary)
+			}
+			// A new MR exists whatever blocks it, so it counts as created.
+			if tt.expected == StatusCreated {
+				blocked := 0
+				if tt.blocker != "" {
+					blocked = 1
+				}
+				if summary.Created != 1 || summary.Blocked != blocked {
+					t.Errorf("expected 1 created and %d blocked MR(s), got %+v", blocked, summary)
+				}
+			}
+		})
+	}
+}
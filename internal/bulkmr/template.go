@@ -0,0 +1,187 @@
+package bulkmr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/changelog"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+const (
+	DefaultTitleTemplate       = "Merge {{.SourceBranch}} into {{.TargetBranch}}"
+	DefaultDescriptionTemplate = "This merge request was created automatically by gitlab-tools.\n\n**Source Branch**: `{{.SourceBranch}}`\n**Target Branch**: `{{.TargetBranch}}`{{with .Changelog.Markdown}}\n\n{{.}}{{end}}"
+)
+
+type RunInfo struct {
+	StartedAt time.Time
+	Version   string
+	Topic     string
+}
+
+type DiffStats struct {
+	Commits      int
+	Files        int
+	Additions    int
+	Deletions    int
+	NewFiles     int
+	DeletedFiles int
+	RenamedFiles int
+}
+
+type TemplateData struct {
+	Project      gitlab.Project
+	SourceBranch string
+	TargetBranch string
+	Commits      []gitlab.Commit
+	Diffs        []gitlab.Diff
+	Stats        DiffStats
+	Changelog    changelog.Changelog
+	Run          RunInfo
+}
+
+type Templates struct {
+	title       *template.Template
+	description *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if len(runes) <= n {
+			return s
+		}
+		return string(runes[:n])
+	},
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+func defaultTemplates() *Templates {
+	templates, err := NewTemplates("", "")
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+// NewTemplates parses the title and description templates. Empty strings
+// fall back to the built-in defaults.
+func NewTemplates(title, description string) (*Templates, error) {
+	return LoadTemplates(title, description, "")
+}
+
+// LoadTemplates combines inline templates with an optional template file.
+// The file defines `{{define "title"}}` and/or `{{define "description"}}`
+// blocks; inline templates take precedence over the file, and anything
+// still missing falls back to the defaults.
+func LoadTemplates(title, description, filePath string) (*Templates, error) {
+	templates := &Templates{}
+
+	if filePath != "" {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+
+		set, err := template.New("file").Funcs(templateFuncs).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template file %s: %w", filePath, err)
+		}
+
+		templates.title = set.Lookup("title")
+		templates.description = set.Lookup("description")
+		if templates.title == nil && templates.description == nil {
+			return nil, fmt.Errorf(`invalid template file %s: expected a {{define "title"}} or {{define "description"}} block`, filePath)
+		}
+	}
+
+	var err error
+	if title != "" || templates.title == nil {
+		if title == "" {
+			title = DefaultTitleTemplate
+		}
+		if templates.title, err = template.New("title").Funcs(templateFuncs).Parse(title); err != nil {
+			return nil, fmt.Errorf("invalid title template: %w", err)
+		}
+	}
+
+	if description != "" || templates.description == nil {
+		if description == "" {
+			description = DefaultDescriptionTemplate
+		}
+		if templates.description, err = template.New("description").Funcs(templateFuncs).Parse(description); err != nil {
+			return nil, fmt.Errorf("invalid description template: %w", err)
+		}
+	}
+
+	return templates, nil
+}
+
+func (t *Templates) Render(data TemplateData) (title, description string, err error) {
+	var buf bytes.Buffer
+	if err := t.title.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render title: %w", err)
+	}
+
+	// GitLab titles are single-line, so template whitespace is collapsed.
+	title = strings.Join(strings.Fields(buf.String()), " ")
+	if title == "" {
+		return "", "", fmt.Errorf("title template rendered an empty title")
+	}
+
+	buf.Reset()
+	if err := t.description.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render description: %w", err)
+	}
+
+	return title, strings.TrimSpace(buf.String()), nil
+}
+
+func newTemplateData(project *gitlab.Project, config Config, compare *gitlab.Compare) TemplateData {
+	stats := DiffStats{
+		Commits: len(compare.Commits),
+		Files:   len(compare.Diffs),
+	}
+
+	for i := range compare.Diffs {
+		diff := &compare.Diffs[i]
+		added, deleted := diff.LineChanges()
+		stats.Additions += added
+		stats.Deletions += deleted
+
+		switch {
+		case diff.NewFile:
+			stats.NewFiles++
+		case diff.DeletedFile:
+			stats.DeletedFiles++
+		case diff.RenamedFile:
+			stats.RenamedFiles++
+		}
+	}
+
+	data := TemplateData{
+		Project:      *project,
+		SourceBranch: config.OriginBranch,
+		TargetBranch: config.TargetBranch,
+		Commits:      compare.Commits,
+		Diffs:        compare.Diffs,
+		Stats:        stats,
+		Run:          config.Run,
+	}
+
+	if config.Changelog {
+		data.Changelog = changelog.Build(compare.Commits, compare.Diffs, changelog.Options{IssuePattern: config.IssuePattern})
+	}
+
+	return data
+}
@@ -0,0 +1,169 @@
+package bulkmr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func sampleTemplateData() TemplateData {
+	return TemplateData{
+		Project:      gitlab.Project{Name: "repo-a", PathWithNamespace: "group/repo-a"},
+		SourceBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Commits: []gitlab.Commit{
+			{ShortID: "abc123", Title: "feat: add login"},
+			{ShortID: "def456", Title: "fix: handle timeout"},
+		},
+		Stats: DiffStats{Commits: 2, Files: 3, Additions: 10, Deletions: 4},
+		Run: RunInfo{
+			StartedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+			Version:   "1.0.0",
+		},
+	}
+}
+
+func TestTemplates_Defaults(t *testing.T) {
+	templates, err := NewTemplates("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	title, description, err := templates.Render(sampleTemplateData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if title != "Merge op-stage into op-rc" {
+		t.Errorf("unexpected title: %q", title)
+	}
+	if !strings.Contains(description, "**Target Branch**: `op-rc`") {
+		t.Errorf("unexpected description: %q", description)
+	}
+}
+
+func TestTemplates_Custom(t *testing.T) {
+	templates, err := NewTemplates(
+		`Release {{date "2006.01" .Run.StartedAt}} → {{.TargetBranch}}`,
+		"{{range .Commits}}- {{.ShortID}} {{.Title}}\n{{end}}{{.Stats.Files}} files, +{{.Stats.Additions}}/-{{.Stats.Deletions}}",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	title, description, err := templates.Render(sampleTemplateData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if title != "Release 2026.10 → op-rc" {
+		t.Errorf("unexpected title: %q", title)
+	}
+	expected := "- abc123 feat: add login\n- def456 fix: handle timeout\n3 files, +10/-4"
+	if description != expected {
+		t.Errorf("unexpected description:\n%s\nexpected:\n%s", description, expected)
+	}
+}
+
+func TestTemplates_Errors(t *testing.T) {
+	if _, err := NewTemplates("{{.SourceBranch", ""); err == nil {
+		t.Error("expected parse error for malformed title template")
+	}
+
+	templates, err := NewTemplates("{{.Missing}}", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := templates.Render(sampleTemplateData()); err == nil {
+		t.Error("expected render error for unknown field")
+	}
+
+	templates, err = NewTemplates("   ", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := templates.Render(sampleTemplateData()); err == nil {
+		t.Error("expected error for empty title")
+	}
+}
+
+func TestLoadTemplates_FileWithInlineOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mr.tmpl")
+	content := `{{define "title"}}From file {{.SourceBranch}}{{end}}{{define "description"}}Project {{.Project.PathWithNamespace}}{{end}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadTemplates("Inline {{.TargetBranch}}", "", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	title, description, err := templates.Render(sampleTemplateData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if title != "Inline op-rc" {
+		t.Errorf("expected inline title to win, got %q", title)
+	}
+	if description != "Project group/repo-a" {
+		t.Errorf("expected description from file, got %q", description)
+	}
+}
+
+func TestProcessProject_UsesTemplates(t *testing.T) {
+	client := newMockClient()
+	client.addProject("group/repo-h", 8)
+	client.addBranch(8, "op-stage")
+	client.addBranch(8, "op-rc")
+
+	templates, err := NewTemplates("Promote {{.Project.PathWithNamespace}}", "{{len .Commits}} commit(s)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service := NewService(client, Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Templates:    templates,
+	})
+
+	result := service.processProject(context.Background(), "group/repo-h")
+	if result.Status != StatusCreated {
+		t.Fatalf("Expected status CREATED, got %s", result.Status)
+	}
+
+	created := client.created[8]
+	if created.Title != "Promote group/repo-h" || created.Description != "1 commit(s)" {
+		t.Errorf("unexpected MR content: %+v", created)
+	}
+}
+
+func TestProcessProject_DefaultDescriptionIncludesChangelog(t *testing.T) {
+	client := newMockClient()
+	client.addProject("group/repo-i", 9)
+	client.addBranch(9, "op-stage")
+	client.addBranch(9, "op-rc")
+
+	service := NewService(client, Config{
+		OriginBranch: "op-stage",
+		TargetBranch: "op-rc",
+		Changelog:    true,
+	})
+
+	result := service.processProject(context.Background(), "group/repo-i")
+	if result.Status != StatusCreated {
+		t.Fatalf("Expected status CREATED, got %s", result.Status)
+	}
+
+	description := client.created[9].Description
+	if !strings.Contains(description, "## Changelog") || !strings.Contains(description, "- Test commit (abc123)") {
+		t.Errorf("expected changelog in description, got:\n%s", description)
+	}
+}
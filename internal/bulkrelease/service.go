@@ -0,0 +1,334 @@
+package bulkrelease
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/changelog"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
+)
+
+type GitLabClient interface {
+	GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error)
+	GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error)
+	GetTag(ctx context.Context, projectID int, tag string) (*gitlab.Tag, error)
+	ListTags(ctx context.Context, projectID int) ([]gitlab.Tag, error)
+	CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error)
+	MergeBase(ctx context.Context, projectID int, refs ...string) (*gitlab.Commit, error)
+	CreateTag(ctx context.Context, projectID int, tag, ref, message string) (*gitlab.Tag, error)
+	CreateRelease(ctx context.Context, projectID int, opts gitlab.CreateReleaseOptions) (*gitlab.Release, error)
+}
+
+type Config struct {
+	// Ref is the branch whose head is tagged.
+	Ref string
+	Tag string
+	// Name is the release title; empty means the tag name.
+	Name string
+	// Message, if set, makes the tag an annotated tag.
+	Message  string
+	Projects []string
+	// IssuePattern extracts issue references for the release notes.
+	IssuePattern *regexp.Regexp
+	// DryRun resolves everything and builds the notes, but creates nothing.
+	DryRun      bool
+	Verbose     bool
+	Concurrency int
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectResult)
+}
+
+type ResultStatus string
+
+const (
+	StatusCreated       ResultStatus = "CREATED"
+	StatusWouldCreate   ResultStatus = "WOULD_CREATE"
+	StatusSkippedExists ResultStatus = "SKIPPED_EXISTS"
+	StatusSkippedNoRef  ResultStatus = "SKIPPED_NO_REF"
+	StatusConflict      ResultStatus = "CONFLICT"
+	StatusNotFound      ResultStatus = "NOT_FOUND"
+	StatusUnauthorized  ResultStatus = "UNAUTHORIZED"
+	StatusError         ResultStatus = "ERROR"
+	StatusCanceled      ResultStatus = "CANCELED"
+)
+
+type ProjectResult struct {
+	Project      string       `json:"project"`
+	Status       ResultStatus `json:"status"`
+	Tag          string       `json:"tag"`
+	SHA          string       `json:"sha,omitempty"`
+	PreviousTag  string       `json:"previous_tag,omitempty"`
+	Commits      int          `json:"commits,omitempty"`
+	ReleaseURL   string       `json:"release_url,omitempty"`
+	Notes        string       `json:"notes,omitempty"`
+	Details      string       `json:"details,omitempty"`
+	ErrorMessage string       `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total         int `json:"total"`
+	Created       int `json:"created"`
+	WouldCreate   int `json:"would_create"`
+	SkippedExists int `json:"skipped_exists"`
+	SkippedNoRef  int `json:"skipped_no_ref"`
+	Conflicts     int `json:"conflicts"`
+	NotFound      int `json:"not_found"`
+	Unauthorized  int `json:"unauthorized"`
+	Errors        int `json:"errors"`
+	Canceled      int `json:"canceled"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Release tags the head of the ref in every project and creates a release
+// with notes on the commits since the previous tag. It is idempotent: a
+// project whose tag already points at the same commit is skipped, after
+// adding the release if an earlier run tagged it without one.
+func (s *Service) Release(ctx context.Context) ([]ProjectResult, Summary) {
+	projects := s.config.Projects
+	n := len(projects)
+
+	var progress func(finished int, result ProjectResult)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectResult) {
+			s.config.Progress(finished, n, result)
+		}
+	}
+
+	results := workpool.Run(n, s.config.Concurrency, func(i int) ProjectResult {
+		if ctx.Err() != nil {
+			return ProjectResult{
+				Project: projects[i],
+				Tag:     s.config.Tag,
+				Status:  StatusCanceled,
+				Details: "Not processed: run was canceled",
+			}
+		}
+
+		result := s.releaseProject(ctx, projects[i])
+		if result.Status == StatusError && ctx.Err() != nil {
+			result.Status = StatusCanceled
+		}
+		return result
+	}, progress)
+
+	return results, summarize(results)
+}
+
+func (s *Service) releaseProject(ctx context.Context, projectPath string) ProjectResult {
+	result := ProjectResult{
+		Project: projectPath,
+		Tag:     s.config.Tag,
+	}
+
+	project, err := s.client.GetProject(ctx, projectPath)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusNotFound
+			result.Details = fmt.Sprintf("Project '%s' does not exist or is not visible to this token", projectPath)
+			return result
+		}
+		return failed(result, "", err)
+	}
+
+	commit, err := s.client.GetCommit(ctx, project.ID, s.config.Ref)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusSkippedNoRef
+			result.Details = fmt.Sprintf("Ref '%s' does not exist", s.config.Ref)
+			return result
+		}
+		return failed(result, "failed to resolve ref", err)
+	}
+	result.SHA = commit.ID
+
+	existing, err := s.client.GetTag(ctx, project.ID, s.config.Tag)
+	switch {
+	case err == nil:
+		if existing.Commit.ID != commit.ID {
+			return conflict(result, existing, s.config.Ref)
+		}
+		if existing.Release != nil {
+			result.Status = StatusSkippedExists
+			result.Details = fmt.Sprintf("Tag '%s' already exists at %s", s.config.Tag, shortSHA(commit.ID))
+			return result
+		}
+	case !gitlab.IsNotFound(err):
+		return failed(result, "failed to check tag", err)
+	}
+
+	if err := s.buildNotes(ctx, project.ID, &result); err != nil {
+		return failed(result, "failed to build release notes", err)
+	}
+
+	if s.config.DryRun {
+		result.Status = StatusWouldCreate
+		result.Details = fmt.Sprintf("Would tag %s at %s (%s)", s.config.Tag, shortSHA(commit.ID), s.changesSince(result))
+		return result
+	}
+
+	if existing == nil {
+		if s.config.Verbose {
+			log.Printf("[%s] Tagging %s at %s (%s)...", projectPath, s.config.Tag, s.config.Ref, shortSHA(commit.ID))
+		}
+
+		// The resolved SHA is tagged instead of the ref, so the notes
+		// describe exactly the tagged commit even if the ref moves.
+		if _, err := s.client.CreateTag(ctx, project.ID, s.config.Tag, commit.ID, s.config.Message); err != nil {
+			// Another run may have created the tag since it was checked.
+			raced, getErr := s.client.GetTag(ctx, project.ID, s.config.Tag)
+			switch {
+			case getErr != nil:
+				return failed(result, "failed to create tag", err)
+			case raced.Commit.ID != commit.ID:
+				return conflict(result, raced, s.config.Ref)
+			case raced.Release != nil:
+				result.Status = StatusSkippedExists
+				result.Details = fmt.Sprintf("Tag '%s' already exists at %s", s.config.Tag, shortSHA(commit.ID))
+				return result
+			}
+		}
+	}
+
+	name := s.config.Name
+	if name == "" {
+		name = s.config.Tag
+	}
+
+	release, err := s.client.CreateRelease(ctx, project.ID, gitlab.CreateReleaseOptions{
+		TagName:     s.config.Tag,
+		Name:        name,
+		Description: result.Notes,
+	})
+	if err != nil {
+		return failed(result, "failed to create release", err)
+	}
+
+	result.Status = StatusCreated
+	result.ReleaseURL = release.Links.Self
+	result.Details = fmt.Sprintf("Released %s at %s (%s)", s.config.Tag, shortSHA(commit.ID), s.changesSince(result))
+	if existing != nil {
+		result.Details = fmt.Sprintf("Tag '%s' already existed at %s; created its release (%s)", s.config.Tag, shortSHA(commit.ID), s.changesSince(result))
+	}
+	return result
+}
+
+// buildNotes sets the release notes from the commits between the highest
+// other tag the tagged commit descends from and the tagged commit. Tags on
+// other branches, such as hotfixes of an older release, are passed over.
+func (s *Service) buildNotes(ctx context.Context, projectID int, result *ProjectResult) error {
+	tags, err := s.client.ListTags(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if tag.Name == s.config.Tag {
+			continue
+		}
+		base, err := s.client.MergeBase(ctx, projectID, tag.Commit.ID, result.SHA)
+		if err != nil {
+			return err
+		}
+		if base.ID == tag.Commit.ID {
+			result.PreviousTag = tag.Name
+			break
+		}
+	}
+
+	if result.PreviousTag == "" {
+		result.Notes = fmt.Sprintf("First release, at %s.", shortSHA(result.SHA))
+		return nil
+	}
+
+	compare, err := s.client.CompareBranches(ctx, projectID, result.SHA, result.PreviousTag)
+	if err != nil {
+		return err
+	}
+	result.Commits = len(compare.Commits)
+
+	notes := changelog.Build(compare.Commits, nil, changelog.Options{IssuePattern: s.config.IssuePattern}).Markdown()
+	if notes == "" {
+		notes = "No changes."
+	}
+	result.Notes = fmt.Sprintf("Changes since `%s`.\n\n%s", result.PreviousTag, notes)
+	return nil
+}
+
+func (s *Service) changesSince(result ProjectResult) string {
+	if result.PreviousTag == "" {
+		return "first release"
+	}
+	return fmt.Sprintf("%d commit(s) since %s", result.Commits, result.PreviousTag)
+}
+
+func conflict(result ProjectResult, tag *gitlab.Tag, ref string) ProjectResult {
+	result.Status = StatusConflict
+	result.Details = fmt.Sprintf("Tag '%s' already exists at %s, but '%s' is at %s", tag.Name, shortSHA(tag.Commit.ID), ref, shortSHA(result.SHA))
+	result.SHA = tag.Commit.ID
+	return result
+}
+
+func summarize(results []ProjectResult) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusCreated:
+			summary.Created++
+		case StatusWouldCreate:
+			summary.WouldCreate++
+		case StatusSkippedExists:
+			summary.SkippedExists++
+		case StatusSkippedNoRef:
+			summary.SkippedNoRef++
+		case StatusConflict:
+			summary.Conflicts++
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		case StatusCanceled:
+			summary.Canceled++
+		}
+	}
+
+	return summary
+}
+
+func failed(result ProjectResult, context string, err error) ProjectResult {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+
+	result.ErrorMessage = err.Error()
+	if context != "" {
+		result.ErrorMessage = fmt.Sprintf("%s: %v", context, err)
+	}
+
+	return result
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
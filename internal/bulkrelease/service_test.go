@@ -0,0 +1,207 @@
+package bulkrelease
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	projects map[string]int
+	refs     map[int]string
+	tags     map[int][]gitlab.Tag
+	commits  map[int][]gitlab.Commit
+	// diverged holds tagged commits that the ref does not descend from.
+	diverged map[string]bool
+	// releaseErrors fail CreateRelease, as for a token that may tag but not
+	// release.
+	releaseErrors map[int]error
+
+	mu       sync.Mutex
+	created  map[int]string
+	releases map[int]gitlab.CreateReleaseOptions
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		projects:      make(map[string]int),
+		refs:          make(map[int]string),
+		tags:          make(map[int][]gitlab.Tag),
+		commits:       make(map[int][]gitlab.Commit),
+		diverged:      make(map[string]bool),
+		releaseErrors: make(map[int]error),
+		created:       make(map[int]string),
+		releases:      make(map[int]gitlab.CreateReleaseOptions),
+	}
+}
+
+func notFound() error {
+	return &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Not Found"}
+}
+
+func (m *mockGitLabClient) GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error) {
+	id, ok := m.projects[projectPath]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Project{ID: id, PathWithNamespace: projectPath}, nil
+}
+
+func (m *mockGitLabClient) GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error) {
+	sha, ok := m.refs[projectID]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Commit{ID: sha}, nil
+}
+
+func (m *mockGitLabClient) GetTag(ctx context.Context, projectID int, tag string) (*gitlab.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tags[projectID] {
+		if t.Name == tag {
+			return &t, nil
+		}
+	}
+	return nil, notFound()
+}
+
+func (m *mockGitLabClient) ListTags(ctx context.Context, projectID int) ([]gitlab.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tags[projectID], nil
+}
+
+func (m *mockGitLabClient) CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error) {
+	return &gitlab.Compare{Commits: m.commits[projectID]}, nil
+}
+
+func (m *mockGitLabClient) MergeBase(ctx context.Context, projectID int, refs ...string) (*gitlab.Commit, error) {
+	if m.diverged[refs[0]] {
+		return &gitlab.Commit{ID: "fork-point"}, nil
+	}
+	return &gitlab.Commit{ID: refs[0]}, nil
+}
+
+func (m *mockGitLabClient) CreateTag(ctx context.Context, projectID int, tag, ref, message string) (*gitlab.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created[projectID] = ref
+	created := gitlab.Tag{Name: tag, Commit: gitlab.Commit{ID: ref}}
+	m.tags[projectID] = append([]gitlab.Tag{created}, m.tags[projectID]...)
+	return &created, nil
+}
+
+func (m *mockGitLabClient) CreateRelease(ctx context.Context, projectID int, opts gitlab.CreateReleaseOptions) (*gitlab.Release, error) {
+	if err := m.releaseErrors[projectID]; err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.releases[projectID] = opts
+	release := &gitlab.Release{TagName: opts.TagName, Name: opts.Name, Description: opts.Description}
+	release.Links.Self = "https://gitlab.example.com/releases/" + opts.TagName
+	return release, nil
+}
+
+func TestRelease(t *testing.T) {
+	client := newMockClient()
+	for i, project := range []string{"group/new", "group/first", "group/done", "group/untagged-release", "group/moved", "group/no-ref", "group/denied"} {
+		client.projects[project] = i + 1
+		client.refs[i+1] = "aaaa1111bbbb"
+	}
+	delete(client.refs, 6)
+
+	client.tags[1] = []gitlab.Tag{{Name: "v2026.09", Commit: gitlab.Commit{ID: "old"}}}
+	client.commits[1] = []gitlab.Commit{
+		{ShortID: "c1", Title: "feat(api): add export endpoint"},
+		{ShortID: "c2", Title: "fix: handle empty export (OPS-12)"},
+	}
+	client.tags[3] = []gitlab.Tag{{Name: "v2026.10", Commit: gitlab.Commit{ID: "aaaa1111bbbb"}, Release: &gitlab.TagInfo{TagName: "v2026.10"}}}
+	client.tags[4] = []gitlab.Tag{{Name: "v2026.10", Commit: gitlab.Commit{ID: "aaaa1111bbbb"}}, {Name: "v2026.09"}}
+	client.tags[5] = []gitlab.Tag{{Name: "v2026.10", Commit: gitlab.Commit{ID: "cccc2222dddd"}}}
+	client.releaseErrors[7] = &gitlab.APIError{StatusCode: http.StatusForbidden, Message: "403 Forbidden"}
+
+	config := Config{
+		Ref:         "op-rc",
+		Tag:         "v2026.10",
+		Projects:    []string{"group/new", "group/first", "group/done", "group/untagged-release", "group/moved", "group/no-ref", "group/denied", "group/missing"},
+		Concurrency: 3,
+	}
+
+	results, summary := NewService(client, config).Release(context.Background())
+
+	expected := []ResultStatus{StatusCreated, StatusCreated, StatusSkippedExists, StatusCreated, StatusConflict, StatusSkippedNoRef, StatusUnauthorized, StatusNotFound}
+	for i, status := range expected {
+		if results[i].Status != status {
+			t.Errorf("%s: expected %s, got %s (%s%s)", results[i].Project, status, results[i].Status, results[i].Details, results[i].ErrorMessage)
+		}
+	}
+
+	notes := client.releases[1].Description
+	if results[0].PreviousTag != "v2026.09" || results[0].Commits != 2 || !strings.HasPrefix(notes, "Changes since `v2026.09`.") {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if !strings.Contains(notes, "**api:** add export endpoint (c1)") || !strings.Contains(notes, "OPS-12") {
+		t.Errorf("unexpected notes:\n%s", notes)
+	}
+	if client.created[1] != "aaaa1111bbbb" || client.releases[1].Name != "v2026.10" {
+		t.Errorf("expected tag at the resolved SHA, got %q, release %+v", client.created[1], client.releases[1])
+	}
+
+	if !strings.HasPrefix(client.releases[2].Description, "First release") {
+		t.Errorf("unexpected notes for the first release: %q", client.releases[2].Description)
+	}
+
+	if _, tagged := client.created[4]; tagged || results[3].PreviousTag != "v2026.09" {
+		t.Errorf("existing tag should only get its release: %+v", results[3])
+	}
+	if results[4].SHA != "cccc2222dddd" {
+		t.Errorf("conflict should report the tag's commit, got %s", results[4].SHA)
+	}
+
+	if summary.Created != 3 || summary.SkippedExists != 1 || summary.Conflicts != 1 || summary.SkippedNoRef != 1 || summary.Unauthorized != 1 || summary.NotFound != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestRelease_DryRun(t *testing.T) {
+	client := newMockClient()
+	client.projects["group/a"] = 1
+	client.refs[1] = "aaaa1111bbbb"
+	client.tags[1] = []gitlab.Tag{{Name: "v2026.09"}}
+	client.commits[1] = []gitlab.Commit{{ShortID: "c1", Title: "fix: retry uploads"}}
+
+	config := Config{Ref: "op-rc", Tag: "v2026.10", Projects: []string{"group/a"}, DryRun: true}
+	results, summary := NewService(client, config).Release(context.Background())
+
+	if results[0].Status != StatusWouldCreate || !strings.Contains(results[0].Notes, "retry uploads") {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if len(client.created) != 0 || len(client.releases) != 0 || summary.WouldCreate != 1 {
+		t.Errorf("dry run created something: tags %v, releases %v", client.created, client.releases)
+	}
+}
+
+func TestRelease_PreviousTagIsAncestor(t *testing.T) {
+	client := newMockClient()
+	client.projects["group/a"] = 1
+	client.refs[1] = "aaaa1111bbbb"
+	// The hotfix tag sits on a release branch but is the higher version.
+	client.tags[1] = []gitlab.Tag{
+		{Name: "v2026.09.1", Commit: gitlab.Commit{ID: "hotfix"}},
+		{Name: "v2026.09", Commit: gitlab.Commit{ID: "old"}},
+	}
+	client.diverged["hotfix"] = true
+
+	config := Config{Ref: "main", Tag: "v2026.10", Projects: []string{"group/a"}, DryRun: true}
+	results, _ := NewService(client, config).Release(context.Background())
+
+	if results[0].PreviousTag != "v2026.09" || !strings.HasPrefix(results[0].Notes, "Changes since `v2026.09`.") {
+		t.Errorf("expected the notes to start from the ancestor tag, got %+v", results[0])
+	}
+}
@@ -0,0 +1,274 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+const maxListedFiles = 50
+
+var (
+	conventionalPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	mergeCommitPattern  = regexp.MustCompile(`^Merge (branch|remote-tracking branch|pull request|request) `)
+
+	// DefaultIssuePattern finds "#12" and "group/project#12" at the start of
+	// a word or after "(", and Jira-style keys such as "OPS-42".
+	DefaultIssuePattern = regexp.MustCompile(`(?:^|[\s(])((?:[\w.-]+/[\w.-]+)?#\d+)|\b([A-Z]{2,}-\d+)\b`)
+)
+
+// nonIssueKeys are prefixes that look like Jira keys to DefaultIssuePattern
+// but name standards and algorithms, as in "UTF-8" or "SHA-256".
+var nonIssueKeys = map[string]bool{
+	"AES": true, "CVE": true, "ECMA": true, "IEEE": true, "ISO": true, "RFC": true,
+	"SHA": true, "TLS": true, "UTC": true, "UTF": true,
+}
+
+var typeTitles = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"style", "Style"},
+	{"chore", "Chores"},
+	{"", "Other Changes"},
+}
+
+type Entry struct {
+	Commit   gitlab.Commit
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking bool
+}
+
+type Group struct {
+	Type    string
+	Title   string
+	Entries []Entry
+}
+
+type FileChange struct {
+	Path      string
+	OldPath   string
+	Status    string
+	Additions int
+	Deletions int
+}
+
+type Changelog struct {
+	Groups    []Group
+	Issues    []string
+	Authors   []string
+	Files     []FileChange
+	Additions int
+	Deletions int
+}
+
+type Options struct {
+	// IssuePattern extracts issue references from commit messages. When it
+	// has capture groups, the first one that matched is the reference.
+	// Defaults to DefaultIssuePattern.
+	IssuePattern *regexp.Regexp
+}
+
+func findIssues(pattern *regexp.Regexp, message string) []string {
+	var refs []string
+	for _, match := range pattern.FindAllStringSubmatch(message, -1) {
+		ref := match[0]
+		for _, group := range match[1:] {
+			if group != "" {
+				ref = group
+				break
+			}
+		}
+
+		// The CLI compiles the default from its string, so compare by source.
+		if pattern.String() == DefaultIssuePattern.String() {
+			if key, _, ok := strings.Cut(ref, "-"); ok && nonIssueKeys[key] {
+				continue
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func Build(commits []gitlab.Commit, diffs []gitlab.Diff, opts Options) Changelog {
+	issuePattern := opts.IssuePattern
+	if issuePattern == nil {
+		issuePattern = DefaultIssuePattern
+	}
+
+	var changelog Changelog
+	grouped := make(map[string][]Entry)
+	issues := make(map[string]bool)
+	authors := make(map[string]bool)
+
+	for _, commit := range commits {
+		message := commit.Message
+		if message == "" {
+			message = commit.Title
+		}
+
+		for _, ref := range findIssues(issuePattern, message) {
+			if !issues[ref] {
+				issues[ref] = true
+				changelog.Issues = append(changelog.Issues, ref)
+			}
+		}
+
+		if mergeCommitPattern.MatchString(commit.Title) {
+			continue
+		}
+
+		if commit.AuthorName != "" && !authors[commit.AuthorName] {
+			authors[commit.AuthorName] = true
+			changelog.Authors = append(changelog.Authors, commit.AuthorName)
+		}
+
+		entry := ParseEntry(commit)
+		grouped[entry.Type] = append(grouped[entry.Type], entry)
+	}
+
+	for _, known := range typeTitles {
+		if entries := grouped[known.Type]; len(entries) > 0 {
+			changelog.Groups = append(changelog.Groups, Group{Type: known.Type, Title: known.Title, Entries: entries})
+		}
+	}
+
+	sort.Strings(changelog.Authors)
+
+	for i := range diffs {
+		diff := &diffs[i]
+		added, deleted := diff.LineChanges()
+		changelog.Additions += added
+		changelog.Deletions += deleted
+
+		change := FileChange{
+			Path:      diff.NewPath,
+			Status:    "modified",
+			Additions: added,
+			Deletions: deleted,
+		}
+
+		switch {
+		case diff.NewFile:
+			change.Status = "added"
+		case diff.DeletedFile:
+			change.Status = "deleted"
+			change.Path = diff.OldPath
+		case diff.RenamedFile:
+			change.Status = "renamed"
+			change.OldPath = diff.OldPath
+		}
+
+		changelog.Files = append(changelog.Files, change)
+	}
+
+	return changelog
+}
+
+// ParseEntry splits a conventional-commit title ("type(scope)!: subject").
+// Titles that do not follow the convention, or use an unknown type, land in
+// the catch-all group with an empty Type.
+func ParseEntry(commit gitlab.Commit) Entry {
+	entry := Entry{Commit: commit, Subject: commit.Title}
+
+	matches := conventionalPattern.FindStringSubmatch(commit.Title)
+	if matches != nil && isKnownType(strings.ToLower(matches[1])) {
+		entry.Type = strings.ToLower(matches[1])
+		entry.Scope = matches[2]
+		entry.Breaking = matches[3] == "!"
+		entry.Subject = matches[4]
+	}
+
+	if strings.Contains(commit.Message, "BREAKING CHANGE:") || strings.Contains(commit.Message, "BREAKING-CHANGE:") {
+		entry.Breaking = true
+	}
+
+	return entry
+}
+
+func isKnownType(commitType string) bool {
+	for _, known := range typeTitles {
+		if known.Type != "" && known.Type == commitType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Changelog) IsEmpty() bool {
+	return len(c.Groups) == 0 && len(c.Files) == 0
+}
+
+func (c Changelog) Markdown() string {
+	if c.IsEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Changelog\n")
+
+	for _, group := range c.Groups {
+		fmt.Fprintf(&b, "\n### %s\n\n", group.Title)
+		for _, entry := range group.Entries {
+			b.WriteString("- ")
+			if entry.Breaking {
+				b.WriteString("**BREAKING** ")
+			}
+			if entry.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", entry.Scope)
+			}
+			fmt.Fprintf(&b, "%s (%s)\n", entry.Subject, entry.Commit.ShortID)
+		}
+	}
+
+	if len(c.Issues) > 0 {
+		fmt.Fprintf(&b, "\n### Issues\n\n%s\n", strings.Join(c.Issues, ", "))
+	}
+
+	if len(c.Authors) > 0 {
+		fmt.Fprintf(&b, "\n### Authors\n\n%s\n", strings.Join(c.Authors, ", "))
+	}
+
+	if len(c.Files) > 0 {
+		fmt.Fprintf(&b, "\n<details>\n<summary>%d file(s) changed, +%d / -%d</summary>\n\n", len(c.Files), c.Additions, c.Deletions)
+		b.WriteString("| File | Change | + | - |\n|------|--------|---|---|\n")
+
+		for i, file := range c.Files {
+			if i == maxListedFiles {
+				fmt.Fprintf(&b, "\n…and %d more file(s)\n", len(c.Files)-maxListedFiles)
+				break
+			}
+
+			path := fmt.Sprintf("`%s`", tableCell(file.Path))
+			if file.OldPath != "" {
+				path = fmt.Sprintf("`%s` → `%s`", tableCell(file.OldPath), tableCell(file.Path))
+			}
+			fmt.Fprintf(&b, "| %s | %s | %d | %d |\n", path, file.Status, file.Additions, file.Deletions)
+		}
+
+		b.WriteString("\n</details>\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// tableCell escapes the pipes that would otherwise end a table cell; GitHub
+// and GitLab unescape them even inside code spans.
+func tableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
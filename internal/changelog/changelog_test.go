@@ -0,0 +1,158 @@
+package changelog
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		commit   gitlab.Commit
+		expected Entry
+	}{
+		{
+			name:     "type with scope",
+			commit:   gitlab.Commit{Title: "feat(api): add login endpoint"},
+			expected: Entry{Type: "feat", Scope: "api", Subject: "add login endpoint"},
+		},
+		{
+			name:     "breaking marker",
+			commit:   gitlab.Commit{Title: "refactor!: drop v1 routes"},
+			expected: Entry{Type: "refactor", Subject: "drop v1 routes", Breaking: true},
+		},
+		{
+			name:     "breaking footer",
+			commit:   gitlab.Commit{Title: "fix: rename header", Message: "fix: rename header\n\nBREAKING CHANGE: clients must send X-Token"},
+			expected: Entry{Type: "fix", Subject: "rename header", Breaking: true},
+		},
+		{
+			name:     "uppercase type",
+			commit:   gitlab.Commit{Title: "Fix: typo"},
+			expected: Entry{Type: "fix", Subject: "typo"},
+		},
+		{
+			name:     "unknown type",
+			commit:   gitlab.Commit{Title: "wip: something"},
+			expected: Entry{Subject: "wip: something"},
+		},
+		{
+			name:     "not conventional",
+			commit:   gitlab.Commit{Title: "Update README"},
+			expected: Entry{Subject: "Update README"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ParseEntry(tt.commit)
+			if entry.Type != tt.expected.Type || entry.Scope != tt.expected.Scope ||
+				entry.Subject != tt.expected.Subject || entry.Breaking != tt.expected.Breaking {
+				t.Errorf("ParseEntry() = %+v, expected %+v", entry, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	commits := []gitlab.Commit{
+		{ShortID: "a1", Title: "fix: handle timeout", Message: "fix: handle timeout\n\nCloses #12", AuthorName: "Bob"},
+		{ShortID: "b2", Title: "feat(api): add login", Message: "feat(api): add login\n\nRefs JIRA-456", AuthorName: "Alice"},
+		{ShortID: "c3", Title: "Merge branch 'feature' into 'op-stage'", Message: "Merge branch 'feature' into 'op-stage'\n\nSee group/other#7", AuthorName: "Carol"},
+		{ShortID: "d4", Title: "Update docs", AuthorName: "Alice"},
+	}
+	diffs := []gitlab.Diff{
+		{NewPath: "api/login.go", NewFile: true, Diff: "@@ -0,0 +1,2 @@\n+package api\n+\n"},
+		{OldPath: "old.go", NewPath: "new.go", RenamedFile: true},
+		{OldPath: "gone.go", NewPath: "gone.go", DeletedFile: true, Diff: "@@ -1 +0,0 @@\n-package gone\n"},
+	}
+
+	changelog := Build(commits, diffs, Options{})
+
+	var titles []string
+	for _, group := range changelog.Groups {
+		titles = append(titles, group.Title)
+	}
+	if strings.Join(titles, ",") != "Features,Bug Fixes,Other Changes" {
+		t.Errorf("unexpected groups: %v", titles)
+	}
+
+	if strings.Join(changelog.Issues, ",") != "#12,JIRA-456,group/other#7" {
+		t.Errorf("unexpected issues: %v", changelog.Issues)
+	}
+
+	if strings.Join(changelog.Authors, ",") != "Alice,Bob" {
+		t.Errorf("unexpected authors (merge commit authors are skipped): %v", changelog.Authors)
+	}
+
+	if changelog.Additions != 2 || changelog.Deletions != 1 {
+		t.Errorf("unexpected line totals: +%d -%d", changelog.Additions, changelog.Deletions)
+	}
+
+	statuses := []string{changelog.Files[0].Status, changelog.Files[1].Status, changelog.Files[2].Status}
+	if strings.Join(statuses, ",") != "added,renamed,deleted" {
+		t.Errorf("unexpected file statuses: %v", statuses)
+	}
+
+	markdown := changelog.Markdown()
+	for _, want := range []string{
+		"### Features\n\n- **api:** add login (b2)",
+		"### Bug Fixes\n\n- handle timeout (a1)",
+		"### Issues\n\n#12, JIRA-456, group/other#7",
+		"| `old.go` → `new.go` | renamed | 0 | 0 |",
+		"3 file(s) changed, +2 / -1",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestMarkdown_EscapesPipesInPaths(t *testing.T) {
+	diffs := []gitlab.Diff{{OldPath: "docs/a|b.md", NewPath: "docs/a|c.md", RenamedFile: true}}
+
+	markdown := Build(nil, diffs, Options{}).Markdown()
+
+	if want := "| `docs/a\\|b.md` → `docs/a\\|c.md` | renamed | 0 | 0 |"; !strings.Contains(markdown, want) {
+		t.Errorf("markdown missing %q:\n%s", want, markdown)
+	}
+}
+
+func TestBuild_DefaultIssuePattern(t *testing.T) {
+	commits := []gitlab.Commit{
+		{Title: "fix: read UTF-8 files", Message: "fix: read UTF-8 files (#31)\n\nChecksums use SHA-256, dates ISO-8601.\nPorted the C#1 sample, see issue#2 and group/app#5; fixes OPS-42."},
+	}
+
+	changelog := Build(commits, nil, Options{})
+
+	if got := strings.Join(changelog.Issues, ","); got != "#31,group/app#5,OPS-42" {
+		t.Errorf("unexpected issues: %s", got)
+	}
+
+	// The --issue-pattern default is compiled again from its string.
+	changelog = Build(commits, nil, Options{IssuePattern: regexp.MustCompile(DefaultIssuePattern.String())})
+	if got := strings.Join(changelog.Issues, ","); got != "#31,group/app#5,OPS-42" {
+		t.Errorf("unexpected issues with the recompiled default: %s", got)
+	}
+}
+
+func TestBuild_CustomIssuePattern(t *testing.T) {
+	commits := []gitlab.Commit{
+		{Title: "fix: use UTF-8 everywhere", Message: "fix: use UTF-8 everywhere\n\nOPS-42"},
+	}
+
+	changelog := Build(commits, nil, Options{IssuePattern: regexp.MustCompile(`\bOPS-\d+\b`)})
+
+	if strings.Join(changelog.Issues, ",") != "OPS-42" {
+		t.Errorf("unexpected issues: %v", changelog.Issues)
+	}
+}
+
+func TestMarkdown_Empty(t *testing.T) {
+	if got := (Changelog{}).Markdown(); got != "" {
+		t.Errorf("expected empty markdown, got %q", got)
+	}
+}
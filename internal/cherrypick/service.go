@@ -0,0 +1,374 @@
+package cherrypick
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
+)
+
+type GitLabClient interface {
+	GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error)
+	GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error)
+	ListCommits(ctx context.Context, projectID int, ref string, since time.Time) ([]gitlab.Commit, error)
+	GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error)
+	CreateBranch(ctx context.Context, projectID int, branch, ref string) (*gitlab.Branch, error)
+	CherryPickCommit(ctx context.Context, projectID int, sha string, opts gitlab.CherryPickOptions) (*gitlab.Commit, error)
+}
+
+type Config struct {
+	// Target is the branch the commits are meant for.
+	Target string
+	// Branch, if set, is created from Target and the commits are picked onto
+	// it instead, so they can go through an MR.
+	Branch   string
+	Projects []string
+	// SHAs picks one commit per project, by project path. Projects without
+	// an entry are searched with Pattern.
+	SHAs map[string]string
+	// Pattern selects the commits on Source whose message matches, made
+	// after Since if it is set.
+	Pattern *regexp.Regexp
+	Source  string
+	Since   time.Time
+	// DryRun checks that every commit applies onto Target without committing
+	// anything. Commits are checked one by one, so a commit that builds on
+	// an earlier one may show as a conflict.
+	DryRun      bool
+	Verbose     bool
+	Concurrency int
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectResult)
+}
+
+type ResultStatus string
+
+const (
+	StatusPicked          ResultStatus = "PICKED"
+	StatusWouldPick       ResultStatus = "WOULD_PICK"
+	StatusSkippedApplied  ResultStatus = "SKIPPED_APPLIED"
+	StatusSkippedNoCommit ResultStatus = "SKIPPED_NO_COMMIT"
+	StatusSkippedBranch   ResultStatus = "SKIPPED_NO_BRANCH"
+	StatusConflict        ResultStatus = "CONFLICT"
+	StatusNotFound        ResultStatus = "NOT_FOUND"
+	StatusUnauthorized    ResultStatus = "UNAUTHORIZED"
+	StatusError           ResultStatus = "ERROR"
+	StatusCanceled        ResultStatus = "CANCELED"
+)
+
+// Outcomes of a single commit.
+const (
+	OutcomePicked    = "picked"
+	OutcomeWouldPick = "would pick"
+	OutcomeApplied   = "already applied"
+	OutcomeConflict  = "conflict"
+	OutcomeNotTried  = "not tried"
+)
+
+type Commit struct {
+	SHA   string `json:"sha"`
+	Title string `json:"title"`
+	// PickedSHA is the commit the cherry-pick created.
+	PickedSHA string `json:"picked_sha,omitempty"`
+	Outcome   string `json:"outcome"`
+}
+
+type ProjectResult struct {
+	Project      string       `json:"project"`
+	Status       ResultStatus `json:"status"`
+	Branch       string       `json:"branch"`
+	Commits      []Commit     `json:"commits"`
+	Details      string       `json:"details,omitempty"`
+	ErrorMessage string       `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total           int `json:"total"`
+	Picked          int `json:"picked"`
+	WouldPick       int `json:"would_pick"`
+	SkippedApplied  int `json:"skipped_applied"`
+	SkippedNoCommit int `json:"skipped_no_commit"`
+	SkippedBranch   int `json:"skipped_no_branch"`
+	Conflicts       int `json:"conflicts"`
+	NotFound        int `json:"not_found"`
+	Unauthorized    int `json:"unauthorized"`
+	Errors          int `json:"errors"`
+	Canceled        int `json:"canceled"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Run cherry-picks the matching commits in every project, oldest first. It
+// is idempotent: commits whose change the branch already has are skipped.
+// The first conflict stops a project, leaving the commits picked before it.
+func (s *Service) Run(ctx context.Context) ([]ProjectResult, Summary) {
+	projects := s.config.Projects
+	n := len(projects)
+
+	var progress func(finished int, result ProjectResult)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectResult) {
+			s.config.Progress(finished, n, result)
+		}
+	}
+
+	results := workpool.Run(n, s.config.Concurrency, func(i int) ProjectResult {
+		if ctx.Err() != nil {
+			return ProjectResult{
+				Project: projects[i],
+				Branch:  s.branch(),
+				Commits: []Commit{},
+				Status:  StatusCanceled,
+				Details: "Not processed: run was canceled",
+			}
+		}
+
+		result := s.pickProject(ctx, projects[i])
+		if result.Status == StatusError && ctx.Err() != nil {
+			result.Status = StatusCanceled
+		}
+		return result
+	}, progress)
+
+	return results, summarize(results)
+}
+
+// branch is where the commits are picked onto.
+func (s *Service) branch() string {
+	if s.config.Branch != "" {
+		return s.config.Branch
+	}
+	return s.config.Target
+}
+
+func (s *Service) pickProject(ctx context.Context, projectPath string) ProjectResult {
+	result := ProjectResult{
+		Project: projectPath,
+		Branch:  s.branch(),
+		Commits: []Commit{},
+	}
+
+	project, err := s.client.GetProject(ctx, projectPath)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusNotFound
+			result.Details = fmt.Sprintf("Project '%s' does not exist or is not visible to this token", projectPath)
+			return result
+		}
+		return failed(result, "", err)
+	}
+
+	sha, bySHA := s.config.SHAs[projectPath]
+	commits, err := s.findCommits(ctx, project.ID, projectPath)
+	switch {
+	case gitlab.IsNotFound(err) && bySHA:
+		result.Status = StatusSkippedNoCommit
+		result.Details = fmt.Sprintf("Commit '%s' does not exist", sha)
+		return result
+	case gitlab.IsNotFound(err):
+		result.Status = StatusSkippedBranch
+		result.Details = fmt.Sprintf("Branch '%s' does not exist", s.config.Source)
+		return result
+	case err != nil:
+		return failed(result, "failed to find commits", err)
+	case len(commits) == 0 && s.config.Pattern == nil:
+		result.Status = StatusSkippedNoCommit
+		result.Details = "No commit given for this project"
+		return result
+	case len(commits) == 0:
+		result.Status = StatusSkippedNoCommit
+		result.Details = fmt.Sprintf("No commits on %s match %s", s.config.Source, s.config.Pattern)
+		return result
+	}
+	for _, commit := range commits {
+		result.Commits = append(result.Commits, Commit{SHA: commit.ID, Title: commit.Title, Outcome: OutcomeNotTried})
+	}
+
+	if _, err := s.client.GetBranch(ctx, project.ID, s.config.Target); err != nil {
+		if gitlab.IsNotFound(err) {
+			result.Status = StatusSkippedBranch
+			result.Details = fmt.Sprintf("Branch '%s' does not exist", s.config.Target)
+			return result
+		}
+		return failed(result, "failed to check target branch", err)
+	}
+
+	// A dry run picks onto the target, which the new branch would start
+	// from anyway.
+	onto := s.branch()
+	if s.config.DryRun {
+		onto = s.config.Target
+	} else if s.config.Branch != "" {
+		if err := s.ensureBranch(ctx, project.ID); err != nil {
+			return failed(result, "failed to create branch", err)
+		}
+	}
+
+	var picked, applied int
+	for i := range result.Commits {
+		commit := &result.Commits[i]
+
+		if s.config.Verbose {
+			log.Printf("[%s] Cherry-picking %s onto %s...", projectPath, shortSHA(commit.SHA), onto)
+		}
+
+		created, err := s.client.CherryPickCommit(ctx, project.ID, commit.SHA, gitlab.CherryPickOptions{Branch: onto, DryRun: s.config.DryRun})
+		switch {
+		case err == nil && s.config.DryRun:
+			commit.Outcome = OutcomeWouldPick
+			picked++
+		case err == nil:
+			commit.Outcome = OutcomePicked
+			commit.PickedSHA = created.ID
+			picked++
+		case gitlab.ErrorCode(err) == "empty":
+			commit.Outcome = OutcomeApplied
+			applied++
+		case gitlab.ErrorCode(err) == "conflict":
+			commit.Outcome = OutcomeConflict
+			result.Status = StatusConflict
+			result.Details = fmt.Sprintf("%s does not apply cleanly onto %s", shortSHA(commit.SHA), onto)
+			switch {
+			case picked > 0 && s.config.DryRun:
+				result.Details += fmt.Sprintf(" (%d commit(s) before it apply)", picked)
+			case picked > 0:
+				result.Details += fmt.Sprintf(" (%d commit(s) picked before it)", picked)
+			}
+			return result
+		default:
+			return failed(result, fmt.Sprintf("failed to cherry-pick %s", shortSHA(commit.SHA)), err)
+		}
+	}
+
+	switch {
+	case picked == 0:
+		result.Status = StatusSkippedApplied
+		result.Details = fmt.Sprintf("%s already has the change(s)", onto)
+	case s.config.DryRun:
+		result.Status = StatusWouldPick
+		result.Details = fmt.Sprintf("%d commit(s) apply cleanly onto %s", picked, onto)
+	default:
+		result.Status = StatusPicked
+		result.Details = fmt.Sprintf("Picked %d commit(s) onto %s", picked, onto)
+	}
+	if applied > 0 && picked > 0 {
+		result.Details += fmt.Sprintf(", %d already applied", applied)
+	}
+
+	return result
+}
+
+// findCommits returns the project's commit by SHA, or the commits matching
+// the pattern, oldest first.
+func (s *Service) findCommits(ctx context.Context, projectID int, projectPath string) ([]gitlab.Commit, error) {
+	if sha, ok := s.config.SHAs[projectPath]; ok {
+		commit, err := s.client.GetCommit(ctx, projectID, sha)
+		if err != nil {
+			return nil, err
+		}
+		return []gitlab.Commit{*commit}, nil
+	}
+
+	if s.config.Pattern == nil {
+		return nil, nil
+	}
+
+	commits, err := s.client.ListCommits(ctx, projectID, s.config.Source, s.config.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []gitlab.Commit
+	for i := len(commits) - 1; i >= 0; i-- {
+		message := commits[i].Message
+		if message == "" {
+			message = commits[i].Title
+		}
+		if s.config.Pattern.MatchString(message) {
+			matches = append(matches, commits[i])
+		}
+	}
+
+	return matches, nil
+}
+
+// ensureBranch creates Branch from Target unless an earlier run already did.
+func (s *Service) ensureBranch(ctx context.Context, projectID int) error {
+	_, err := s.client.GetBranch(ctx, projectID, s.config.Branch)
+	switch {
+	case err == nil:
+		return nil
+	case !gitlab.IsNotFound(err):
+		return err
+	}
+
+	_, err = s.client.CreateBranch(ctx, projectID, s.config.Branch, s.config.Target)
+	return err
+}
+
+func summarize(results []ProjectResult) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusPicked:
+			summary.Picked++
+		case StatusWouldPick:
+			summary.WouldPick++
+		case StatusSkippedApplied:
+			summary.SkippedApplied++
+		case StatusSkippedNoCommit:
+			summary.SkippedNoCommit++
+		case StatusSkippedBranch:
+			summary.SkippedBranch++
+		case StatusConflict:
+			summary.Conflicts++
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		case StatusCanceled:
+			summary.Canceled++
+		}
+	}
+
+	return summary
+}
+
+func failed(result ProjectResult, context string, err error) ProjectResult {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+
+	result.ErrorMessage = err.Error()
+	if context != "" {
+		result.ErrorMessage = fmt.Sprintf("%s: %v", context, err)
+	}
+
+	return result
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
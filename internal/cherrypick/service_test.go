@@ -0,0 +1,217 @@
+package cherrypick
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	projects map[string]int
+	branches map[int]map[string]bool
+	// commits lists each project's commits newest first, as the API does.
+	commits map[int][]gitlab.Commit
+	// pickErrors fail the cherry-pick of a commit, by SHA.
+	pickErrors map[string]error
+
+	mu      sync.Mutex
+	created map[int]string
+	picks   map[int][]gitlab.CherryPickOptions
+	picked  map[int][]string
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		projects:   make(map[string]int),
+		branches:   make(map[int]map[string]bool),
+		commits:    make(map[int][]gitlab.Commit),
+		pickErrors: make(map[string]error),
+		created:    make(map[int]string),
+		picks:      make(map[int][]gitlab.CherryPickOptions),
+		picked:     make(map[int][]string),
+	}
+}
+
+func notFound() error {
+	return &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Not Found"}
+}
+
+func (m *mockGitLabClient) GetProject(ctx context.Context, projectPath string) (*gitlab.Project, error) {
+	id, ok := m.projects[projectPath]
+	if !ok {
+		return nil, notFound()
+	}
+	return &gitlab.Project{ID: id, PathWithNamespace: projectPath}, nil
+}
+
+func (m *mockGitLabClient) GetCommit(ctx context.Context, projectID int, ref string) (*gitlab.Commit, error) {
+	for _, commit := range m.commits[projectID] {
+		if commit.ID == ref {
+			return &commit, nil
+		}
+	}
+	return nil, notFound()
+}
+
+func (m *mockGitLabClient) ListCommits(ctx context.Context, projectID int, ref string, since time.Time) ([]gitlab.Commit, error) {
+	if !m.branches[projectID][ref] {
+		return nil, notFound()
+	}
+	return m.commits[projectID], nil
+}
+
+func (m *mockGitLabClient) GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.branches[projectID][branch] {
+		return nil, notFound()
+	}
+	return &gitlab.Branch{Name: branch}, nil
+}
+
+func (m *mockGitLabClient) CreateBranch(ctx context.Context, projectID int, branch, ref string) (*gitlab.Branch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created[projectID] = ref
+	m.branches[projectID][branch] = true
+	return &gitlab.Branch{Name: branch}, nil
+}
+
+func (m *mockGitLabClient) CherryPickCommit(ctx context.Context, projectID int, sha string, opts gitlab.CherryPickOptions) (*gitlab.Commit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.picks[projectID] = append(m.picks[projectID], opts)
+	if err := m.pickErrors[sha]; err != nil {
+		return nil, err
+	}
+	m.picked[projectID] = append(m.picked[projectID], sha)
+	return &gitlab.Commit{ID: "new-" + sha}, nil
+}
+
+func pickError(code string) error {
+	return &gitlab.APIError{StatusCode: http.StatusBadRequest, Message: "400 Bad Request", ErrorCode: code}
+}
+
+func TestRun(t *testing.T) {
+	client := newMockClient()
+	for i, project := range []string{"group/picked", "group/by-sha", "group/applied", "group/conflict", "group/no-match", "group/no-rc"} {
+		client.projects[project] = i + 1
+		client.branches[i+1] = map[string]bool{"main": true, "op-rc": true}
+		client.commits[i+1] = []gitlab.Commit{
+			{ID: "newer", Title: "fix: follow-up", Message: "fix: follow-up\n\nRefs OPS-42"},
+			{ID: "unrelated", Title: "feat: something else"},
+			{ID: "older", Title: "fix: crash on empty export (OPS-42)"},
+		}
+	}
+	client.commits[2] = []gitlab.Commit{{ID: "pinned", Title: "fix: one-off"}}
+	client.commits[3] = []gitlab.Commit{{ID: "applied", Title: "fix: OPS-42"}}
+	client.commits[4] = []gitlab.Commit{{ID: "clashes", Title: "fix: OPS-42 again"}, {ID: "clean", Title: "fix: OPS-42"}}
+	client.commits[5] = []gitlab.Commit{{ID: "unrelated", Title: "feat: something else"}}
+	delete(client.branches[6], "op-rc")
+	client.pickErrors["applied"] = pickError("empty")
+	client.pickErrors["clashes"] = pickError("conflict")
+
+	config := Config{
+		Target:      "op-rc",
+		Projects:    []string{"group/picked", "group/by-sha", "group/applied", "group/conflict", "group/no-match", "group/no-rc", "group/missing"},
+		SHAs:        map[string]string{"group/by-sha": "pinned"},
+		Pattern:     regexp.MustCompile(`OPS-42`),
+		Source:      "main",
+		Concurrency: 3,
+	}
+
+	results, summary := NewService(client, config).Run(context.Background())
+
+	expected := []ResultStatus{StatusPicked, StatusPicked, StatusSkippedApplied, StatusConflict, StatusSkippedNoCommit, StatusSkippedBranch, StatusNotFound}
+	for i, status := range expected {
+		if results[i].Status != status {
+			t.Errorf("%s: expected %s, got %s (%s%s)", results[i].Project, status, results[i].Status, results[i].Details, results[i].ErrorMessage)
+		}
+	}
+
+	if got := strings.Join(client.picked[1], ","); got != "older,newer" {
+		t.Errorf("expected matching commits picked oldest first, got %s", got)
+	}
+	if results[0].Commits[0].PickedSHA != "new-older" || client.picks[1][0].Branch != "op-rc" || client.picks[1][0].DryRun {
+		t.Errorf("unexpected pick: %+v, %+v", results[0].Commits[0], client.picks[1][0])
+	}
+	if got := strings.Join(client.picked[2], ","); got != "pinned" {
+		t.Errorf("expected only the given SHA picked, got %s", got)
+	}
+	if results[2].Commits[0].Outcome != OutcomeApplied {
+		t.Errorf("unexpected outcome: %+v", results[2].Commits[0])
+	}
+
+	conflict := results[3]
+	if conflict.Commits[0].Outcome != OutcomePicked || conflict.Commits[1].Outcome != OutcomeConflict || !strings.Contains(conflict.Details, "1 commit(s) picked before it") {
+		t.Errorf("unexpected conflict result: %+v", conflict)
+	}
+
+	if len(client.created) != 0 {
+		t.Errorf("no branch should be created without Branch: %v", client.created)
+	}
+
+	expectedSummary := Summary{Total: 7, Picked: 2, SkippedApplied: 1, SkippedNoCommit: 1, SkippedBranch: 1, Conflicts: 1, NotFound: 1}
+	if summary != expectedSummary {
+		t.Errorf("expected summary %+v, got %+v", expectedSummary, summary)
+	}
+}
+
+func TestRun_NewBranch(t *testing.T) {
+	client := newMockClient()
+	client.projects["group/a"] = 1
+	client.branches[1] = map[string]bool{"op-rc": true}
+	client.commits[1] = []gitlab.Commit{{ID: "fix", Title: "fix: OPS-42"}}
+
+	config := Config{
+		Target:   "op-rc",
+		Branch:   "hotfix/OPS-42",
+		Projects: []string{"group/a"},
+		SHAs:     map[string]string{"group/a": "fix"},
+	}
+	results, _ := NewService(client, config).Run(context.Background())
+
+	if results[0].Status != StatusPicked || results[0].Branch != "hotfix/OPS-42" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if client.created[1] != "op-rc" || client.picks[1][0].Branch != "hotfix/OPS-42" {
+		t.Errorf("expected the branch created from the target and picked onto, got %v, %+v", client.created, client.picks[1])
+	}
+
+	// A second run finds the branch and the change already there.
+	client.created = make(map[int]string)
+	client.pickErrors["fix"] = pickError("empty")
+	results, _ = NewService(client, config).Run(context.Background())
+	if results[0].Status != StatusSkippedApplied || len(client.created) != 0 {
+		t.Errorf("expected rerun to be skipped, got %+v, created %v", results[0], client.created)
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	client := newMockClient()
+	client.projects["group/a"] = 1
+	client.branches[1] = map[string]bool{"op-rc": true}
+	client.commits[1] = []gitlab.Commit{{ID: "fix", Title: "fix: OPS-42"}}
+
+	config := Config{
+		Target:   "op-rc",
+		Branch:   "hotfix/OPS-42",
+		Projects: []string{"group/a"},
+		SHAs:     map[string]string{"group/a": "fix"},
+		DryRun:   true,
+	}
+	results, summary := NewService(client, config).Run(context.Background())
+
+	if results[0].Status != StatusWouldPick || results[0].Commits[0].Outcome != OutcomeWouldPick || summary.WouldPick != 1 {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if len(client.created) != 0 || client.picks[1][0].Branch != "op-rc" || !client.picks[1][0].DryRun {
+		t.Errorf("dry run should only check against the target: created %v, picks %+v", client.created, client.picks[1])
+	}
+}
@@ -0,0 +1,169 @@
+package codeowners
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Locations lists where GitLab looks for a CODEOWNERS file, in priority order.
+var Locations = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+type rule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+type section struct {
+	name  string
+	rules []rule
+}
+
+type File struct {
+	sections []section
+}
+
+var sectionPattern = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*(.*)$`)
+
+func Parse(content string) *File {
+	file := &File{sections: []section{{}}}
+	var defaultOwners []string
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if matches := sectionPattern.FindStringSubmatch(line); matches != nil {
+			file.sections = append(file.sections, section{name: matches[1]})
+			defaultOwners = strings.Fields(matches[2])
+			continue
+		}
+
+		fields := splitFields(line)
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = defaultOwners
+		}
+
+		current := &file.sections[len(file.sections)-1]
+		current.rules = append(current.rules, rule{pattern: compilePattern(fields[0]), owners: owners})
+	}
+
+	return file
+}
+
+// Owners returns the owners of path. Within a section the last matching rule
+// wins; every section contributes its match, as GitLab does.
+func (f *File) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+
+	var owners []string
+	seen := make(map[string]bool)
+
+	for _, sec := range f.sections {
+		for i := len(sec.rules) - 1; i >= 0; i-- {
+			if !sec.rules[i].pattern.MatchString(path) {
+				continue
+			}
+			for _, owner := range sec.rules[i].owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+			break
+		}
+	}
+
+	return owners
+}
+
+// Usernames keeps the owners that can be individual users: "@name" entries
+// without a group path. Emails, roles ("@@maintainer") and "@group/sub" are
+// dropped.
+func Usernames(owners []string) []string {
+	var usernames []string
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") || strings.HasPrefix(owner, "@@") || strings.Contains(owner, "/") {
+			continue
+		}
+		usernames = append(usernames, strings.TrimPrefix(owner, "@"))
+	}
+	return usernames
+}
+
+func splitFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == ' ':
+			current.WriteByte(' ')
+			i++
+		case line[i] == ' ' || line[i] == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteByte(line[i])
+		}
+	}
+
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
+
+// compilePattern translates a gitignore-style CODEOWNERS pattern. Patterns
+// with a leading or inner slash are anchored at the repository root, others
+// match at any depth; a pattern naming a directory covers everything below it.
+func compilePattern(pattern string) *regexp.Regexp {
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(trimmed); i++ {
+		switch c := trimmed[i]; c {
+		case '*':
+			if i+1 < len(trimmed) && trimmed[i+1] == '*' {
+				if i+2 < len(trimmed) && trimmed[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	lastComponent := trimmed[strings.LastIndex(trimmed, "/")+1:]
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		b.WriteString("/.*")
+	case !strings.ContainsAny(lastComponent, "*?"):
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,62 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `# Default owners
+* @alice
+
+*.md @docs-team
+/build/ @bob @ci/maintainers
+docs/api/ @carol
+**/migrations/*.sql @dba
+path\ with\ spaces/ @dave
+
+[Frontend] @erin
+web/
+web/legacy/ @frank
+
+^[Optional]
+*.go @gopher @@maintainer ops@example.com
+`
+
+func TestOwners(t *testing.T) {
+	file := Parse(sample)
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "main.go", expected: []string{"@alice", "@gopher", "@@maintainer", "ops@example.com"}},
+		{path: "README.md", expected: []string{"@docs-team"}},
+		{path: "guide/intro.md", expected: []string{"@docs-team"}},
+		{path: "build/Dockerfile", expected: []string{"@bob", "@ci/maintainers"}},
+		{path: "src/build/Dockerfile", expected: []string{"@alice"}},
+		{path: "docs/api/v1/spec.yaml", expected: []string{"@carol"}},
+		{path: "db/migrations/001_init.sql", expected: []string{"@dba"}},
+		{path: "migrations/002.sql", expected: []string{"@dba"}},
+		{path: "path with spaces/file.txt", expected: []string{"@dave"}},
+		{path: "web/index.html", expected: []string{"@alice", "@erin"}},
+		{path: "web/legacy/old.js", expected: []string{"@alice", "@frank"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			owners := file.Owners(tt.path)
+			if strings.Join(owners, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Owners(%q) = %v, expected %v", tt.path, owners, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUsernames(t *testing.T) {
+	owners := []string{"@alice", "@ci/maintainers", "@@maintainer", "ops@example.com", "@bob"}
+
+	usernames := Usernames(owners)
+	if strings.Join(usernames, ",") != "alice,bob" {
+		t.Errorf("Usernames() = %v, expected [alice bob]", usernames)
+	}
+}
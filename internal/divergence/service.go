@@ -0,0 +1,221 @@
+package divergence
+
+import (
+	"context"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
+)
+
+type GitLabClient interface {
+	BranchExists(ctx context.Context, projectID int, branch string) (bool, error)
+	CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error)
+	FindOpenMergeRequests(ctx context.Context, projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error)
+}
+
+type Config struct {
+	Projects []gitlab.Project
+	// Chain lists the environment branches in promotion order, as in
+	// develop, op-stage, op-rc, main. Each branch is compared with the next.
+	Chain       []string
+	Concurrency int
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectStatus)
+}
+
+// Pair is the divergence between two neighbouring branches of the chain.
+// Ahead counts the commits on From that are not on To yet, Behind those on
+// To that are not on From.
+type Pair struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Ahead         int            `json:"ahead"`
+	Behind        int            `json:"behind"`
+	Missing       []string       `json:"missing,omitempty"`
+	MergeRequests []MergeRequest `json:"merge_requests"`
+	ErrorMessage  string         `json:"error,omitempty"`
+}
+
+type MergeRequest struct {
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	WebURL string `json:"web_url"`
+	Draft  bool   `json:"draft"`
+}
+
+// Pending reports whether From has commits that still have to be promoted.
+func (p Pair) Pending() bool {
+	return p.Ahead > 0
+}
+
+type ProjectStatus struct {
+	Project         string   `json:"project"`
+	WebURL          string   `json:"web_url"`
+	MissingBranches []string `json:"missing_branches"`
+	Pairs           []Pair   `json:"pairs"`
+	Unauthorized    bool     `json:"unauthorized,omitempty"`
+	ErrorMessage    string   `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total int `json:"total"`
+	// Pending counts projects with commits to promote in any pair, InSync
+	// those without, Missing those lacking a branch of the chain.
+	Pending       int `json:"pending"`
+	InSync        int `json:"in_sync"`
+	Missing       int `json:"missing"`
+	MergeRequests int `json:"merge_requests"`
+	Unauthorized  int `json:"unauthorized"`
+	Errors        int `json:"errors"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Run compares every pair of neighbouring chain branches in every project.
+// It only reads, so a canceled run returns the projects with the error.
+func (s *Service) Run(ctx context.Context) ([]ProjectStatus, Summary) {
+	projects := s.config.Projects
+	n := len(projects)
+
+	var progress func(finished int, result ProjectStatus)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectStatus) {
+			s.config.Progress(finished, n, result)
+		}
+	}
+
+	results := workpool.Run(n, s.config.Concurrency, func(i int) ProjectStatus {
+		return s.projectStatus(ctx, projects[i])
+	}, progress)
+
+	return results, summarize(results)
+}
+
+func (s *Service) projectStatus(ctx context.Context, project gitlab.Project) ProjectStatus {
+	result := ProjectStatus{
+		Project:         project.PathWithNamespace,
+		WebURL:          project.WebURL,
+		MissingBranches: []string{},
+		Pairs:           []Pair{},
+	}
+
+	exists := make(map[string]bool)
+	for _, branch := range s.config.Chain {
+		ok, err := s.client.BranchExists(ctx, project.ID, branch)
+		if err != nil {
+			return failed(result, err)
+		}
+		exists[branch] = ok
+		if !ok {
+			result.MissingBranches = append(result.MissingBranches, branch)
+		}
+	}
+
+	for i := 0; i+1 < len(s.config.Chain); i++ {
+		pair := Pair{
+			From:          s.config.Chain[i],
+			To:            s.config.Chain[i+1],
+			MergeRequests: []MergeRequest{},
+		}
+
+		for _, branch := range []string{pair.From, pair.To} {
+			if !exists[branch] {
+				pair.Missing = append(pair.Missing, branch)
+			}
+		}
+		if len(pair.Missing) == 0 {
+			if err := s.comparePair(ctx, project.ID, &pair); err != nil {
+				if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+					return failed(result, err)
+				}
+				pair.ErrorMessage = err.Error()
+			}
+		}
+
+		result.Pairs = append(result.Pairs, pair)
+	}
+
+	return result
+}
+
+func (s *Service) comparePair(ctx context.Context, projectID int, pair *Pair) error {
+	ahead, err := s.client.CompareBranches(ctx, projectID, pair.From, pair.To)
+	if err != nil {
+		return err
+	}
+	behind, err := s.client.CompareBranches(ctx, projectID, pair.To, pair.From)
+	if err != nil {
+		return err
+	}
+	pair.Ahead = len(ahead.Commits)
+	pair.Behind = len(behind.Commits)
+
+	mrs, err := s.client.FindOpenMergeRequests(ctx, projectID, pair.From, pair.To)
+	if err != nil {
+		return err
+	}
+	for _, mr := range mrs {
+		pair.MergeRequests = append(pair.MergeRequests, MergeRequest{
+			IID:    mr.IID,
+			Title:  mr.Title,
+			WebURL: mr.WebURL,
+			Draft:  mr.IsDraft(),
+		})
+	}
+
+	return nil
+}
+
+func summarize(results []ProjectStatus) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch {
+		case result.Unauthorized:
+			summary.Unauthorized++
+			continue
+		case result.ErrorMessage != "":
+			summary.Errors++
+			continue
+		}
+
+		pending, failed := false, false
+		for _, pair := range result.Pairs {
+			pending = pending || pair.Pending()
+			failed = failed || pair.ErrorMessage != ""
+			summary.MergeRequests += len(pair.MergeRequests)
+		}
+
+		switch {
+		case failed:
+			summary.Errors++
+		case pending:
+			summary.Pending++
+		case len(result.MissingBranches) == 0:
+			summary.InSync++
+		}
+		if len(result.MissingBranches) > 0 {
+			summary.Missing++
+		}
+	}
+
+	return summary
+}
+
+func failed(result ProjectStatus, err error) ProjectStatus {
+	result.Unauthorized = gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err)
+	result.ErrorMessage = err.Error()
+	result.Pairs = []Pair{}
+	return result
+}
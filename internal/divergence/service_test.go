@@ -0,0 +1,125 @@
+package divergence
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	// branches holds the commits of each branch per project; a branch is
+	// ahead of another by the commits the other does not have.
+	branches    map[int]map[string][]string
+	mrs         map[int]map[string][]gitlab.MergeRequest
+	compareErrs map[int]error
+}
+
+func (m *mockGitLabClient) BranchExists(ctx context.Context, projectID int, branch string) (bool, error) {
+	_, ok := m.branches[projectID][branch]
+	return ok, nil
+}
+
+func (m *mockGitLabClient) CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error) {
+	if err := m.compareErrs[projectID]; err != nil {
+		return nil, err
+	}
+
+	onTarget := make(map[string]bool)
+	for _, sha := range m.branches[projectID][targetBranch] {
+		onTarget[sha] = true
+	}
+
+	var compare gitlab.Compare
+	for _, sha := range m.branches[projectID][sourceBranch] {
+		if !onTarget[sha] {
+			compare.Commits = append(compare.Commits, gitlab.Commit{ID: sha})
+		}
+	}
+	return &compare, nil
+}
+
+func (m *mockGitLabClient) FindOpenMergeRequests(ctx context.Context, projectID int, sourceBranch, targetBranch string) ([]gitlab.MergeRequest, error) {
+	return m.mrs[projectID][sourceBranch+">"+targetBranch], nil
+}
+
+func TestRun(t *testing.T) {
+	client := &mockGitLabClient{
+		branches: map[int]map[string][]string{
+			// develop has two new commits, main a hotfix that op-rc lacks.
+			1: {
+				"develop":  {"a", "b", "c", "d"},
+				"op-stage": {"a", "b"},
+				"op-rc":    {"a", "b"},
+				"main":     {"a", "b", "hotfix"},
+			},
+			2: {
+				"develop":  {"a"},
+				"op-stage": {"a"},
+				"main":     {"a"},
+			},
+			3: {
+				"develop":  {"a"},
+				"op-stage": {"a"},
+				"op-rc":    {"a"},
+				"main":     {"a"},
+			},
+			4: {
+				"develop":  {"a"},
+				"op-stage": {"a"},
+				"op-rc":    {"a"},
+				"main":     {"a"},
+			},
+		},
+		mrs: map[int]map[string][]gitlab.MergeRequest{
+			1: {"develop>op-stage": {{IID: 7, Title: "Draft: Promote develop", WebURL: "https://gitlab.example.com/mr/7"}}},
+		},
+		compareErrs: map[int]error{
+			4: &gitlab.APIError{StatusCode: http.StatusForbidden, Message: "403 Forbidden"},
+		},
+	}
+
+	config := Config{
+		Projects: []gitlab.Project{
+			{ID: 1, PathWithNamespace: "group/pending"},
+			{ID: 2, PathWithNamespace: "group/no-rc"},
+			{ID: 3, PathWithNamespace: "group/synced"},
+			{ID: 4, PathWithNamespace: "group/denied"},
+		},
+		Chain: []string{"develop", "op-stage", "op-rc", "main"},
+	}
+
+	results, summary := NewService(client, config).Run(context.Background())
+
+	pending := results[0].Pairs
+	if len(pending) != 3 {
+		t.Fatalf("expected 3 pairs, got %+v", pending)
+	}
+	if pending[0].Ahead != 2 || pending[0].Behind != 0 || len(pending[0].MergeRequests) != 1 || !pending[0].MergeRequests[0].Draft {
+		t.Errorf("unexpected develop → op-stage: %+v", pending[0])
+	}
+	if pending[1].Pending() || pending[1].Behind != 0 {
+		t.Errorf("expected op-stage → op-rc in sync: %+v", pending[1])
+	}
+	if pending[2].Ahead != 0 || pending[2].Behind != 1 {
+		t.Errorf("expected op-rc behind main by the hotfix: %+v", pending[2])
+	}
+
+	noRC := results[1]
+	if len(noRC.MissingBranches) != 1 || noRC.MissingBranches[0] != "op-rc" {
+		t.Errorf("unexpected missing branches: %v", noRC.MissingBranches)
+	}
+	if noRC.Pairs[0].Missing != nil || len(noRC.Pairs[1].Missing) != 1 || len(noRC.Pairs[2].Missing) != 1 {
+		t.Errorf("unexpected pairs: %+v", noRC.Pairs)
+	}
+
+	if !results[3].Unauthorized || results[3].ErrorMessage == "" {
+		t.Errorf("expected unauthorized project, got %+v", results[3])
+	}
+
+	expected := Summary{Total: 4, Pending: 1, InSync: 1, Missing: 1, MergeRequests: 1, Unauthorized: 1}
+	if summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+}
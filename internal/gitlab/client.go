@@ -1,9 +1,12 @@
 package gitlab
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,6 +18,9 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	verbose    bool
+	retry      RetryPolicy
+	pollPolicy PollPolicy
+	sleep      func(context.Context, time.Duration) error
 }
 
 func NewClient(baseURL, token string, verbose bool) *Client {
@@ -24,61 +30,237 @@ func NewClient(baseURL, token string, verbose bool) *Client {
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
-		verbose: verbose,
+		verbose:    verbose,
+		retry:      DefaultRetryPolicy(),
+		pollPolicy: DefaultPollPolicy(),
+		sleep:      sleepContext,
 	}
 }
 
-func (c *Client) GetProject(projectPath string) (*Project, error) {
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) GetProject(ctx context.Context, projectPath string) (*Project, error) {
 	encodedPath := url.PathEscape(projectPath)
 	endpoint := fmt.Sprintf("%s/api/v4/projects/%s", c.baseURL, encodedPath)
 
 	var project Project
-	if err := c.doRequest("GET", endpoint, nil, &project); err != nil {
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &project); err != nil {
 		return nil, fmt.Errorf("failed to get project %s: %w", projectPath, err)
 	}
 
 	return &project, nil
 }
 
-func (c *Client) BranchExists(projectID int, branchName string) (bool, error) {
+func (c *Client) BranchExists(ctx context.Context, projectID int, branchName string) (bool, error) {
 	encodedBranch := url.PathEscape(branchName)
 	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/branches/%s", c.baseURL, projectID, encodedBranch)
 
-	resp, err := c.makeRequest("GET", endpoint, nil)
-	if err != nil {
+	if err := c.doRequest(ctx, "GET", endpoint, nil, nil); err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
 		return false, fmt.Errorf("failed to check branch %s: %w", branchName, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return false, nil
+	return true, nil
+}
+
+func (c *Client) ListBranches(ctx context.Context, projectID int) ([]Branch, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/branches?per_page=%d", c.baseURL, projectID, defaultPerPage)
+
+	branches, err := collectAll(paginate[Branch](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+func (c *Client) GetBranch(ctx context.Context, projectID int, branchName string) (*Branch, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/branches/%s", c.baseURL, projectID, url.PathEscape(branchName))
+
+	var branch Branch
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &branch); err != nil {
+		return nil, fmt.Errorf("failed to get branch %s: %w", branchName, err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	return &branch, nil
+}
+
+// CreateBranch creates branchName at ref, which may be a branch, tag or SHA.
+func (c *Client) CreateBranch(ctx context.Context, projectID int, branchName, ref string) (*Branch, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/branches", c.baseURL, projectID)
+
+	payload := map[string]string{
+		"branch": branchName,
+		"ref":    ref,
 	}
 
-	return true, nil
+	var branch Branch
+	if err := c.doRequest(ctx, "POST", endpoint, payload, &branch); err != nil {
+		return nil, fmt.Errorf("failed to create branch %s: %w", branchName, err)
+	}
+
+	return &branch, nil
+}
+
+func (c *Client) DeleteBranch(ctx context.Context, projectID int, branchName string) error {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/branches/%s", c.baseURL, projectID, url.PathEscape(branchName))
+
+	if err := c.doRequest(ctx, "DELETE", endpoint, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branchName, err)
+	}
+
+	return nil
+}
+
+// ListCommits returns the commits reachable from ref, newest first. A
+// non-zero since limits them to commits made after it.
+func (c *Client) ListCommits(ctx context.Context, projectID int, ref string, since time.Time) ([]Commit, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/commits?ref_name=%s&per_page=%d",
+		c.baseURL,
+		projectID,
+		url.QueryEscape(ref),
+		defaultPerPage,
+	)
+	if !since.IsZero() {
+		endpoint += "&since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+
+	commits, err := collectAll(paginate[Commit](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	return commits, nil
+}
+
+type CherryPickOptions struct {
+	Branch string `json:"branch"`
+	// DryRun checks that the commit applies cleanly without committing.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// CherryPickCommit applies the commit sha onto a branch and returns the new
+// commit. GitLab reports a failed pick with the error code "conflict", or
+// "empty" when the branch already has the change; see ErrorCode.
+func (c *Client) CherryPickCommit(ctx context.Context, projectID int, sha string, opts CherryPickOptions) (*Commit, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/commits/%s/cherry_pick", c.baseURL, projectID, url.PathEscape(sha))
+
+	var commit Commit
+	if err := c.doRequest(ctx, "POST", endpoint, opts, &commit); err != nil {
+		return nil, fmt.Errorf("failed to cherry-pick %s onto %s: %w", sha, opts.Branch, err)
+	}
+
+	return &commit, nil
+}
+
+// GetCommit resolves ref, which may be a branch, tag or SHA, to its commit.
+func (c *Client) GetCommit(ctx context.Context, projectID int, ref string) (*Commit, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/commits/%s", c.baseURL, projectID, url.PathEscape(ref))
+
+	var commit Commit
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &commit); err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", ref, err)
+	}
+
+	return &commit, nil
+}
+
+// ListTags returns the project's tags, highest version first.
+func (c *Client) ListTags(ctx context.Context, projectID int) ([]Tag, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/tags?order_by=version&sort=desc&per_page=%d", c.baseURL, projectID, defaultPerPage)
+
+	tags, err := collectAll(paginate[Tag](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (c *Client) GetTag(ctx context.Context, projectID int, tagName string) (*Tag, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/tags/%s", c.baseURL, projectID, url.PathEscape(tagName))
+
+	var tag Tag
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &tag); err != nil {
+		return nil, fmt.Errorf("failed to get tag %s: %w", tagName, err)
+	}
+
+	return &tag, nil
+}
+
+// CreateTag creates tagName at ref. A non-empty message makes it an
+// annotated tag.
+func (c *Client) CreateTag(ctx context.Context, projectID int, tagName, ref, message string) (*Tag, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/tags", c.baseURL, projectID)
+
+	payload := map[string]string{
+		"tag_name": tagName,
+		"ref":      ref,
+	}
+	if message != "" {
+		payload["message"] = message
+	}
+
+	var tag Tag
+	if err := c.doRequest(ctx, "POST", endpoint, payload, &tag); err != nil {
+		return nil, fmt.Errorf("failed to create tag %s: %w", tagName, err)
+	}
+
+	return &tag, nil
+}
+
+type CreateReleaseOptions struct {
+	TagName     string `json:"tag_name"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CreateRelease creates a release for an existing tag.
+func (c *Client) CreateRelease(ctx context.Context, projectID int, opts CreateReleaseOptions) (*Release, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/releases", c.baseURL, projectID)
+
+	var release Release
+	if err := c.doRequest(ctx, "POST", endpoint, opts, &release); err != nil {
+		return nil, fmt.Errorf("failed to create release %s: %w", opts.TagName, err)
+	}
+
+	return &release, nil
 }
 
-func (c *Client) FindOpenMergeRequests(projectID int, sourceBranch, targetBranch string) ([]MergeRequest, error) {
-	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests?state=opened&source_branch=%s&target_branch=%s",
+func (c *Client) FindOpenMergeRequests(ctx context.Context, projectID int, sourceBranch, targetBranch string) ([]MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests?state=opened&source_branch=%s&target_branch=%s&per_page=%d",
 		c.baseURL,
 		projectID,
 		url.QueryEscape(sourceBranch),
 		url.QueryEscape(targetBranch),
+		defaultPerPage,
 	)
 
-	var mergeRequests []MergeRequest
-	if err := c.doRequest("GET", endpoint, nil, &mergeRequests); err != nil {
+	mergeRequests, err := collectAll(paginate[MergeRequest](ctx, c, endpoint))
+	if err != nil {
 		return nil, fmt.Errorf("failed to find merge requests: %w", err)
 	}
 
 	return mergeRequests, nil
 }
 
-func (c *Client) CompareBranches(projectID int, sourceBranch, targetBranch string) (*Compare, error) {
+func (c *Client) CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*Compare, error) {
 	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/compare?from=%s&to=%s",
 		c.baseURL,
 		projectID,
@@ -87,107 +269,422 @@ func (c *Client) CompareBranches(projectID int, sourceBranch, targetBranch strin
 	)
 
 	var compare Compare
-	if err := c.doRequest("GET", endpoint, nil, &compare); err != nil {
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &compare); err != nil {
 		return nil, fmt.Errorf("failed to compare branches: %w", err)
 	}
 
 	return &compare, nil
 }
 
-func (c *Client) ListOpenMergeRequestsByTarget(projectID int, targetBranch string) ([]MergeRequest, error) {
-	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests?state=opened&target_branch=%s",
+// MergeBase returns the common ancestor of refs; a ref is an ancestor of
+// another when it is their merge base.
+func (c *Client) MergeBase(ctx context.Context, projectID int, refs ...string) (*Commit, error) {
+	query := url.Values{}
+	for _, ref := range refs {
+		query.Add("refs[]", ref)
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/merge_base?%s", c.baseURL, projectID, query.Encode())
+
+	var commit Commit
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &commit); err != nil {
+		return nil, fmt.Errorf("failed to get merge base: %w", err)
+	}
+
+	return &commit, nil
+}
+
+func (c *Client) ListOpenMergeRequestsByTarget(ctx context.Context, projectID int, targetBranch string) ([]MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests?state=opened&target_branch=%s&per_page=%d",
 		c.baseURL,
 		projectID,
 		url.QueryEscape(targetBranch),
+		defaultPerPage,
 	)
 
-	var mergeRequests []MergeRequest
-	if err := c.doRequest("GET", endpoint, nil, &mergeRequests); err != nil {
+	mergeRequests, err := collectAll(paginate[MergeRequest](ctx, c, endpoint))
+	if err != nil {
 		return nil, fmt.Errorf("failed to list merge requests: %w", err)
 	}
 
 	return mergeRequests, nil
 }
 
-func (c *Client) AcceptMergeRequest(projectID, mrIID int) (*MergeRequest, error) {
+func (c *Client) ListOpenMergeRequests(ctx context.Context, projectID int) ([]MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests?state=opened&per_page=%d",
+		c.baseURL,
+		projectID,
+		defaultPerPage,
+	)
+
+	mergeRequests, err := collectAll(paginate[MergeRequest](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merge requests: %w", err)
+	}
+
+	return mergeRequests, nil
+}
+
+func (c *Client) GetMergeRequest(ctx context.Context, projectID, mrIID int) (*MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d", c.baseURL, projectID, mrIID)
+
+	var mergeRequest MergeRequest
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &mergeRequest); err != nil {
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
+	}
+
+	return &mergeRequest, nil
+}
+
+func (c *Client) GetMergeRequestApprovals(ctx context.Context, projectID, mrIID int) (*Approvals, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/approvals", c.baseURL, projectID, mrIID)
+
+	var approvals Approvals
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &approvals); err != nil {
+		return nil, fmt.Errorf("failed to get merge request approvals: %w", err)
+	}
+
+	return &approvals, nil
+}
+
+type AddToMergeTrainOptions struct {
+	// SHA makes GitLab refuse (409) unless it is still the MR's head.
+	SHA    string
+	Squash bool
+	// WhenPipelineSucceeds adds the MR once its running pipeline succeeds.
+	WhenPipelineSucceeds bool
+}
+
+func (c *Client) AddToMergeTrain(ctx context.Context, projectID, mrIID int, opts AddToMergeTrainOptions) ([]MergeTrainCar, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_trains/merge_requests/%d", c.baseURL, projectID, mrIID)
+
+	payload := map[string]interface{}{}
+	if opts.SHA != "" {
+		payload["sha"] = opts.SHA
+	}
+	if opts.Squash {
+		payload["squash"] = true
+	}
+	if opts.WhenPipelineSucceeds {
+		// GitLab 17 renamed when_pipeline_succeeds to auto_merge; each
+		// version ignores the name it does not know.
+		payload["when_pipeline_succeeds"] = true
+		payload["auto_merge"] = true
+	}
+
+	var cars []MergeTrainCar
+	if err := c.doRequest(ctx, "POST", endpoint, payload, &cars); err != nil {
+		return nil, fmt.Errorf("failed to add merge request to merge train: %w", err)
+	}
+
+	return cars, nil
+}
+
+// GetMergeTrainCar returns the merge train entry of an MR. GitLab answers
+// 404 for MRs that are not (or no longer) on a train.
+func (c *Client) GetMergeTrainCar(ctx context.Context, projectID, mrIID int) (*MergeTrainCar, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_trains/merge_requests/%d", c.baseURL, projectID, mrIID)
+
+	var car MergeTrainCar
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &car); err != nil {
+		return nil, fmt.Errorf("failed to get merge train status: %w", err)
+	}
+
+	return &car, nil
+}
+
+// RebaseMergeRequest asks GitLab to rebase the MR's source branch onto its
+// target. The rebase runs in the background; see WaitForRebase.
+func (c *Client) RebaseMergeRequest(ctx context.Context, projectID, mrIID int) error {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/rebase", c.baseURL, projectID, mrIID)
+
+	if err := c.doRequest(ctx, "PUT", endpoint, nil, nil); err != nil {
+		return fmt.Errorf("failed to rebase merge request: %w", err)
+	}
+
+	return nil
+}
+
+// GetMergeRequestApprovalState returns the approval rules of an MR and
+// whether each is satisfied. Approval rules need GitLab Premium.
+func (c *Client) GetMergeRequestApprovalState(ctx context.Context, projectID, mrIID int) (*ApprovalState, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/approval_state", c.baseURL, projectID, mrIID)
+
+	var state ApprovalState
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &state); err != nil {
+		return nil, fmt.Errorf("failed to get merge request approval state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// ApproveMergeRequest approves an MR as the token's user. A non-empty sha
+// makes GitLab refuse the approval (409) unless it is still the MR's head.
+func (c *Client) ApproveMergeRequest(ctx context.Context, projectID, mrIID int, sha string) (*Approvals, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/approve", c.baseURL, projectID, mrIID)
+
+	payload := map[string]interface{}{}
+	if sha != "" {
+		payload["sha"] = sha
+	}
+
+	var approvals Approvals
+	if err := c.doRequest(ctx, "POST", endpoint, payload, &approvals); err != nil {
+		return nil, fmt.Errorf("failed to approve merge request: %w", err)
+	}
+
+	return &approvals, nil
+}
+
+func (c *Client) UnapproveMergeRequest(ctx context.Context, projectID, mrIID int) error {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/unapprove", c.baseURL, projectID, mrIID)
+
+	if err := c.doRequest(ctx, "POST", endpoint, nil, nil); err != nil {
+		return fmt.Errorf("failed to unapprove merge request: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) ListMergeRequestDiffs(ctx context.Context, projectID, mrIID int) ([]Diff, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/diffs?per_page=%d", c.baseURL, projectID, mrIID, defaultPerPage)
+
+	diffs, err := collectAll(paginate[Diff](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merge request diffs: %w", err)
+	}
+
+	return diffs, nil
+}
+
+func (c *Client) ListMergeRequestPipelines(ctx context.Context, projectID, mrIID int) ([]Pipeline, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/pipelines?per_page=%d", c.baseURL, projectID, mrIID, defaultPerPage)
+
+	pipelines, err := collectAll(paginate[Pipeline](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merge request pipelines: %w", err)
+	}
+
+	return pipelines, nil
+}
+
+func (c *Client) GetPipeline(ctx context.Context, projectID, pipelineID int) (*Pipeline, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/pipelines/%d", c.baseURL, projectID, pipelineID)
+
+	var pipeline Pipeline
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &pipeline); err != nil {
+		return nil, fmt.Errorf("failed to get pipeline: %w", err)
+	}
+
+	return &pipeline, nil
+}
+
+// ListCommitPipelines returns the pipelines GitLab ran for a commit on a ref,
+// newest first.
+func (c *Client) ListCommitPipelines(ctx context.Context, projectID int, ref, sha string) ([]Pipeline, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/pipelines?ref=%s&sha=%s", c.baseURL, projectID, url.QueryEscape(ref), url.QueryEscape(sha))
+
+	var pipelines []Pipeline
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &pipelines); err != nil {
+		return nil, fmt.Errorf("failed to list commit pipelines: %w", err)
+	}
+
+	return pipelines, nil
+}
+
+func (c *Client) ListPipelineJobs(ctx context.Context, projectID, pipelineID int) ([]Job, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/pipelines/%d/jobs?per_page=%d", c.baseURL, projectID, pipelineID, defaultPerPage)
+
+	jobs, err := collectAll(paginate[Job](ctx, c, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pipeline jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+type AcceptMergeRequestOptions struct {
+	// MergeWhenPipelineSucceeds sets the MR to merge automatically once its
+	// running pipeline succeeds instead of merging right away.
+	MergeWhenPipelineSucceeds bool
+	Squash                    bool
+	ShouldRemoveSourceBranch  bool
+	MergeCommitMessage        string
+	SquashCommitMessage       string
+	// SHA makes GitLab refuse the merge (409) unless it is still the head
+	// of the source branch.
+	SHA string
+}
+
+func (c *Client) AcceptMergeRequest(ctx context.Context, projectID, mrIID int, opts AcceptMergeRequestOptions) (*MergeRequest, error) {
 	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/merge", c.baseURL, projectID, mrIID)
 
+	payload := map[string]interface{}{}
+	if opts.MergeWhenPipelineSucceeds {
+		payload["merge_when_pipeline_succeeds"] = true
+	}
+	if opts.Squash {
+		payload["squash"] = true
+	}
+	if opts.ShouldRemoveSourceBranch {
+		payload["should_remove_source_branch"] = true
+	}
+	if opts.MergeCommitMessage != "" {
+		payload["merge_commit_message"] = opts.MergeCommitMessage
+	}
+	if opts.SquashCommitMessage != "" {
+		payload["squash_commit_message"] = opts.SquashCommitMessage
+	}
+	if opts.SHA != "" {
+		payload["sha"] = opts.SHA
+	}
+
 	var mergeRequest MergeRequest
-	if err := c.doRequest("PUT", endpoint, nil, &mergeRequest); err != nil {
+	if err := c.doRequest(ctx, "PUT", endpoint, payload, &mergeRequest); err != nil {
 		return nil, fmt.Errorf("failed to accept merge request: %w", err)
 	}
 
 	return &mergeRequest, nil
 }
 
-func (c *Client) CreateMergeRequest(projectID int, sourceBranch, targetBranch, title, description string) (*MergeRequest, error) {
-	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests", c.baseURL, projectID)
+type CreateMergeRequestOptions struct {
+	SourceBranch string   `json:"source_branch"`
+	TargetBranch string   `json:"target_branch"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Labels       []string `json:"labels,omitempty"`
+	AssigneeIDs  []int    `json:"assignee_ids,omitempty"`
+	ReviewerIDs  []int    `json:"reviewer_ids,omitempty"`
+	MilestoneID  int      `json:"milestone_id,omitempty"`
+}
 
-	payload := map[string]interface{}{
-		"source_branch": sourceBranch,
-		"target_branch": targetBranch,
-		"title":         title,
-		"description":   description,
-	}
+func (c *Client) CreateMergeRequest(ctx context.Context, projectID int, opts CreateMergeRequestOptions) (*MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests", c.baseURL, projectID)
 
 	var mergeRequest MergeRequest
-	if err := c.doRequest("POST", endpoint, payload, &mergeRequest); err != nil {
+	if err := c.doRequest(ctx, "POST", endpoint, opts, &mergeRequest); err != nil {
 		return nil, fmt.Errorf("failed to create merge request: %w", err)
 	}
 
 	return &mergeRequest, nil
 }
 
-func (c *Client) doRequest(method, endpoint string, payload interface{}, result interface{}) error {
-	var body io.Reader
+// GetUserByUsername returns nil without an error when no such user exists.
+func (c *Client) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/users?username=%s", c.baseURL, url.QueryEscape(username))
+
+	var users []User
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &users); err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	return &users[0], nil
+}
+
+func (c *Client) FindMilestone(ctx context.Context, projectID int, title string) (*Milestone, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/milestones?title=%s&include_ancestors=true",
+		c.baseURL,
+		projectID,
+		url.QueryEscape(title),
+	)
+
+	var milestones []Milestone
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &milestones); err != nil {
+		return nil, fmt.Errorf("failed to look up milestone %s: %w", title, err)
+	}
+
+	for i := range milestones {
+		if milestones[i].Title == title {
+			return &milestones[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("milestone %s not found", title)
+}
+
+func (c *Client) GetRawFile(ctx context.Context, projectID int, filePath, ref string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/repository/files/%s/raw?ref=%s",
+		c.baseURL,
+		projectID,
+		url.PathEscape(filePath),
+		url.QueryEscape(ref),
+	)
+
+	content, _, err := c.doRawRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", filePath, ref, err)
+	}
+
+	return content, nil
+}
+
+func (c *Client) doRequest(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) error {
+	_, err := c.doRequestWithHeaders(ctx, method, endpoint, payload, result)
+	return err
+}
+
+func (c *Client) doRequestWithHeaders(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) (http.Header, error) {
+	respBody, header, err := c.doRawRequest(ctx, method, endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+
+	return header, nil
+}
+
+func (c *Client) doRawRequest(ctx context.Context, method, endpoint string, payload interface{}) ([]byte, http.Header, error) {
+	var body []byte
 	if payload != nil {
 		jsonData, err := json.Marshal(payload)
 		if err != nil {
-			return fmt.Errorf("failed to marshal payload: %w", err)
+			return nil, nil, fmt.Errorf("failed to marshal payload: %w", err)
 		}
-		body = strings.NewReader(string(jsonData))
+		body = jsonData
 	}
 
-	resp, err := c.makeRequest(method, endpoint, body)
+	resp, err := c.makeRequest(ctx, method, endpoint, body)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if c.verbose {
-		fmt.Printf("[DEBUG] %s %s -> %d\n", method, endpoint, resp.StatusCode)
+		log.Printf("[DEBUG] %s %s -> %d", method, endpoint, resp.StatusCode)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	if result != nil {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to unmarshal response: %w", err)
-		}
+		return nil, nil, newAPIError(method, endpoint, resp, respBody)
 	}
 
-	return nil
+	return respBody, resp.Header, nil
 }
 
-func (c *Client) ListTopics(page, perPage int) ([]Topic, error) {
+func (c *Client) ListTopics(ctx context.Context, page, perPage int) ([]Topic, error) {
 	endpoint := fmt.Sprintf("%s/api/v4/topics?page=%d&per_page=%d", c.baseURL, page, perPage)
 
 	var topics []Topic
-	if err := c.doRequest("GET", endpoint, nil, &topics); err != nil {
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &topics); err != nil {
 		return nil, fmt.Errorf("failed to list topics: %w", err)
 	}
 
 	return topics, nil
 }
 
-func (c *Client) ListProjectsByTopic(topicName string, page, perPage int) ([]Project, error) {
+func (c *Client) ListProjectsByTopic(ctx context.Context, topicName string, page, perPage int) ([]Project, error) {
 	endpoint := fmt.Sprintf("%s/api/v4/projects?topic=%s&page=%d&per_page=%d",
 		c.baseURL,
 		url.QueryEscape(topicName),
@@ -196,26 +693,53 @@ func (c *Client) ListProjectsByTopic(topicName string, page, perPage int) ([]Pro
 	)
 
 	var projects []Project
-	if err := c.doRequest("GET", endpoint, nil, &projects); err != nil {
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &projects); err != nil {
 		return nil, fmt.Errorf("failed to list projects for topic %s: %w", topicName, err)
 	}
 
 	return projects, nil
 }
 
-func (c *Client) makeRequest(method, endpoint string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, endpoint, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
 
-	req.Header.Set("PRIVATE-TOKEN", c.token)
-	req.Header.Set("Content-Type", "application/json")
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+		req.Header.Set("Content-Type", "application/json")
 
-	return resp, nil
+		resp, err := c.httpClient.Do(req)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if attempt < c.retry.MaxRetries {
+			if delay, ok := c.retry.delay(method, attempt, resp, err, time.Now()); ok {
+				if resp != nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				if c.verbose {
+					log.Printf("[DEBUG] %s %s -> retrying in %s (attempt %d/%d)", method, endpoint, delay, attempt+1, c.retry.MaxRetries)
+				}
+				if err := c.sleep(ctx, delay); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("HTTP request failed: %w", err)
+		}
+
+		return resp, nil
+	}
 }
@@ -0,0 +1,154 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	Message    string
+	// ErrorCode is the machine-readable reason some endpoints add, such as
+	// "conflict" or "empty" for cherry-picks.
+	ErrorCode string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return msg
+}
+
+func newAPIError(method, endpoint string, resp *http.Response, body []byte) *APIError {
+	path := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		path = u.RequestURI()
+	}
+
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Endpoint:   path,
+		Message:    parseErrorMessage(body),
+		ErrorCode:  parseErrorCode(body),
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+}
+
+// parseErrorMessage flattens GitLab's error bodies, which carry either a
+// "message" (a string, a list, or a map of field errors) or an OAuth-style
+// "error"/"error_description" pair.
+func parseErrorMessage(body []byte) string {
+	var payload struct {
+		Message          json.RawMessage `json:"message"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	if len(payload.Message) > 0 {
+		if msg := flattenMessage(payload.Message); msg != "" {
+			return msg
+		}
+	}
+
+	if payload.Error != "" {
+		if payload.ErrorDescription != "" {
+			return payload.Error + ": " + payload.ErrorDescription
+		}
+		return payload.Error
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+func parseErrorCode(body []byte) string {
+	var payload struct {
+		ErrorCode string `json:"error_code"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.ErrorCode
+}
+
+func flattenMessage(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, "; ")
+	}
+
+	var fields map[string][]string
+	if err := json.Unmarshal(raw, &fields); err == nil {
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s %s", key, strings.Join(fields[key], ", ")))
+		}
+		return strings.Join(parts, "; ")
+	}
+
+	return strings.TrimSpace(string(raw))
+}
+
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode
+	}
+	return ""
+}
+
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden
+}
+
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+func IsRateLimited(err error) bool {
+	return StatusCode(err) == http.StatusTooManyRequests
+}
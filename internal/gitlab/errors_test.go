@@ -0,0 +1,139 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseErrorMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "string message",
+			body:     `{"message": "404 Project Not Found"}`,
+			expected: "404 Project Not Found",
+		},
+		{
+			name:     "list message",
+			body:     `{"message": ["Another open merge request already exists for this source branch: !12"]}`,
+			expected: "Another open merge request already exists for this source branch: !12",
+		},
+		{
+			name:     "field errors",
+			body:     `{"message": {"title": ["can't be blank"], "base": ["is invalid"]}}`,
+			expected: "base is invalid; title can't be blank",
+		},
+		{
+			name:     "oauth error",
+			body:     `{"error": "invalid_token", "error_description": "Token was revoked"}`,
+			expected: "invalid_token: Token was revoked",
+		},
+		{
+			name:     "plain text body",
+			body:     "Bad Gateway\n",
+			expected: "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseErrorMessage([]byte(tt.body)); got != tt.expected {
+				t.Errorf("parseErrorMessage() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDoRequest_ReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	_, err := client.GetProject(context.Background(), "group/missing")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Method != "GET" {
+		t.Errorf("unexpected status/method: %d %s", apiErr.StatusCode, apiErr.Method)
+	}
+	if apiErr.Endpoint != "/api/v4/projects/group%2Fmissing" {
+		t.Errorf("unexpected endpoint: %s", apiErr.Endpoint)
+	}
+	if apiErr.Message != "404 Project Not Found" || apiErr.RequestID != "req-123" {
+		t.Errorf("unexpected message/request id: %q %q", apiErr.Message, apiErr.RequestID)
+	}
+	if !IsNotFound(err) || IsConflict(err) {
+		t.Error("expected IsNotFound to match and IsConflict not to")
+	}
+}
+
+func TestStatusHelpers(t *testing.T) {
+	wrap := func(code int) error {
+		return fmt.Errorf("context: %w", &APIError{StatusCode: code})
+	}
+
+	if !IsUnauthorized(wrap(http.StatusUnauthorized)) {
+		t.Error("IsUnauthorized should match 401")
+	}
+	if !IsForbidden(wrap(http.StatusForbidden)) {
+		t.Error("IsForbidden should match 403")
+	}
+	if !IsConflict(wrap(http.StatusConflict)) {
+		t.Error("IsConflict should match 409")
+	}
+	if !IsRateLimited(wrap(http.StatusTooManyRequests)) {
+		t.Error("IsRateLimited should match 429")
+	}
+	if IsNotFound(errors.New("plain error")) {
+		t.Error("IsNotFound should not match non-API errors")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message": "Sorry, we cannot cherry-pick this commit automatically.", "error_code": "conflict"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	_, err := client.CherryPickCommit(context.Background(), 1, "abc123", CherryPickOptions{Branch: "op-rc"})
+
+	if ErrorCode(err) != "conflict" {
+		t.Errorf("expected error code conflict, got %q (%v)", ErrorCode(err), err)
+	}
+	if ErrorCode(errors.New("plain error")) != "" {
+		t.Error("ErrorCode should be empty for non-API errors")
+	}
+}
+
+func TestBranchExists_NotFoundIsFalse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "404 Branch Not Found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	exists, err := client.BranchExists(context.Background(), 1, "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exists {
+		t.Error("expected branch not to exist")
+	}
+}
@@ -0,0 +1,149 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultPerPage = 100
+
+// paginate walks every page of a list endpoint, following the Link header
+// (keyset pagination) when present and falling back to X-Next-Page.
+func paginate[T any](ctx context.Context, c *Client, endpoint string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := endpoint
+		for next != "" {
+			var items []T
+			header, err := c.doRequestWithHeaders(ctx, "GET", next, nil, &items)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if len(items) == 0 {
+				return
+			}
+
+			following := nextPageURL(next, header)
+			if following == next {
+				return
+			}
+			next = following
+		}
+	}
+}
+
+func collectAll[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func nextPageURL(current string, header http.Header) string {
+	if link := parseLinkHeader(header.Get("Link"))["next"]; link != "" {
+		return link
+	}
+
+	nextPage := strings.TrimSpace(header.Get("X-Next-Page"))
+	if nextPage == "" {
+		return ""
+	}
+
+	if _, err := strconv.Atoi(nextPage); err != nil {
+		return ""
+	}
+
+	u, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+
+	query := u.Query()
+	query.Set("page", nextPage)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func parseLinkHeader(value string) map[string]string {
+	links := make(map[string]string)
+
+	for _, part := range strings.Split(value, ",") {
+		segments := strings.Split(strings.TrimSpace(part), ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		target = target[1 : len(target)-1]
+
+		for _, param := range segments[1:] {
+			key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(key) != "rel" {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+				links[rel] = target
+			}
+		}
+	}
+
+	return links
+}
+
+func perPageOrDefault(perPage int) int {
+	if perPage <= 0 {
+		return defaultPerPage
+	}
+	return perPage
+}
+
+func (c *Client) AllTopics(ctx context.Context, perPage int) iter.Seq2[Topic, error] {
+	endpoint := fmt.Sprintf("%s/api/v4/topics?per_page=%d", c.baseURL, perPageOrDefault(perPage))
+	return paginate[Topic](ctx, c, endpoint)
+}
+
+func (c *Client) ListAllTopics(ctx context.Context, perPage int) ([]Topic, error) {
+	topics, err := collectAll(c.AllTopics(ctx, perPage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	return topics, nil
+}
+
+func (c *Client) AllProjectsByTopic(ctx context.Context, topicName string, perPage int) iter.Seq2[Project, error] {
+	endpoint := fmt.Sprintf("%s/api/v4/projects?topic=%s&pagination=keyset&order_by=id&sort=asc&per_page=%d",
+		c.baseURL,
+		url.QueryEscape(topicName),
+		perPageOrDefault(perPage),
+	)
+	return paginate[Project](ctx, c, endpoint)
+}
+
+func (c *Client) ListAllProjectsByTopic(ctx context.Context, topicName string, perPage int) ([]Project, error) {
+	projects, err := collectAll(c.AllProjectsByTopic(ctx, topicName, perPage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects for topic %s: %w", topicName, err)
+	}
+	return projects, nil
+}
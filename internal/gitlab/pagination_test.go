@@ -0,0 +1,116 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	header := `<https://gitlab.example.com/api/v4/projects?id_after=42&per_page=2>; rel="next", <https://gitlab.example.com/api/v4/projects?per_page=2>; rel="first"`
+
+	links := parseLinkHeader(header)
+
+	if got := links["next"]; got != "https://gitlab.example.com/api/v4/projects?id_after=42&per_page=2" {
+		t.Errorf("next link = %q", got)
+	}
+	if got := links["first"]; got != "https://gitlab.example.com/api/v4/projects?per_page=2" {
+		t.Errorf("first link = %q", got)
+	}
+}
+
+func TestListAllTopics_FollowsNextPageHeader(t *testing.T) {
+	pages := map[int][]Topic{
+		1: {{ID: 1, Name: "backend"}, {ID: 2, Name: "frontend"}},
+		2: {{ID: 3, Name: "infra"}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if _, ok := pages[page+1]; ok {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		} else {
+			w.Header().Set("X-Next-Page", "")
+		}
+		_ = json.NewEncoder(w).Encode(pages[page])
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	topics, err := client.ListAllTopics(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(topics) != 3 {
+		t.Fatalf("expected 3 topics, got %d", len(topics))
+	}
+	if topics[2].Name != "infra" {
+		t.Errorf("expected last topic infra, got %s", topics[2].Name)
+	}
+}
+
+func TestListAllProjectsByTopic_FollowsKeysetLink(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("topic") != "backend" {
+			t.Errorf("expected topic filter, got %q", query.Get("topic"))
+		}
+
+		switch query.Get("id_after") {
+		case "":
+			if query.Get("pagination") != "keyset" {
+				t.Errorf("expected keyset pagination, got %q", query.Get("pagination"))
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects?topic=backend&pagination=keyset&order_by=id&sort=asc&per_page=1&id_after=1>; rel="next"`, server.URL))
+			_ = json.NewEncoder(w).Encode([]Project{{ID: 1, PathWithNamespace: "group/a"}})
+		case "1":
+			_ = json.NewEncoder(w).Encode([]Project{{ID: 2, PathWithNamespace: "group/b"}})
+		default:
+			t.Errorf("unexpected id_after %q", query.Get("id_after"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	projects, err := client.ListAllProjectsByTopic(context.Background(), "backend", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(projects) != 2 || projects[1].PathWithNamespace != "group/b" {
+		t.Errorf("unexpected projects: %+v", projects)
+	}
+}
+
+func TestAllProjectsByTopic_StopsEarly(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Next-Page", strconv.Itoa(requests+1))
+		_ = json.NewEncoder(w).Encode([]Project{{ID: requests}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", false)
+	for project, err := range client.AllProjectsByTopic(context.Background(), "backend", 1) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if project.ID == 2 {
+			break
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
@@ -0,0 +1,170 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PollPolicy bounds how long the client waits for work GitLab finishes in
+// the background, such as computing whether an MR can be merged. Timeout
+// counts the time spent waiting between attempts.
+type PollPolicy struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+	Timeout     time.Duration
+}
+
+func DefaultPollPolicy() PollPolicy {
+	return PollPolicy{
+		Interval:    time.Second,
+		MaxInterval: 5 * time.Second,
+		Timeout:     time.Minute,
+	}
+}
+
+func (c *Client) SetPollPolicy(policy PollPolicy) {
+	c.pollPolicy = policy
+}
+
+// poll calls check until it reports done, doubling the wait in between up to
+// MaxInterval. It returns false without an error when the timeout (0 = the
+// policy's) passed.
+func (c *Client) poll(ctx context.Context, timeout time.Duration, check func() (bool, error)) (bool, error) {
+	policy := c.pollPolicy
+	if timeout <= 0 {
+		timeout = policy.Timeout
+	}
+
+	interval := policy.Interval
+	var waited time.Duration
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return done, err
+		}
+
+		if waited+interval > timeout {
+			return false, nil
+		}
+
+		if err := c.sleep(ctx, interval); err != nil {
+			return false, err
+		}
+		waited += interval
+
+		interval *= 2
+		if interval > policy.MaxInterval {
+			interval = policy.MaxInterval
+		}
+	}
+}
+
+// WaitForMergeStatus fetches an MR, asking GitLab to recheck its
+// mergeability, until the check has finished or the poll timeout passed.
+// The last fetched MR is returned either way; MergeStatusChecked tells which.
+func (c *Client) WaitForMergeStatus(ctx context.Context, projectID, mrIID int) (*MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d?with_merge_status_recheck=true&include_diverged_commits_count=true", c.baseURL, projectID, mrIID)
+
+	mergeRequest, err := c.waitForMergeRequest(ctx, endpoint, 0, (*MergeRequest).MergeStatusChecked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request status: %w", err)
+	}
+
+	return mergeRequest, nil
+}
+
+// WaitForRebase polls an MR until GitLab has finished rebasing it. A failed
+// rebase is reported through MergeError on the returned MR.
+func (c *Client) WaitForRebase(ctx context.Context, projectID, mrIID int) (*MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d?include_rebase_in_progress=true", c.baseURL, projectID, mrIID)
+
+	mergeRequest, err := c.waitForMergeRequest(ctx, endpoint, 0, func(mr *MergeRequest) bool {
+		return !mr.RebaseInProgress
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request rebase status: %w", err)
+	}
+
+	return mergeRequest, nil
+}
+
+// WaitForMergeRequest re-fetches an MR until done reports true or the
+// timeout (0 = the poll policy's) passed, and returns the last fetched MR.
+func (c *Client) WaitForMergeRequest(ctx context.Context, projectID, mrIID int, timeout time.Duration, done func(*MergeRequest) bool) (*MergeRequest, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d", c.baseURL, projectID, mrIID)
+
+	mergeRequest, err := c.waitForMergeRequest(ctx, endpoint, timeout, done)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
+	}
+
+	return mergeRequest, nil
+}
+
+func (c *Client) waitForMergeRequest(ctx context.Context, endpoint string, timeout time.Duration, done func(*MergeRequest) bool) (*MergeRequest, error) {
+	var mergeRequest *MergeRequest
+	_, err := c.poll(ctx, timeout, func() (bool, error) {
+		var current MergeRequest
+		if err := c.doRequest(ctx, "GET", endpoint, nil, &current); err != nil {
+			return false, err
+		}
+		mergeRequest = &current
+		return done(&current), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeRequest, nil
+}
+
+// WaitForCommitPipeline polls the newest pipeline of a commit on a ref until
+// done reports true or the timeout (0 = the poll policy's) passed. done gets
+// nil, and nil is returned, while there is no pipeline.
+func (c *Client) WaitForCommitPipeline(ctx context.Context, projectID int, ref, sha string, timeout time.Duration, done func(*Pipeline) bool) (*Pipeline, error) {
+	var pipeline *Pipeline
+	_, err := c.poll(ctx, timeout, func() (bool, error) {
+		pipelines, err := c.ListCommitPipelines(ctx, projectID, ref, sha)
+		if err != nil {
+			return false, err
+		}
+		pipeline = nil
+		if len(pipelines) > 0 {
+			pipeline = &pipelines[0]
+		}
+		return done(pipeline), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeline, nil
+}
+
+// WaitForMergeTrainCar polls an MR's merge train entry until it has been
+// merged, it left the train (removed is true), or the timeout (0 = the poll
+// policy's) passed. car is the last entry seen, nil if there never was one.
+// A missing entry only counts as removed once it was seen or when joined
+// tells that the MR was already on the train; otherwise the MR has yet to
+// join it, as MRs set to join when their pipeline succeeds do.
+func (c *Client) WaitForMergeTrainCar(ctx context.Context, projectID, mrIID int, joined bool, timeout time.Duration) (car *MergeTrainCar, removed bool, err error) {
+	_, err = c.poll(ctx, timeout, func() (bool, error) {
+		current, err := c.GetMergeTrainCar(ctx, projectID, mrIID)
+		if IsNotFound(err) {
+			removed = joined || car != nil
+			return removed, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		car = current
+		return current.IsFinished(), nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return car, removed, nil
+}
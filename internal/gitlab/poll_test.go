@@ -0,0 +1,175 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitForMergeStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		checked  bool
+		requests int
+		sleeps   []time.Duration
+	}{
+		{
+			name:     "already checked",
+			statuses: []string{"mergeable"},
+			checked:  true,
+			requests: 1,
+		},
+		{
+			name:     "polls until checked",
+			statuses: []string{"unchecked", "checking", "checking", "conflict"},
+			checked:  true,
+			requests: 4,
+			sleeps:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:     "gives up after the timeout",
+			statuses: []string{"checking", "checking", "checking", "checking", "checking"},
+			checked:  false,
+			requests: 4,
+			sleeps:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("with_merge_status_recheck") != "true" {
+					t.Errorf("expected a recheck to be requested, got %s", r.URL)
+				}
+				status := tt.statuses[requests]
+				requests++
+				fmt.Fprintf(w, `{"iid": 3, "detailed_merge_status": %q}`, status)
+			}))
+			defer server.Close()
+
+			client, sleeps := newTestClient(server.URL)
+			client.SetPollPolicy(PollPolicy{Interval: time.Second, MaxInterval: 3 * time.Second, Timeout: 6 * time.Second})
+
+			mr, err := client.WaitForMergeStatus(context.Background(), 1, 3)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if mr.MergeStatusChecked() != tt.checked {
+				t.Errorf("expected checked=%v, got status %s", tt.checked, mr.DetailedMergeStatus)
+			}
+			if requests != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, requests)
+			}
+			if fmt.Sprint(*sleeps) != fmt.Sprint(tt.sleeps) {
+				t.Errorf("expected sleeps %v, got %v", tt.sleeps, *sleeps)
+			}
+		})
+	}
+}
+
+func TestWaitForRebase(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("include_rebase_in_progress") != "true" {
+			t.Errorf("expected the rebase state to be requested, got %s", r.URL)
+		}
+		requests++
+		if requests < 3 {
+			fmt.Fprint(w, `{"iid": 3, "rebase_in_progress": true}`)
+			return
+		}
+		fmt.Fprint(w, `{"iid": 3, "sha": "def456", "rebase_in_progress": false, "merge_error": null}`)
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(server.URL)
+	mr, err := client.WaitForRebase(context.Background(), 1, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mr.RebaseInProgress || mr.SHA != "def456" || mr.MergeError != "" {
+		t.Errorf("unexpected MR after rebase: %+v", mr)
+	}
+	if requests != 3 || len(*sleeps) != 2 {
+		t.Errorf("expected 3 requests and 2 sleeps, got %d and %d", requests, len(*sleeps))
+	}
+}
+
+func TestWaitForMergeTrainCar(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		joined    bool
+		status    string
+		removed   bool
+	}{
+		{
+			name:      "merged",
+			responses: []string{`{"status": "fresh"}`, `{"status": "merging"}`, `{"status": "merged"}`},
+			joined:    true,
+			status:    "merged",
+		},
+		{
+			name:      "dropped",
+			responses: []string{`{"status": "fresh", "pipeline": {"status": "failed"}}`, ``},
+			joined:    true,
+			status:    "fresh",
+			removed:   true,
+		},
+		{
+			name:      "left before the first check",
+			responses: []string{``},
+			joined:    true,
+			removed:   true,
+		},
+		{
+			name:      "joins later and is dropped",
+			responses: []string{``, ``, `{"status": "fresh", "pipeline": {"status": "failed"}}`, ``},
+			status:    "fresh",
+			removed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v4/projects/1/merge_trains/merge_requests/3" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				response := tt.responses[requests]
+				requests++
+				if response == "" {
+					http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, response)
+			}))
+			defer server.Close()
+
+			client, _ := newTestClient(server.URL)
+			car, removed, err := client.WaitForMergeTrainCar(context.Background(), 1, 3, tt.joined, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var status string
+			if car != nil {
+				status = car.Status
+			}
+			if status != tt.status || removed != tt.removed {
+				t.Errorf("expected %q (removed=%v), got %q (removed=%v)", tt.status, tt.removed, status, removed)
+			}
+			if requests != len(tt.responses) {
+				t.Errorf("expected %d requests, got %d", len(tt.responses), requests)
+			}
+		})
+	}
+}
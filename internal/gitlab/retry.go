@@ -0,0 +1,118 @@
+package gitlab
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   60 * time.Second,
+	}
+}
+
+// delay reports whether the request should be retried and how long to wait.
+// Requests that GitLab rejected before doing any work (429, failed dials)
+// are always safe to repeat; everything else is only retried for idempotent
+// methods so a POST or PUT is never replayed after the server may have acted
+// on it.
+func (p RetryPolicy) delay(method string, attempt int, resp *http.Response, err error, now time.Time) (time.Duration, bool) {
+	if err != nil {
+		if !isIdempotent(method) && !isDialError(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if wait, ok := rateLimitWait(resp.Header, now); ok {
+			if wait > p.MaxDelay {
+				return 0, false
+			}
+			return wait, true
+		}
+		return p.backoff(attempt), true
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		if !isIdempotent(method) {
+			return 0, false
+		}
+		if wait, ok := rateLimitWait(resp.Header, now); ok && wait <= p.MaxDelay {
+			return wait, true
+		}
+		return p.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// backoff doubles the base delay per attempt and picks a random point in the
+// upper half of that window so parallel clients do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.BaseDelay << attempt
+	if wait <= 0 || wait > p.MaxDelay {
+		wait = p.MaxDelay
+	}
+
+	half := wait / 2
+	if half <= 0 {
+		return wait
+	}
+
+	return half + rand.N(half+1)
+}
+
+func rateLimitWait(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return clampWait(time.Duration(seconds) * time.Second), true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return clampWait(at.Sub(now)), true
+		}
+	}
+
+	if value := strings.TrimSpace(header.Get("RateLimit-Reset")); value != "" {
+		if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return clampWait(time.Unix(epoch, 0).Sub(now)), true
+		}
+	}
+
+	return 0, false
+}
+
+func clampWait(wait time.Duration) time.Duration {
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// isIdempotent leaves out PUT: GitLab uses it for actions such as merging and
+// rebasing, which fail rather than succeed again when replayed.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
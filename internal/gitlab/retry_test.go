@@ -0,0 +1,201 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestClient(url string) (*Client, *[]time.Duration) {
+	var sleeps []time.Duration
+	client := NewClient(url, "token", false)
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute})
+	client.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return client, &sleeps
+}
+
+func TestDoRequest_RetriesServerErrorsForGet(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 7}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(server.URL)
+	project, err := client.GetProject(context.Background(), "group/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if project.ID != 7 {
+		t.Errorf("expected project 7, got %d", project.ID)
+	}
+	if attempts != 3 || len(*sleeps) != 2 {
+		t.Errorf("expected 3 attempts and 2 sleeps, got %d and %d", attempts, len(*sleeps))
+	}
+}
+
+func TestDoRequest_DoesNotRetryPostOnServerError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server.URL)
+	if _, err := client.CreateMergeRequest(context.Background(), 1, CreateMergeRequestOptions{SourceBranch: "op-stage", TargetBranch: "op-rc", Title: "title"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestDoRequest_DoesNotRetryPutOnServerError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server.URL)
+	if _, err := client.AcceptMergeRequest(context.Background(), 1, 2, AcceptMergeRequestOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestDoRequest_RetriesPostWhenRateLimited(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 5}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(server.URL)
+	mr, err := client.CreateMergeRequest(context.Background(), 1, CreateMergeRequestOptions{SourceBranch: "op-stage", TargetBranch: "op-rc", Title: "title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mr.IID != 5 {
+		t.Errorf("expected MR !5, got !%d", mr.IID)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 2*time.Second {
+		t.Errorf("expected a single 2s wait, got %v", *sleeps)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+		ok       bool
+	}{
+		{
+			name:     "retry-after seconds",
+			header:   http.Header{"Retry-After": []string{"5"}},
+			expected: 5 * time.Second,
+			ok:       true,
+		},
+		{
+			name:     "retry-after http date",
+			header:   http.Header{"Retry-After": []string{now.Add(10 * time.Second).UTC().Format(http.TimeFormat)}},
+			expected: 10 * time.Second,
+			ok:       true,
+		},
+		{
+			name:     "ratelimit-reset epoch",
+			header:   http.Header{"Ratelimit-Reset": []string{strconv.FormatInt(now.Unix()+30, 10)}},
+			expected: 30 * time.Second,
+			ok:       true,
+		},
+		{
+			name:     "reset in the past",
+			header:   http.Header{"Ratelimit-Reset": []string{strconv.FormatInt(now.Unix()-30, 10)}},
+			expected: 0,
+			ok:       true,
+		},
+		{
+			name:   "no headers",
+			header: http.Header{},
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := rateLimitWait(tt.header, now)
+			if ok != tt.ok || wait != tt.expected {
+				t.Errorf("rateLimitWait() = %v, %v; expected %v, %v", wait, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_BackoffStaysWithinBounds(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := policy.backoff(attempt)
+		window := policy.BaseDelay << attempt
+		if window > policy.MaxDelay || window <= 0 {
+			window = policy.MaxDelay
+		}
+		if wait < window/2 || wait > window {
+			t.Errorf("attempt %d: backoff %v outside [%v, %v]", attempt, wait, window/2, window)
+		}
+	}
+}
+
+func TestDoRequest_StopsRetryingWhenContextCanceled(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(server.URL, "token", false)
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	_, err := client.GetProject(ctx, "group/repo")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
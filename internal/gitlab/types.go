@@ -1,6 +1,10 @@
 package gitlab
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Project struct {
 	ID                int      `json:"id"`
@@ -9,6 +13,7 @@ type Project struct {
 	WebURL            string   `json:"web_url"`
 	Description       string   `json:"description"`
 	Topics            []string `json:"topics"`
+	DefaultBranch     string   `json:"default_branch"`
 }
 
 type Topic struct {
@@ -20,21 +25,165 @@ type Topic struct {
 }
 
 type MergeRequest struct {
+	ID           int        `json:"id"`
+	IID          int        `json:"iid"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	WebURL       string     `json:"web_url"`
+	State        string     `json:"state"`
+	Draft        bool       `json:"draft"`
+	SourceBranch string     `json:"source_branch"`
+	TargetBranch string     `json:"target_branch"`
+	ProjectID    int        `json:"project_id"`
+	Labels       []string   `json:"labels"`
+	Milestone    *Milestone `json:"milestone"`
+	SHA          string     `json:"sha"`
+	HasConflicts bool       `json:"has_conflicts"`
+	// MergeStatus is the legacy mergeability field; DetailedMergeStatus
+	// (GitLab 15.6+) also covers discussions, approvals and pipelines.
+	MergeStatus                 string `json:"merge_status"`
+	DetailedMergeStatus         string `json:"detailed_merge_status"`
+	BlockingDiscussionsResolved bool   `json:"blocking_discussions_resolved"`
+	// DivergedCommitsCount (commits the source is behind the target) and
+	// RebaseInProgress are only returned when asked for.
+	DivergedCommitsCount int    `json:"diverged_commits_count"`
+	RebaseInProgress     bool   `json:"rebase_in_progress"`
+	MergeError           string `json:"merge_error"`
+	MergeCommitSHA       string `json:"merge_commit_sha"`
+	SquashCommitSHA      string `json:"squash_commit_sha"`
+	// HeadPipeline is only returned when fetching a single merge request.
+	HeadPipeline *Pipeline `json:"head_pipeline"`
+
+	MergeWhenPipelineSucceeds bool `json:"merge_when_pipeline_succeeds"`
+}
+
+type Pipeline struct {
+	ID     int    `json:"id"`
+	IID    int    `json:"iid"`
+	SHA    string `json:"sha"`
+	Ref    string `json:"ref"`
+	Status string `json:"status"`
+	WebURL string `json:"web_url"`
+}
+
+type MergeTrainCar struct {
+	ID           int          `json:"id"`
+	MergeRequest MergeRequest `json:"merge_request"`
+	Pipeline     *Pipeline    `json:"pipeline"`
+	Status       string       `json:"status"`
+	TargetBranch string       `json:"target_branch"`
+	CreatedAt    time.Time    `json:"created_at"`
+	MergedAt     *time.Time   `json:"merged_at"`
+	Duration     int          `json:"duration"`
+}
+
+// IsFinished reports whether the car left the train by being merged. Cars
+// that are dropped disappear from the train instead.
+func (c *MergeTrainCar) IsFinished() bool {
+	return c.Status == "merged" || c.Status == "skip_merged"
+}
+
+type Job struct {
 	ID           int    `json:"id"`
-	IID          int    `json:"iid"`
-	Title        string `json:"title"`
+	Name         string `json:"name"`
+	Stage        string `json:"stage"`
+	Status       string `json:"status"`
+	AllowFailure bool   `json:"allow_failure"`
 	WebURL       string `json:"web_url"`
-	State        string `json:"state"`
-	Draft        bool   `json:"draft"`
-	SourceBranch string `json:"source_branch"`
-	TargetBranch string `json:"target_branch"`
-	ProjectID    int    `json:"project_id"`
+}
+
+type Approvals struct {
+	Approved          bool       `json:"approved"`
+	ApprovalsRequired int        `json:"approvals_required"`
+	ApprovalsLeft     int        `json:"approvals_left"`
+	ApprovedBy        []Approver `json:"approved_by"`
+	// UserHasApproved and UserCanApprove refer to the token's user.
+	UserHasApproved bool `json:"user_has_approved"`
+	UserCanApprove  bool `json:"user_can_approve"`
+}
+
+type Approver struct {
+	User User `json:"user"`
+}
+
+type ApprovalState struct {
+	RulesOverwritten bool           `json:"approval_rules_overwritten"`
+	Rules            []ApprovalRule `json:"rules"`
+}
+
+type ApprovalRule struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	RuleType          string `json:"rule_type"`
+	ApprovalsRequired int    `json:"approvals_required"`
+	Approved          bool   `json:"approved"`
+	ApprovedBy        []User `json:"approved_by"`
+}
+
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	WebURL   string `json:"web_url"`
+}
+
+type Milestone struct {
+	ID     int    `json:"id"`
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
 }
 
 type Commit struct {
-	ID      string `json:"id"`
-	ShortID string `json:"short_id"`
-	Title   string `json:"title"`
+	ID          string    `json:"id"`
+	ShortID     string    `json:"short_id"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	CreatedAt   time.Time `json:"created_at"`
+	// CommittedDate is when the commit was made, as opposed to authored.
+	CommittedDate time.Time `json:"committed_date"`
+	WebURL        string    `json:"web_url"`
+}
+
+type Branch struct {
+	Name      string `json:"name"`
+	Commit    Commit `json:"commit"`
+	Merged    bool   `json:"merged"`
+	Protected bool   `json:"protected"`
+	Default   bool   `json:"default"`
+	WebURL    string `json:"web_url"`
+}
+
+type Tag struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	// Target is the SHA of the tag object for annotated tags and of the
+	// commit otherwise; Commit is always the tagged commit.
+	Target    string   `json:"target"`
+	Commit    Commit   `json:"commit"`
+	Release   *TagInfo `json:"release"`
+	Protected bool     `json:"protected"`
+}
+
+// TagInfo is the release summary embedded in a tag.
+type TagInfo struct {
+	TagName     string `json:"tag_name"`
+	Description string `json:"description"`
+}
+
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Links       struct {
+		Self string `json:"self"`
+	} `json:"_links"`
 }
 
 type Compare struct {
@@ -51,12 +200,27 @@ type Diff struct {
 	NewFile     bool   `json:"new_file"`
 	RenamedFile bool   `json:"renamed_file"`
 	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
 }
 
 func (c *Compare) HasChanges() bool {
 	return len(c.Commits) > 0
 }
 
+// LineChanges counts added and removed lines in the hunk text GitLab
+// returns for a diff (it starts at the first @@ header, without file headers).
+func (d *Diff) LineChanges() (added, deleted int) {
+	for _, line := range strings.Split(d.Diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
 func (mr *MergeRequest) IsDraft() bool {
 	if mr.Draft {
 		return true
@@ -65,3 +229,83 @@ func (mr *MergeRequest) IsDraft() bool {
 	titleLower := strings.ToLower(strings.TrimSpace(mr.Title))
 	return strings.HasPrefix(titleLower, "draft:") || strings.HasPrefix(titleLower, "wip:")
 }
+
+// MergeStatusChecked reports whether GitLab has finished computing the
+// MR's mergeability.
+func (mr *MergeRequest) MergeStatusChecked() bool {
+	switch mr.DetailedMergeStatus {
+	case "unchecked", "checking", "preparing", "approvals_syncing":
+		return false
+	}
+
+	switch mr.MergeStatus {
+	case "unchecked", "checking", "cannot_be_merged_recheck":
+		return false
+	}
+
+	return true
+}
+
+// ResultingSHA returns the commit the target branch got from merging the MR:
+// the merge commit, else the squash commit, else (fast-forward) the head.
+func (mr *MergeRequest) ResultingSHA() string {
+	switch {
+	case mr.MergeCommitSHA != "":
+		return mr.MergeCommitSHA
+	case mr.SquashCommitSHA != "":
+		return mr.SquashCommitSHA
+	default:
+		return mr.SHA
+	}
+}
+
+// IsBehind reports whether the target branch has commits the source branch
+// lacks, as far as the fetched fields tell.
+func (mr *MergeRequest) IsBehind() bool {
+	return mr.DetailedMergeStatus == "need_rebase" || mr.DivergedCommitsCount > 0
+}
+
+func (mr *MergeRequest) IsConflicting() bool {
+	return mr.HasConflicts || mr.DetailedMergeStatus == "conflict" || mr.MergeStatus == "cannot_be_merged"
+}
+
+// Blocker explains what keeps GitLab from merging the MR until someone acts
+// on it, or returns "". Conflicts, drafts, approvals and pipelines are left
+// out: callers check those themselves.
+func (mr *MergeRequest) Blocker() string {
+	switch mr.DetailedMergeStatus {
+	case "", "mergeable", "unchecked", "checking", "preparing", "approvals_syncing",
+		"conflict", "draft_status", "not_approved", "ci_must_pass", "ci_still_running":
+	case "discussions_not_resolved":
+		return "unresolved discussions"
+	case "need_rebase":
+		return "source branch must be rebased"
+	case "blocked_status":
+		return "blocked by another merge request"
+	case "not_open":
+		return "merge request is not open"
+	case "requested_changes":
+		return "a reviewer requested changes"
+	case "external_status_checks":
+		return "external status checks have not passed"
+	default:
+		return fmt.Sprintf("GitLab reports %s", strings.ReplaceAll(mr.DetailedMergeStatus, "_", " "))
+	}
+
+	// Instances before GitLab 15.6 have no detailed status and only report
+	// discussions through blocking_discussions_resolved.
+	if mr.DetailedMergeStatus == "" && mr.MergeStatus != "" && !mr.BlockingDiscussionsResolved {
+		return "unresolved discussions"
+	}
+
+	return ""
+}
+
+// IsActive reports whether the pipeline has not finished yet.
+func (p *Pipeline) IsActive() bool {
+	switch p.Status {
+	case "created", "waiting_for_resource", "preparing", "pending", "running", "scheduled":
+		return true
+	}
+	return false
+}
@@ -84,3 +84,83 @@ func TestMergeRequest_IsDraft(t *testing.T) {
 		})
 	}
 }
+
+func TestDiff_LineChanges(t *testing.T) {
+	diff := Diff{
+		Diff: "@@ -1,3 +1,4 @@\n package main\n-import \"fmt\"\n+import (\n+\t\"fmt\"\n+)\n--- a removed SQL comment\n\\ No newline at end of file\n",
+	}
+
+	added, deleted := diff.LineChanges()
+	if added != 3 || deleted != 2 {
+		t.Errorf("LineChanges() = %d, %d; expected 3, 2", added, deleted)
+	}
+}
+
+func TestMergeRequest_Mergeability(t *testing.T) {
+	tests := []struct {
+		name        string
+		mr          MergeRequest
+		checked     bool
+		conflicting bool
+		blocker     string
+	}{
+		{
+			name:    "mergeable",
+			mr:      MergeRequest{MergeStatus: "can_be_merged", DetailedMergeStatus: "mergeable", BlockingDiscussionsResolved: true},
+			checked: true,
+		},
+		{
+			name: "still checking",
+			mr:   MergeRequest{MergeStatus: "checking", DetailedMergeStatus: "checking"},
+		},
+		{
+			name:        "conflict",
+			mr:          MergeRequest{MergeStatus: "cannot_be_merged", DetailedMergeStatus: "conflict"},
+			checked:     true,
+			conflicting: true,
+		},
+		{
+			name:        "legacy conflict",
+			mr:          MergeRequest{MergeStatus: "cannot_be_merged", BlockingDiscussionsResolved: true},
+			checked:     true,
+			conflicting: true,
+		},
+		{
+			name:    "unresolved discussions",
+			mr:      MergeRequest{MergeStatus: "can_be_merged", DetailedMergeStatus: "discussions_not_resolved"},
+			checked: true,
+			blocker: "unresolved discussions",
+		},
+		{
+			name:    "legacy unresolved discussions",
+			mr:      MergeRequest{MergeStatus: "can_be_merged"},
+			checked: true,
+			blocker: "unresolved discussions",
+		},
+		{
+			name:    "policy checks are left to callers",
+			mr:      MergeRequest{MergeStatus: "can_be_merged", DetailedMergeStatus: "ci_must_pass"},
+			checked: true,
+		},
+		{
+			name:    "unknown status",
+			mr:      MergeRequest{MergeStatus: "can_be_merged", DetailedMergeStatus: "merge_time"},
+			checked: true,
+			blocker: "GitLab reports merge time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mr.MergeStatusChecked(); got != tt.checked {
+				t.Errorf("MergeStatusChecked() = %v, expected %v", got, tt.checked)
+			}
+			if got := tt.mr.IsConflicting(); got != tt.conflicting {
+				t.Errorf("IsConflicting() = %v, expected %v", got, tt.conflicting)
+			}
+			if got := tt.mr.Blocker(); got != tt.blocker {
+				t.Errorf("Blocker() = %q, expected %q", got, tt.blocker)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type MessageData struct {
+	Project      gitlab.Project
+	MergeRequest gitlab.MergeRequest
+}
+
+// Messages holds the commit message templates. A nil template leaves the
+// message to GitLab's project default.
+type Messages struct {
+	mergeCommit  *template.Template
+	squashCommit *template.Template
+}
+
+var messageFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+func NewMessages(mergeCommit, squashCommit string) (*Messages, error) {
+	messages := &Messages{}
+
+	var err error
+	if mergeCommit != "" {
+		if messages.mergeCommit, err = template.New("merge-commit").Funcs(messageFuncs).Parse(mergeCommit); err != nil {
+			return nil, fmt.Errorf("invalid merge commit message template: %w", err)
+		}
+	}
+
+	if squashCommit != "" {
+		if messages.squashCommit, err = template.New("squash-commit").Funcs(messageFuncs).Parse(squashCommit); err != nil {
+			return nil, fmt.Errorf("invalid squash commit message template: %w", err)
+		}
+	}
+
+	return messages, nil
+}
+
+func (m *Messages) Render(data MessageData) (mergeCommit, squashCommit string, err error) {
+	if mergeCommit, err = render(m.mergeCommit, data); err != nil {
+		return "", "", fmt.Errorf("failed to render merge commit message: %w", err)
+	}
+
+	if squashCommit, err = render(m.squashCommit, data); err != nil {
+		return "", "", fmt.Errorf("failed to render squash commit message: %w", err)
+	}
+
+	return mergeCommit, squashCommit, nil
+}
+
+func render(tmpl *template.Template, data MessageData) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,258 @@
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+// dependencyTopicPrefix marks project topics that name a project whose MRs
+// must be merged first, as in "after:group/lib-core".
+const dependencyTopicPrefix = "after:"
+
+// Dependencies maps a project to the projects it depends on. Projects are
+// named by path with namespace or, when that is unambiguous, by their path.
+type Dependencies map[string][]string
+
+type orderFile struct {
+	Dependencies Dependencies `json:"dependencies"`
+}
+
+func LoadDependencies(path string) (Dependencies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order file: %w", err)
+	}
+
+	var file orderFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid order file %s: %w", path, err)
+	}
+
+	return file.Dependencies, nil
+}
+
+// OrderProjects sorts projects so every project comes after the projects it
+// depends on, according to deps and the projects' "after:" topics. Otherwise
+// the given order is kept. Dependencies on projects that are not in the list
+// are ignored. The returned dependencies name projects by path with
+// namespace.
+func OrderProjects(projects []gitlab.Project, deps Dependencies) ([]gitlab.Project, Dependencies, error) {
+	resolve := projectResolver(projects)
+
+	resolved := make(Dependencies)
+	add := func(project, upstream string) error {
+		dependent, ok := resolve(project)
+		if !ok {
+			return nil
+		}
+		target, ok := resolve(upstream)
+		if !ok {
+			return nil
+		}
+		if target == dependent {
+			return fmt.Errorf("project %s depends on itself", dependent)
+		}
+		for _, existing := range resolved[dependent] {
+			if existing == target {
+				return nil
+			}
+		}
+		resolved[dependent] = append(resolved[dependent], target)
+		return nil
+	}
+
+	for project, upstreams := range deps {
+		for _, upstream := range upstreams {
+			if err := add(project, upstream); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, project := range projects {
+		for _, topic := range project.Topics {
+			if upstream, ok := strings.CutPrefix(topic, dependencyTopicPrefix); ok {
+				if err := add(project.PathWithNamespace, upstream); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	// Repeatedly take the first project whose dependencies are all placed,
+	// which keeps the given order wherever the dependencies allow it.
+	ordered := make([]gitlab.Project, 0, len(projects))
+	placed := make(map[string]bool)
+	remaining := projects
+	for len(remaining) > 0 {
+		next := -1
+		for i, project := range remaining {
+			ready := true
+			for _, upstream := range resolved[project.PathWithNamespace] {
+				if !placed[upstream] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			var names []string
+			for _, project := range remaining {
+				names = append(names, project.PathWithNamespace)
+			}
+			return nil, nil, fmt.Errorf("dependency cycle between %s", strings.Join(names, ", "))
+		}
+
+		ordered = append(ordered, remaining[next])
+		placed[remaining[next].PathWithNamespace] = true
+		remaining = append(remaining[:next:next], remaining[next+1:]...)
+	}
+
+	return ordered, resolved, nil
+}
+
+// projectResolver looks projects up by path with namespace, or by path when
+// only one project has it.
+func projectResolver(projects []gitlab.Project) func(name string) (string, bool) {
+	byPath := make(map[string]string)
+	byName := make(map[string][]string)
+	for _, project := range projects {
+		byPath[project.PathWithNamespace] = project.PathWithNamespace
+		name := path.Base(project.PathWithNamespace)
+		byName[name] = append(byName[name], project.PathWithNamespace)
+	}
+
+	return func(name string) (string, bool) {
+		name = strings.Trim(strings.TrimSpace(name), "/")
+		if full, ok := byPath[name]; ok {
+			return full, true
+		}
+		if matches := byName[name]; len(matches) == 1 {
+			return matches[0], true
+		}
+		return "", false
+	}
+}
+
+// dependents reports whether any project depends on project.
+func (d Dependencies) dependents(project string) bool {
+	for _, upstreams := range d {
+		for _, upstream := range upstreams {
+			if upstream == project {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// blockedBy returns why the MRs of project must not be merged because of a
+// project it depends on, or an empty string.
+func (s *Service) blockedBy(project gitlab.Project, failures map[string]string) string {
+	for _, upstream := range s.config.Dependencies[project.PathWithNamespace] {
+		if reason := failures[upstream]; reason != "" {
+			return fmt.Sprintf("waits for %s: %s", upstream, reason)
+		}
+	}
+	return ""
+}
+
+func blockedResult(project gitlab.Project, mr gitlab.MergeRequest, reason string) Result {
+	return Result{
+		Project:         project.PathWithNamespace,
+		MergeRequestIID: mr.IID,
+		Title:           mr.Title,
+		SourceBranch:    mr.SourceBranch,
+		TargetBranch:    mr.TargetBranch,
+		MergeRequestURL: mr.WebURL,
+		Status:          StatusBlocked,
+		Reason:          reason,
+	}
+}
+
+// settleUpstream waits until the merges of a project others depend on are
+// done, tracking its MRs on merge trains first, and returns why its
+// dependents must not be merged (empty if they may) and the train entries of
+// other projects.
+func (s *Service) settleUpstream(ctx context.Context, project gitlab.Project, results []Result, queued []trainEntry) (string, []trainEntry) {
+	var own, rest []trainEntry
+	for _, entry := range queued {
+		if entry.projectID == project.ID {
+			own = append(own, entry)
+		} else {
+			rest = append(rest, entry)
+		}
+	}
+	s.trackTrains(ctx, results, own)
+
+	var merged []Result
+	for _, result := range results {
+		if result.Project != project.PathWithNamespace {
+			continue
+		}
+		if result.Status != StatusMerged {
+			return fmt.Sprintf("!%d was not merged (%s)", result.MergeRequestIID, describe(result)), rest
+		}
+		merged = append(merged, result)
+	}
+
+	for _, result := range merged {
+		if reason := s.mergePipelineFailure(ctx, project.ID, result); reason != "" {
+			return reason, rest
+		}
+	}
+
+	return "", rest
+}
+
+// mergePipelineFailure waits for the pipeline of a merged MR's commit on the
+// target branch, so dependents are only merged once, for example, a library
+// release job has run. Projects without CI never get a pipeline.
+func (s *Service) mergePipelineFailure(ctx context.Context, projectID int, result Result) string {
+	if result.MergeCommitSHA == "" {
+		return ""
+	}
+
+	pipeline, err := s.client.WaitForCommitPipeline(ctx, projectID, result.TargetBranch, result.MergeCommitSHA, 0, func(pipeline *gitlab.Pipeline) bool {
+		return pipeline != nil
+	})
+	if err == nil && pipeline != nil && pipeline.IsActive() {
+		pipeline, err = s.client.WaitForCommitPipeline(ctx, projectID, result.TargetBranch, result.MergeCommitSHA, s.config.PipelineTimeout, func(pipeline *gitlab.Pipeline) bool {
+			return pipeline != nil && !pipeline.IsActive()
+		})
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Sprintf("failed to check the pipeline of the !%d merge: %v", result.MergeRequestIID, err)
+	case pipeline == nil:
+		return ""
+	case pipeline.IsActive():
+		return fmt.Sprintf("pipeline of the !%d merge did not finish in time", result.MergeRequestIID)
+	case pipeline.Status != "success":
+		return fmt.Sprintf("pipeline of the !%d merge is %s", result.MergeRequestIID, pipeline.Status)
+	}
+
+	return ""
+}
+
+func describe(result Result) string {
+	switch {
+	case result.Reason != "":
+		return fmt.Sprintf("%s: %s", result.Status, result.Reason)
+	case result.ErrorMessage != "":
+		return fmt.Sprintf("%s: %s", result.Status, result.ErrorMessage)
+	default:
+		return string(result.Status)
+	}
+}
@@ -0,0 +1,188 @@
+package merge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func TestOrderProjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		topics   map[int][]string
+		deps     Dependencies
+		expected []string
+		err      string
+	}{
+		{
+			name:     "keeps the order without dependencies",
+			expected: []string{"group/repo-a", "group/repo-b", "group/repo-c"},
+		},
+		{
+			name:     "order file by full path and by path",
+			deps:     Dependencies{"group/repo-a": {"repo-c"}, "repo-b": {"group/repo-a"}},
+			expected: []string{"group/repo-c", "group/repo-a", "group/repo-b"},
+		},
+		{
+			name:     "after topics",
+			topics:   map[int][]string{1: {"backend", "after:repo-b"}},
+			expected: []string{"group/repo-b", "group/repo-a", "group/repo-c"},
+		},
+		{
+			name:     "unknown projects are ignored",
+			deps:     Dependencies{"group/repo-a": {"other/lib"}, "other/app": {"group/repo-a"}},
+			expected: []string{"group/repo-a", "group/repo-b", "group/repo-c"},
+		},
+		{
+			name:   "cycle",
+			topics: map[int][]string{1: {"after:repo-b"}, 2: {"after:repo-a"}},
+			err:    "dependency cycle between group/repo-a, group/repo-b",
+		},
+		{
+			name: "self dependency",
+			deps: Dependencies{"repo-c": {"group/repo-c"}},
+			err:  "project group/repo-c depends on itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := projects(1, 2, 3)
+			for i := range list {
+				list[i].Topics = tt.topics[list[i].ID]
+			}
+
+			ordered, _, err := OrderProjects(list, tt.deps)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, project := range ordered {
+				names = append(names, project.PathWithNamespace)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+			if list[0].PathWithNamespace != "group/repo-a" {
+				t.Errorf("the given projects were reordered in place")
+			}
+		})
+	}
+}
+
+func TestLoadDependencies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order.json")
+	if err := os.WriteFile(path, []byte(`{"dependencies": {"group/app": ["group/lib"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := LoadDependencies(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(deps, Dependencies{"group/app": {"group/lib"}}) {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+}
+
+func TestRun_Dependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(client *mockGitLabClient)
+		statuses   []Status
+		lastReason string
+	}{
+		{
+			name:     "upstream merged and its pipeline succeeded",
+			setup:    func(client *mockGitLabClient) { client.commitPipelines["merge-1"] = "success" },
+			statuses: []Status{StatusMerged, StatusMerged, StatusMerged},
+		},
+		{
+			name:     "upstream without CI",
+			setup:    func(client *mockGitLabClient) {},
+			statuses: []Status{StatusMerged, StatusMerged, StatusMerged},
+		},
+		{
+			name:       "upstream pipeline failed",
+			setup:      func(client *mockGitLabClient) { client.commitPipelines["merge-1"] = "failed" },
+			statuses:   []Status{StatusMerged, StatusBlocked, StatusBlocked},
+			lastReason: "waits for group/repo-b: waits for group/repo-a: pipeline of the !1 merge is failed",
+		},
+		{
+			name: "upstream merge failed",
+			setup: func(client *mockGitLabClient) {
+				client.acceptErrors[1] = &gitlab.APIError{StatusCode: 405}
+			},
+			statuses:   []Status{StatusError, StatusBlocked, StatusBlocked},
+			lastReason: "waits for group/repo-b: waits for group/repo-a: !1 was not merged (ERROR: GitLab reports the MR is not mergeable (API request failed with status 405))",
+		},
+		{
+			name: "middle project failed",
+			setup: func(client *mockGitLabClient) {
+				client.pipelines[2] = "running"
+			},
+			statuses:   []Status{StatusMerged, StatusSkipped, StatusBlocked},
+			lastReason: "waits for group/repo-b: !2 was not merged (SKIPPED: head pipeline is running)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}}
+			client.mergeRequests[2] = []gitlab.MergeRequest{{IID: 2}}
+			client.mergeRequests[3] = []gitlab.MergeRequest{{IID: 3}}
+			for iid := 1; iid <= 3; iid++ {
+				client.pipelines[iid] = "success"
+			}
+			tt.setup(client)
+
+			// Given in reverse, the order puts the library first.
+			ordered, deps, err := OrderProjects(projects(3, 2, 1), Dependencies{
+				"repo-b": {"repo-a"},
+				"repo-c": {"repo-b"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := NewService(client, Config{
+				TargetBranch: "main",
+				Projects:     ordered,
+				Dependencies: deps,
+				Policy:       Policy{RequirePipelineSuccess: true},
+			})
+
+			results, summary := service.Run(context.Background())
+
+			var statuses []Status
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.statuses) {
+				t.Fatalf("expected %v, got %v", tt.statuses, statuses)
+			}
+			if last := results[2]; last.MergeRequestIID != 3 || last.Reason != tt.lastReason {
+				t.Errorf("unexpected last result: !%d %q", last.MergeRequestIID, last.Reason)
+			}
+			if tt.lastReason != "" && slices.Contains(client.accepted, 3) {
+				t.Errorf("blocked !3 was merged")
+			}
+			if summary.Total != 3 {
+				t.Errorf("unexpected summary: %+v", summary)
+			}
+		})
+	}
+}
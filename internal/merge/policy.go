@@ -0,0 +1,148 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+// Policy decides which merge requests may be merged. Drafts and MRs that
+// GitLab reports as not mergeable are never merged; the other checks are
+// opt-in.
+type Policy struct {
+	RequirePipelineSuccess bool
+	RequireApprovals       bool
+	// SourcePattern and TargetPattern, if set, must match the MR's source
+	// and target branch.
+	SourcePattern *regexp.Regexp
+	TargetPattern *regexp.Regexp
+}
+
+// check returns the status and reason for not merging mr, or an empty status
+// when it may be merged, along with the details gathered on the way. The
+// merge request is re-fetched because the list endpoint does not include the
+// head pipeline, and GitLab may still be computing its mergeability.
+func (s *Service) check(ctx context.Context, project gitlab.Project, mr gitlab.MergeRequest) (*Candidate, Status, string, error) {
+	policy := s.config.Policy
+	candidate := &Candidate{Project: project, MergeRequest: mr}
+
+	if mr.IsDraft() {
+		return candidate, StatusSkipped, "merge request is a draft", nil
+	}
+
+	if policy.SourcePattern != nil && !policy.SourcePattern.MatchString(mr.SourceBranch) {
+		return candidate, StatusSkipped, fmt.Sprintf("source branch %s does not match %s", mr.SourceBranch, policy.SourcePattern), nil
+	}
+
+	if policy.TargetPattern != nil && !policy.TargetPattern.MatchString(mr.TargetBranch) {
+		return candidate, StatusSkipped, fmt.Sprintf("target branch %s does not match %s", mr.TargetBranch, policy.TargetPattern), nil
+	}
+
+	details, err := s.client.WaitForMergeStatus(ctx, project.ID, mr.IID)
+	if err != nil {
+		return candidate, "", "", err
+	}
+	candidate.MergeRequest = *details
+
+	if details.IsConflicting() {
+		return candidate, StatusConflict, "merge request has conflicts", nil
+	}
+
+	if !details.MergeStatusChecked() {
+		return candidate, StatusSkipped, "GitLab is still checking mergeability", nil
+	}
+
+	candidate.NeedsRebase = s.config.Rebase && details.IsBehind()
+
+	if pipeline := details.HeadPipeline; pipeline != nil && pipeline.Status != "success" {
+		if candidate.Jobs, err = s.client.ListPipelineJobs(ctx, project.ID, pipeline.ID); err != nil {
+			return candidate, "", "", err
+		}
+	}
+
+	if policy.RequirePipelineSuccess {
+		if reason := s.pipelineReason(candidate); reason != "" {
+			return candidate, StatusSkipped, reason, nil
+		}
+	}
+
+	if policy.RequireApprovals {
+		approvals, err := s.client.GetMergeRequestApprovals(ctx, project.ID, mr.IID)
+		if err != nil {
+			return candidate, "", "", err
+		}
+		if approvals.ApprovalsLeft > 0 {
+			return candidate, StatusSkipped, s.approvalsReason(ctx, project.ID, mr.IID, approvals.ApprovalsLeft), nil
+		}
+	}
+
+	// A needed rebase is the one blocker this command can lift itself.
+	if blocker := details.Blocker(); blocker != "" && !(candidate.NeedsRebase && details.DetailedMergeStatus == "need_rebase") {
+		return candidate, StatusNotMergeable, blocker, nil
+	}
+
+	return candidate, "", "", nil
+}
+
+// pipelineReason explains why the head pipeline blocks the merge. A running
+// pipeline is acceptable when the MR is set to merge once it succeeds.
+func (s *Service) pipelineReason(candidate *Candidate) string {
+	pipeline := candidate.MergeRequest.HeadPipeline
+
+	switch {
+	case pipeline == nil:
+		return "no pipeline for the head commit"
+	case pipeline.Status == "success":
+		return ""
+	case pipeline.IsActive() && s.config.MergeWhenPipelineSucceeds:
+		return ""
+	}
+
+	reason := fmt.Sprintf("head pipeline is %s", pipeline.Status)
+	if failed := FailedJobs(candidate.Jobs); len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, job := range failed {
+			names[i] = job.Name
+		}
+		reason += fmt.Sprintf(" (failed jobs: %s)", strings.Join(names, ", "))
+	}
+
+	return reason
+}
+
+// approvalsReason names the unsatisfied approval rules when GitLab exposes
+// them. Rules are a Premium feature, so failing to read them is not an error.
+func (s *Service) approvalsReason(ctx context.Context, projectID, mrIID, left int) string {
+	reason := fmt.Sprintf("%d more approval(s) required", left)
+
+	state, err := s.client.GetMergeRequestApprovalState(ctx, projectID, mrIID)
+	if err != nil {
+		return reason
+	}
+
+	var pending []string
+	for _, rule := range state.Rules {
+		if !rule.Approved {
+			pending = append(pending, rule.Name)
+		}
+	}
+	if len(pending) > 0 {
+		reason += fmt.Sprintf(" (rules: %s)", strings.Join(pending, ", "))
+	}
+
+	return reason
+}
+
+// FailedJobs returns the jobs that failed without being allowed to.
+func FailedJobs(jobs []gitlab.Job) []gitlab.Job {
+	var failed []gitlab.Job
+	for _, job := range jobs {
+		if job.Status == "failed" && !job.AllowFailure {
+			failed = append(failed, job)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,197 @@
+package merge
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+func TestRun_AutoMergeAppliesPolicy(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{
+		{IID: 1, SourceBranch: "release/1.0"},
+		{IID: 2, SourceBranch: "release/1.1", Draft: true},
+		{IID: 3, SourceBranch: "feature/x"},
+		{IID: 4, SourceBranch: "release/1.2", HasConflicts: true},
+		{IID: 5, SourceBranch: "release/1.3"},
+		{IID: 6, SourceBranch: "release/1.4"},
+		{IID: 7, SourceBranch: "release/1.5"},
+		{IID: 8, SourceBranch: "release/1.6"},
+		{IID: 9, SourceBranch: "release/1.7"},
+		{IID: 10, SourceBranch: "release/1.8"},
+	}
+	client.pipelines[1] = "success"
+	client.pipelines[4] = "success"
+	client.pipelines[5] = "failed"
+	client.pipelines[7] = "success"
+	client.approvalsLeft[7] = 2
+	client.pipelines[8] = "success"
+	client.approvalsLeft[8] = 1
+	client.approvalRules[8] = []gitlab.ApprovalRule{
+		{Name: "Security", Approved: false},
+		{Name: "All Members", Approved: true},
+	}
+	client.pipelines[9] = "success"
+	client.mergeStatuses[9] = "discussions_not_resolved"
+	client.mergeStatuses[10] = "checking"
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1),
+		Policy: Policy{
+			RequirePipelineSuccess: true,
+			RequireApprovals:       true,
+			SourcePattern:          regexp.MustCompile(`^release/`),
+		},
+	})
+
+	results, summary := service.Run(context.Background())
+
+	expected := []struct {
+		status Status
+		reason string
+	}{
+		{StatusMerged, ""},
+		{StatusSkipped, "merge request is a draft"},
+		{StatusSkipped, "source branch feature/x does not match ^release/"},
+		{StatusConflict, "merge request has conflicts"},
+		{StatusSkipped, "head pipeline is failed"},
+		{StatusSkipped, "no pipeline for the head commit"},
+		{StatusSkipped, "2 more approval(s) required"},
+		{StatusSkipped, "1 more approval(s) required (rules: Security)"},
+		{StatusNotMergeable, "unresolved discussions"},
+		{StatusSkipped, "GitLab is still checking mergeability"},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		if results[i].Status != want.status || results[i].Reason != want.reason {
+			t.Errorf("MR !%d: expected %s (%q), got %s (%q)", results[i].MergeRequestIID, want.status, want.reason, results[i].Status, results[i].Reason)
+		}
+	}
+	if len(client.accepted) != 1 || summary.Merged != 1 || summary.Skipped != 7 || summary.Conflicts != 1 || summary.NotMergeable != 1 {
+		t.Errorf("unexpected merges %v / summary %+v", client.accepted, summary)
+	}
+}
+
+func TestRun_PipelineGating(t *testing.T) {
+	tests := []struct {
+		name      string
+		pipeline  string
+		jobs      []gitlab.Job
+		autoMerge bool
+		status    Status
+		reason    string
+	}{
+		{
+			name:     "success merges",
+			pipeline: "success",
+			status:   StatusMerged,
+		},
+		{
+			name:     "running is skipped",
+			pipeline: "running",
+			status:   StatusSkipped,
+			reason:   "head pipeline is running",
+		},
+		{
+			name:     "failed lists failed jobs",
+			pipeline: "failed",
+			jobs: []gitlab.Job{
+				{Name: "lint", Status: "failed", AllowFailure: true},
+				{Name: "test", Status: "failed"},
+				{Name: "build", Status: "success"},
+			},
+			status: StatusSkipped,
+			reason: "head pipeline is failed (failed jobs: test)",
+		},
+		{
+			name:      "running is scheduled with auto-merge",
+			pipeline:  "running",
+			autoMerge: true,
+			status:    StatusScheduled,
+			reason:    "merges when the pipeline succeeds",
+		},
+		{
+			name:      "failed is still skipped with auto-merge",
+			pipeline:  "failed",
+			autoMerge: true,
+			status:    StatusSkipped,
+			reason:    "head pipeline is failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}}
+			client.pipelines[1] = tt.pipeline
+			client.jobs[101] = tt.jobs
+
+			var prompted []Candidate
+			service := NewService(client, Config{
+				TargetBranch:              "main",
+				Projects:                  projects(1),
+				Policy:                    Policy{RequirePipelineSuccess: true},
+				MergeWhenPipelineSucceeds: tt.autoMerge,
+				Confirm: func(ctx context.Context, candidate Candidate) (bool, error) {
+					prompted = append(prompted, candidate)
+					return true, nil
+				},
+			})
+
+			results, _ := service.Run(context.Background())
+			if results[0].Status != tt.status || results[0].Reason != tt.reason {
+				t.Errorf("expected %s (%q), got %s (%q)", tt.status, tt.reason, results[0].Status, results[0].Reason)
+			}
+			if results[0].PipelineStatus != tt.pipeline {
+				t.Errorf("expected pipeline status %s, got %s", tt.pipeline, results[0].PipelineStatus)
+			}
+
+			merged := tt.status == StatusMerged || tt.status == StatusScheduled
+			if (len(prompted) == 1) != merged {
+				t.Errorf("unexpected prompts: %d", len(prompted))
+			}
+			if merged && client.acceptOptions[1].MergeWhenPipelineSucceeds != tt.autoMerge {
+				t.Errorf("unexpected accept options: %+v", client.acceptOptions[1])
+			}
+			if merged && prompted[0].MergeRequest.HeadPipeline == nil {
+				t.Error("expected the prompt to receive the head pipeline")
+			}
+		})
+	}
+}
+
+func TestRun_TargetPattern(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{
+		{IID: 1, SourceBranch: "hotfix/a", TargetBranch: "release/1.2"},
+		{IID: 2, SourceBranch: "feature/x", TargetBranch: "develop"},
+	}
+	client.pipelines[1] = "success"
+	client.pipelines[2] = "success"
+
+	service := NewService(client, Config{
+		Projects: projects(1),
+		Policy: Policy{
+			RequirePipelineSuccess: true,
+			TargetPattern:          regexp.MustCompile(`^release/`),
+		},
+	})
+
+	results, _ := service.Run(context.Background())
+
+	if !client.listedAll {
+		t.Error("expected MRs to be listed without a target filter")
+	}
+	if results[0].Status != StatusMerged || results[0].TargetBranch != "release/1.2" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if results[1].Status != StatusSkipped || results[1].Reason != "target branch develop does not match ^release/" {
+		t.Errorf("unexpected result: %+v", results[1])
+	}
+}
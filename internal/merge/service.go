@@ -0,0 +1,477 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type GitLabClient interface {
+	ListOpenMergeRequestsByTarget(ctx context.Context, projectID int, targetBranch string) ([]gitlab.MergeRequest, error)
+	ListOpenMergeRequests(ctx context.Context, projectID int) ([]gitlab.MergeRequest, error)
+	WaitForMergeStatus(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error)
+	GetMergeRequestApprovals(ctx context.Context, projectID int, mrIID int) (*gitlab.Approvals, error)
+	GetMergeRequestApprovalState(ctx context.Context, projectID int, mrIID int) (*gitlab.ApprovalState, error)
+	ListPipelineJobs(ctx context.Context, projectID int, pipelineID int) ([]gitlab.Job, error)
+	AcceptMergeRequest(ctx context.Context, projectID int, mrIID int, opts gitlab.AcceptMergeRequestOptions) (*gitlab.MergeRequest, error)
+	RebaseMergeRequest(ctx context.Context, projectID int, mrIID int) error
+	WaitForRebase(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error)
+	WaitForMergeRequest(ctx context.Context, projectID int, mrIID int, timeout time.Duration, done func(*gitlab.MergeRequest) bool) (*gitlab.MergeRequest, error)
+	AddToMergeTrain(ctx context.Context, projectID int, mrIID int, opts gitlab.AddToMergeTrainOptions) ([]gitlab.MergeTrainCar, error)
+	WaitForMergeTrainCar(ctx context.Context, projectID int, mrIID int, joined bool, timeout time.Duration) (*gitlab.MergeTrainCar, bool, error)
+	WaitForCommitPipeline(ctx context.Context, projectID int, ref, sha string, timeout time.Duration, done func(*gitlab.Pipeline) bool) (*gitlab.Pipeline, error)
+}
+
+type Config struct {
+	// TargetBranch, if set, limits the run to MRs into this branch. Without
+	// it every open MR is listed, for Policy.TargetPattern to select from.
+	TargetBranch string
+	// Projects are merged in this order, which must already put every
+	// project after its dependencies (see OrderProjects).
+	Projects []gitlab.Project
+	// Dependencies, by path with namespace, hold back a project's MRs until
+	// every MR of the projects it depends on merged and the pipelines of
+	// their merge commits on the target succeeded.
+	Dependencies Dependencies
+	Policy       Policy
+	// MergeWhenPipelineSucceeds uses GitLab's auto-merge for MRs whose head
+	// pipeline is still running instead of merging them right away.
+	MergeWhenPipelineSucceeds bool
+	Squash                    bool
+	RemoveSourceBranch        bool
+	// SHAGuard sends the head SHA the MR had when it was checked (and shown
+	// for confirmation), so GitLab refuses the merge if anyone pushed since.
+	SHAGuard bool
+	// Messages, if set, renders custom merge and squash commit messages.
+	Messages *Messages
+	// Rebase rebases MRs whose source branch is behind the target (after
+	// confirmation) and waits for the new pipeline before merging them.
+	Rebase bool
+	// PipelineTimeout bounds the wait for the pipeline of a rebased MR.
+	PipelineTimeout time.Duration
+	// MergeTimeout bounds the wait for GitLab to finish a merge it accepted.
+	MergeTimeout time.Duration
+	// Train adds MRs to their target's merge train instead of merging them,
+	// then tracks every one of them until it merged or was dropped.
+	Train bool
+	// TrainTimeout bounds the wait for each MR on, or set to join, a merge
+	// train.
+	TrainTimeout time.Duration
+	// Confirm is asked before every merge that passes the policy. Returning
+	// an error (for example when stdin is closed) stops the run without
+	// merging anything else. Without Confirm the run is non-interactive and
+	// every MR passing the policy is merged.
+	Confirm func(ctx context.Context, candidate Candidate) (bool, error)
+	// Progress, if set, is called with every result as soon as it is known.
+	Progress func(result Result)
+	// Tracked, if set, is called with the final result of every MR that was
+	// added to (or set to join) a merge train, once it left the train.
+	Tracked func(result Result)
+}
+
+// Candidate is a merge request that passed the policy, with the details
+// needed to decide on it.
+type Candidate struct {
+	Project gitlab.Project
+	// MergeRequest is fetched individually, so HeadPipeline is set when the
+	// MR has a pipeline.
+	MergeRequest gitlab.MergeRequest
+	// Jobs of the head pipeline, only fetched when it has not succeeded.
+	Jobs []gitlab.Job
+	// NeedsRebase is set when Config.Rebase is on and the MR is behind its
+	// target; it is rebased after confirmation.
+	NeedsRebase bool
+}
+
+type Status string
+
+const (
+	StatusMerged       Status = "MERGED"
+	StatusScheduled    Status = "SCHEDULED"
+	StatusQueued       Status = "QUEUED"
+	StatusDropped      Status = "DROPPED"
+	StatusSkipped      Status = "SKIPPED"
+	StatusBlocked      Status = "BLOCKED"
+	StatusConflict     Status = "CONFLICT"
+	StatusNotMergeable Status = "NOT_MERGEABLE"
+	StatusUnauthorized Status = "UNAUTHORIZED"
+	StatusError        Status = "ERROR"
+)
+
+type Result struct {
+	Project         string `json:"project"`
+	MergeRequestIID int    `json:"merge_request_iid,omitempty"`
+	Title           string `json:"title,omitempty"`
+	SourceBranch    string `json:"source_branch,omitempty"`
+	TargetBranch    string `json:"target_branch"`
+	MergeRequestURL string `json:"merge_request_url,omitempty"`
+	PipelineStatus  string `json:"pipeline_status,omitempty"`
+	Rebased         bool   `json:"rebased,omitempty"`
+	Status          Status `json:"status"`
+	MergeCommitSHA  string `json:"merge_commit_sha,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	ErrorMessage    string `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Total        int `json:"total"`
+	Merged       int `json:"merged"`
+	Scheduled    int `json:"scheduled"`
+	Queued       int `json:"queued"`
+	Dropped      int `json:"dropped"`
+	Skipped      int `json:"skipped"`
+	Blocked      int `json:"blocked"`
+	Conflicts    int `json:"conflicts"`
+	NotMergeable int `json:"not_mergeable"`
+	Unauthorized int `json:"unauthorized"`
+	Errors       int `json:"errors"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Run walks the projects in order and offers every open MR targeting the
+// branch for merging. It is sequential on purpose: each merge may wait for a
+// confirmation. In train mode the MRs are tracked once all were enqueued.
+func (s *Service) Run(ctx context.Context) ([]Result, Summary) {
+	var results []Result
+	var queued []trainEntry
+	// failures holds why the dependents of a project must not be merged,
+	// for every project that has dependents.
+	failures := make(map[string]string)
+
+	record := func(result Result) {
+		results = append(results, result)
+		if s.config.Progress != nil {
+			s.config.Progress(result)
+		}
+	}
+
+projects:
+	for _, project := range s.config.Projects {
+		if ctx.Err() != nil {
+			break
+		}
+
+		hasDependents := s.config.Dependencies.dependents(project.PathWithNamespace)
+		blocked := s.blockedBy(project, failures)
+		if blocked != "" && hasDependents {
+			failures[project.PathWithNamespace] = blocked
+		}
+
+		mrs, err := s.listMergeRequests(ctx, project.ID)
+		if err != nil {
+			record(failed(Result{
+				Project:      project.PathWithNamespace,
+				TargetBranch: s.config.TargetBranch,
+			}, err, fmt.Sprintf("failed to fetch merge requests: %v", err)))
+			if blocked == "" && hasDependents {
+				failures[project.PathWithNamespace] = "its merge requests could not be fetched"
+			}
+			continue
+		}
+
+		for _, mr := range mrs {
+			// Interactive runs never offered drafts, so they stay out of
+			// the report there.
+			if mr.IsDraft() && s.config.Confirm != nil {
+				continue
+			}
+
+			if blocked != "" {
+				record(blockedResult(project, mr, blocked))
+				continue
+			}
+
+			result, err := s.processMergeRequest(ctx, project, mr)
+			if err != nil {
+				break projects
+			}
+			record(result)
+
+			if result.Status == StatusQueued || (s.config.Train && result.Status == StatusScheduled) {
+				queued = append(queued, trainEntry{index: len(results) - 1, projectID: project.ID, joined: result.Status == StatusQueued})
+			}
+		}
+
+		if blocked == "" && hasDependents {
+			failures[project.PathWithNamespace], queued = s.settleUpstream(ctx, project, results, queued)
+		}
+	}
+
+	s.trackTrains(ctx, results, queued)
+
+	return results, summarize(results)
+}
+
+func (s *Service) listMergeRequests(ctx context.Context, projectID int) ([]gitlab.MergeRequest, error) {
+	if s.config.TargetBranch == "" {
+		return s.client.ListOpenMergeRequests(ctx, projectID)
+	}
+	return s.client.ListOpenMergeRequestsByTarget(ctx, projectID, s.config.TargetBranch)
+}
+
+// processMergeRequest checks, confirms and merges a single MR. An error is
+// only returned when Confirm asked to stop the run.
+func (s *Service) processMergeRequest(ctx context.Context, project gitlab.Project, mr gitlab.MergeRequest) (Result, error) {
+	result := Result{
+		Project:         project.PathWithNamespace,
+		MergeRequestIID: mr.IID,
+		Title:           mr.Title,
+		SourceBranch:    mr.SourceBranch,
+		TargetBranch:    mr.TargetBranch,
+		MergeRequestURL: mr.WebURL,
+	}
+
+	candidate, status, reason, err := s.check(ctx, project, mr)
+	if err != nil {
+		return failed(result, err, fmt.Sprintf("failed to check merge request: %v", err)), nil
+	}
+
+	if pipeline := candidate.MergeRequest.HeadPipeline; pipeline != nil {
+		result.PipelineStatus = pipeline.Status
+	}
+
+	if status != "" {
+		result.Status = status
+		result.Reason = reason
+		return result, nil
+	}
+
+	if s.config.Confirm != nil {
+		ok, err := s.config.Confirm(ctx, *candidate)
+		if err != nil {
+			return result, err
+		}
+
+		if !ok {
+			result.Status = StatusSkipped
+			result.Reason = "declined"
+			return result, nil
+		}
+	}
+
+	if candidate.NeedsRebase {
+		status, reason, err := s.rebase(ctx, candidate)
+		if err != nil {
+			return failed(result, err, fmt.Sprintf("failed to rebase: %v", err)), nil
+		}
+		if status == "" {
+			result.Rebased = true
+			candidate, status, reason, err = s.check(ctx, project, mr)
+			if err != nil {
+				return failed(result, err, fmt.Sprintf("failed to check rebased merge request: %v", err)), nil
+			}
+			if pipeline := candidate.MergeRequest.HeadPipeline; pipeline != nil {
+				result.PipelineStatus = pipeline.Status
+			}
+			if status == "" && candidate.NeedsRebase {
+				status, reason = StatusSkipped, "target branch moved again during the rebase"
+			}
+		}
+		if status != "" {
+			result.Status = status
+			result.Reason = reason
+			return result, nil
+		}
+	}
+
+	opts, err := s.acceptOptions(candidate)
+	if err != nil {
+		return failed(result, err, err.Error()), nil
+	}
+
+	if s.config.Train {
+		return s.addToTrain(ctx, result, project.ID, opts), nil
+	}
+
+	accepted, err := s.client.AcceptMergeRequest(ctx, project.ID, mr.IID, opts)
+	if err != nil {
+		return failed(result, err, failureReason(err)), nil
+	}
+
+	if opts.MergeWhenPipelineSucceeds {
+		result.Status = StatusScheduled
+		result.Reason = "merges when the pipeline succeeds"
+		return result, nil
+	}
+
+	return s.awaitMerge(ctx, result, project.ID, candidate.MergeRequest.MergeError, accepted), nil
+}
+
+// awaitMerge polls an accepted MR until GitLab has merged it. The merge runs
+// in the background, so the accept response may still show the MR open.
+// previousError is the MR's merge_error from before the merge, which GitLab
+// may still report until the new attempt is done.
+func (s *Service) awaitMerge(ctx context.Context, result Result, projectID int, previousError string, mr *gitlab.MergeRequest) Result {
+	finished := func(mr *gitlab.MergeRequest) bool {
+		return mr.State == "merged" || mr.State == "closed" || (mr.MergeError != "" && mr.MergeError != previousError)
+	}
+
+	if !finished(mr) {
+		var err error
+		if mr, err = s.client.WaitForMergeRequest(ctx, projectID, mr.IID, s.config.MergeTimeout, finished); err != nil {
+			return failed(result, err, fmt.Sprintf("merge was accepted but checking its outcome failed: %v", err))
+		}
+	}
+
+	if mr.State == "merged" {
+		result.Status = StatusMerged
+		result.MergeCommitSHA = mr.ResultingSHA()
+		return result
+	}
+
+	result.Status = StatusError
+	switch {
+	case mr.State == "closed":
+		result.ErrorMessage = "merge request was closed instead of merged"
+	case finished(mr):
+		result.ErrorMessage = "merge failed: " + mr.MergeError
+	default:
+		result.ErrorMessage = fmt.Sprintf("merge did not complete in time (state: %s)", mr.State)
+	}
+
+	return result
+}
+
+// rebase rebases the MR onto its target and waits for the pipeline of the
+// rebased commit, so the MR can be checked again. A non-empty status means
+// the MR cannot be merged now.
+func (s *Service) rebase(ctx context.Context, candidate *Candidate) (Status, string, error) {
+	projectID, mrIID := candidate.Project.ID, candidate.MergeRequest.IID
+
+	if err := s.client.RebaseMergeRequest(ctx, projectID, mrIID); err != nil {
+		return "", "", err
+	}
+
+	rebased, err := s.client.WaitForRebase(ctx, projectID, mrIID)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case rebased.MergeError != "":
+		return StatusNotMergeable, "rebase failed: " + rebased.MergeError, nil
+	case rebased.RebaseInProgress:
+		return StatusSkipped, "rebase is still in progress", nil
+	}
+
+	return "", "", s.waitForPipeline(ctx, projectID, mrIID, rebased.SHA)
+}
+
+// waitForPipeline waits for the pipeline of sha to start and, unless GitLab
+// is asked to merge once it succeeds, to finish. Projects without CI never
+// get one; the policy decides about those when the MR is checked again.
+func (s *Service) waitForPipeline(ctx context.Context, projectID, mrIID int, sha string) error {
+	mr, err := s.client.WaitForMergeRequest(ctx, projectID, mrIID, 0, func(mr *gitlab.MergeRequest) bool {
+		return mr.HeadPipeline != nil && mr.HeadPipeline.SHA == sha
+	})
+	if err != nil || mr.HeadPipeline == nil || mr.HeadPipeline.SHA != sha || s.config.MergeWhenPipelineSucceeds {
+		return err
+	}
+
+	_, err = s.client.WaitForMergeRequest(ctx, projectID, mrIID, s.config.PipelineTimeout, func(mr *gitlab.MergeRequest) bool {
+		return mr.HeadPipeline != nil && !mr.HeadPipeline.IsActive()
+	})
+	return err
+}
+
+func (s *Service) acceptOptions(candidate *Candidate) (gitlab.AcceptMergeRequestOptions, error) {
+	mr := candidate.MergeRequest
+	opts := gitlab.AcceptMergeRequestOptions{
+		Squash:                   s.config.Squash,
+		ShouldRemoveSourceBranch: s.config.RemoveSourceBranch,
+	}
+
+	if pipeline := mr.HeadPipeline; s.config.MergeWhenPipelineSucceeds && pipeline != nil && pipeline.IsActive() {
+		opts.MergeWhenPipelineSucceeds = true
+	}
+
+	if s.config.SHAGuard {
+		opts.SHA = mr.SHA
+	}
+
+	if s.config.Messages != nil {
+		mergeCommit, squashCommit, err := s.config.Messages.Render(MessageData{
+			Project:      candidate.Project,
+			MergeRequest: mr,
+		})
+		if err != nil {
+			return opts, err
+		}
+
+		opts.MergeCommitMessage = mergeCommit
+		if s.config.Squash {
+			opts.SquashCommitMessage = squashCommit
+		}
+	}
+
+	return opts, nil
+}
+
+func summarize(results []Result) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusMerged:
+			summary.Merged++
+		case StatusScheduled:
+			summary.Scheduled++
+		case StatusQueued:
+			summary.Queued++
+		case StatusDropped:
+			summary.Dropped++
+		case StatusSkipped:
+			summary.Skipped++
+		case StatusBlocked:
+			summary.Blocked++
+		case StatusConflict:
+			summary.Conflicts++
+		case StatusNotMergeable:
+			summary.NotMergeable++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		}
+	}
+
+	return summary
+}
+
+func failed(result Result, err error, message string) Result {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+	result.ErrorMessage = message
+	return result
+}
+
+func failureReason(err error) string {
+	switch {
+	case gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err):
+		return fmt.Sprintf("token is not allowed to merge this MR (%v)", err)
+	case gitlab.IsConflict(err):
+		return fmt.Sprintf("source branch changed since it was checked or conflicts with the target (%v)", err)
+	case gitlab.StatusCode(err) == http.StatusMethodNotAllowed:
+		return fmt.Sprintf("GitLab reports the MR is not mergeable (%v)", err)
+	case gitlab.StatusCode(err) == http.StatusNotAcceptable:
+		return fmt.Sprintf("branch cannot be merged (%v)", err)
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,600 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+type mockGitLabClient struct {
+	mergeRequests map[int][]gitlab.MergeRequest
+	listErrors    map[int]error
+	acceptErrors  map[int]error
+	pipelines     map[int]string
+	approvalsLeft map[int]int
+	approvalRules map[int][]gitlab.ApprovalRule
+	jobs          map[int][]gitlab.Job
+	mergeStatuses map[int]string
+	behind        map[int]bool
+	rebaseErrors  map[int]string
+	rebased       []int
+	acceptStates  map[int]string
+	mergeOutcomes map[int]gitlab.MergeRequest
+	accepted      []int
+	acceptOptions map[int]gitlab.AcceptMergeRequestOptions
+	trainErrors   map[int]error
+	trainCars     map[int]*gitlab.MergeTrainCar
+	trained       []int
+	// commitPipelines holds the status of the target branch pipeline per
+	// merge commit SHA.
+	commitPipelines map[string]string
+	// listedAll records that MRs were listed without a target filter.
+	listedAll bool
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		mergeRequests:   make(map[int][]gitlab.MergeRequest),
+		listErrors:      make(map[int]error),
+		acceptErrors:    make(map[int]error),
+		pipelines:       make(map[int]string),
+		approvalsLeft:   make(map[int]int),
+		approvalRules:   make(map[int][]gitlab.ApprovalRule),
+		jobs:            make(map[int][]gitlab.Job),
+		mergeStatuses:   make(map[int]string),
+		behind:          make(map[int]bool),
+		rebaseErrors:    make(map[int]string),
+		acceptStates:    make(map[int]string),
+		mergeOutcomes:   make(map[int]gitlab.MergeRequest),
+		acceptOptions:   make(map[int]gitlab.AcceptMergeRequestOptions),
+		trainErrors:     make(map[int]error),
+		trainCars:       make(map[int]*gitlab.MergeTrainCar),
+		commitPipelines: make(map[string]string),
+	}
+}
+
+func (m *mockGitLabClient) ListOpenMergeRequestsByTarget(ctx context.Context, projectID int, targetBranch string) ([]gitlab.MergeRequest, error) {
+	if err := m.listErrors[projectID]; err != nil {
+		return nil, err
+	}
+	return m.mergeRequests[projectID], nil
+}
+
+func (m *mockGitLabClient) ListOpenMergeRequests(ctx context.Context, projectID int) ([]gitlab.MergeRequest, error) {
+	m.listedAll = true
+	return m.ListOpenMergeRequestsByTarget(ctx, projectID, "")
+}
+
+func (m *mockGitLabClient) WaitForMergeStatus(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error) {
+	for _, mr := range m.mergeRequests[projectID] {
+		if mr.IID != mrIID {
+			continue
+		}
+		mr.DetailedMergeStatus = m.mergeStatuses[mrIID]
+		if m.behind[mrIID] {
+			mr.DivergedCommitsCount = 1
+		}
+		if slices.Contains(m.rebased, mrIID) {
+			mr.SHA = "rebased"
+		}
+		if status, ok := m.pipelines[mrIID]; ok {
+			mr.HeadPipeline = &gitlab.Pipeline{ID: 100 + mrIID, SHA: mr.SHA, Status: status}
+		}
+		return &mr, nil
+	}
+	return nil, &gitlab.APIError{StatusCode: http.StatusNotFound}
+}
+
+func (m *mockGitLabClient) RebaseMergeRequest(ctx context.Context, projectID int, mrIID int) error {
+	m.rebased = append(m.rebased, mrIID)
+	if m.rebaseErrors[mrIID] == "" {
+		m.behind[mrIID] = false
+		if m.mergeStatuses[mrIID] == "need_rebase" {
+			m.mergeStatuses[mrIID] = "mergeable"
+		}
+	}
+	return nil
+}
+
+func (m *mockGitLabClient) WaitForRebase(ctx context.Context, projectID int, mrIID int) (*gitlab.MergeRequest, error) {
+	return &gitlab.MergeRequest{IID: mrIID, SHA: "rebased", MergeError: m.rebaseErrors[mrIID]}, nil
+}
+
+func (m *mockGitLabClient) WaitForMergeRequest(ctx context.Context, projectID int, mrIID int, timeout time.Duration, done func(*gitlab.MergeRequest) bool) (*gitlab.MergeRequest, error) {
+	// After the merge was accepted the outcome is returned as is, whether
+	// done or not, the latter standing for a timeout.
+	if outcome, ok := m.mergeOutcomes[mrIID]; ok && (slices.Contains(m.accepted, mrIID) || slices.Contains(m.trained, mrIID)) {
+		return &outcome, nil
+	}
+
+	mr, err := m.WaitForMergeStatus(ctx, projectID, mrIID)
+	if err == nil && !done(mr) {
+		return nil, fmt.Errorf("mock: !%d never reached the awaited state", mrIID)
+	}
+	return mr, err
+}
+
+func (m *mockGitLabClient) GetMergeRequestApprovals(ctx context.Context, projectID int, mrIID int) (*gitlab.Approvals, error) {
+	left := m.approvalsLeft[mrIID]
+	return &gitlab.Approvals{Approved: left == 0, ApprovalsRequired: 1, ApprovalsLeft: left}, nil
+}
+
+func (m *mockGitLabClient) GetMergeRequestApprovalState(ctx context.Context, projectID int, mrIID int) (*gitlab.ApprovalState, error) {
+	rules, ok := m.approvalRules[mrIID]
+	if !ok {
+		return nil, &gitlab.APIError{StatusCode: http.StatusNotFound}
+	}
+	return &gitlab.ApprovalState{Rules: rules}, nil
+}
+
+func (m *mockGitLabClient) ListPipelineJobs(ctx context.Context, projectID int, pipelineID int) ([]gitlab.Job, error) {
+	return m.jobs[pipelineID], nil
+}
+
+func (m *mockGitLabClient) AcceptMergeRequest(ctx context.Context, projectID int, mrIID int, opts gitlab.AcceptMergeRequestOptions) (*gitlab.MergeRequest, error) {
+	if err := m.acceptErrors[mrIID]; err != nil {
+		return nil, err
+	}
+	m.accepted = append(m.accepted, mrIID)
+	m.acceptOptions[mrIID] = opts
+
+	state, ok := m.acceptStates[mrIID]
+	if !ok {
+		state = "merged"
+	}
+	return &gitlab.MergeRequest{IID: mrIID, State: state, MergeCommitSHA: fmt.Sprintf("merge-%d", mrIID)}, nil
+}
+
+func (m *mockGitLabClient) AddToMergeTrain(ctx context.Context, projectID int, mrIID int, opts gitlab.AddToMergeTrainOptions) ([]gitlab.MergeTrainCar, error) {
+	if err := m.trainErrors[mrIID]; err != nil {
+		return nil, err
+	}
+	m.trained = append(m.trained, mrIID)
+	return []gitlab.MergeTrainCar{{Status: "idle"}}, nil
+}
+
+// WaitForMergeTrainCar returns the car set up for the MR, no car standing for
+// one that left the train or, if it had yet to join, never joined.
+func (m *mockGitLabClient) WaitForMergeTrainCar(ctx context.Context, projectID int, mrIID int, joined bool, timeout time.Duration) (*gitlab.MergeTrainCar, bool, error) {
+	car, ok := m.trainCars[mrIID]
+	return car, !ok && joined, nil
+}
+
+func (m *mockGitLabClient) WaitForCommitPipeline(ctx context.Context, projectID int, ref, sha string, timeout time.Duration, done func(*gitlab.Pipeline) bool) (*gitlab.Pipeline, error) {
+	status, ok := m.commitPipelines[sha]
+	if !ok {
+		return nil, nil
+	}
+	return &gitlab.Pipeline{SHA: sha, Ref: ref, Status: status}, nil
+}
+
+func projects(ids ...int) []gitlab.Project {
+	var list []gitlab.Project
+	for _, id := range ids {
+		list = append(list, gitlab.Project{ID: id, PathWithNamespace: "group/repo-" + string(rune('a'+id-1))})
+	}
+	return list
+}
+
+func answers(values map[int]bool) func(context.Context, Candidate) (bool, error) {
+	return func(ctx context.Context, candidate Candidate) (bool, error) {
+		return values[candidate.MergeRequest.IID], nil
+	}
+}
+
+func TestRun_MergesConfirmedAndSkipsDrafts(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{
+		{IID: 1, Title: "Release", TargetBranch: "main"},
+		{IID: 2, Title: "Draft: WIP", Draft: true, TargetBranch: "main"},
+		{IID: 3, Title: "Hotfix", TargetBranch: "main"},
+	}
+
+	var progressed int
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1),
+		Confirm:      answers(map[int]bool{1: true, 3: false}),
+		Progress:     func(Result) { progressed++ },
+	})
+
+	results, summary := service.Run(context.Background())
+
+	if len(results) != 2 || progressed != 2 {
+		t.Fatalf("expected 2 results and progress calls, got %d and %d", len(results), progressed)
+	}
+	if results[0].Status != StatusMerged || results[1].Status != StatusSkipped {
+		t.Errorf("unexpected statuses: %s, %s", results[0].Status, results[1].Status)
+	}
+	if len(client.accepted) != 1 || client.accepted[0] != 1 {
+		t.Errorf("unexpected merges: %v", client.accepted)
+	}
+	if summary != (Summary{Total: 2, Merged: 1, Skipped: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestRun_ClassifiesErrors(t *testing.T) {
+	client := newMockClient()
+	client.listErrors[1] = &gitlab.APIError{StatusCode: http.StatusForbidden}
+	client.mergeRequests[2] = []gitlab.MergeRequest{{IID: 7}, {IID: 8}}
+	client.acceptErrors[7] = &gitlab.APIError{StatusCode: http.StatusMethodNotAllowed}
+	client.acceptErrors[8] = &gitlab.APIError{StatusCode: http.StatusUnauthorized}
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1, 2),
+		Confirm:      answers(map[int]bool{7: true, 8: true}),
+	})
+
+	results, summary := service.Run(context.Background())
+
+	expected := []Status{StatusUnauthorized, StatusError, StatusUnauthorized}
+	for i, status := range expected {
+		if results[i].Status != status {
+			t.Errorf("result %d: expected %s, got %s (%s)", i, status, results[i].Status, results[i].ErrorMessage)
+		}
+	}
+	if summary.Errors != 1 || summary.Unauthorized != 2 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestRun_ConfirmErrorStops(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}}
+	client.mergeRequests[2] = []gitlab.MergeRequest{{IID: 2}}
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1, 2),
+		Confirm: func(ctx context.Context, candidate Candidate) (bool, error) {
+			return false, errors.New("stdin closed")
+		},
+	})
+
+	results, _ := service.Run(context.Background())
+	if len(results) != 0 || len(client.accepted) != 0 {
+		t.Errorf("expected the run to stop, got %d results and %d merges", len(results), len(client.accepted))
+	}
+}
+
+func TestRun_AcceptOptions(t *testing.T) {
+	messages, err := NewMessages(
+		"Merge '{{.MergeRequest.SourceBranch}}' into '{{.MergeRequest.TargetBranch}}'\n\nSee {{.Project.PathWithNamespace}}!{{.MergeRequest.IID}}",
+		"{{.MergeRequest.Title}} (!{{.MergeRequest.IID}})",
+	)
+	if err != nil {
+		t.Fatalf("NewMessages: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected gitlab.AcceptMergeRequestOptions
+	}{
+		{
+			name:     "defaults send nothing",
+			expected: gitlab.AcceptMergeRequestOptions{},
+		},
+		{
+			name:   "squash with messages and sha guard",
+			config: Config{Squash: true, RemoveSourceBranch: true, SHAGuard: true, Messages: messages},
+			expected: gitlab.AcceptMergeRequestOptions{
+				Squash:                   true,
+				ShouldRemoveSourceBranch: true,
+				MergeCommitMessage:       "Merge 'release/1.2' into 'main'\n\nSee group/repo-a!4",
+				SquashCommitMessage:      "Release 1.2 (!4)",
+				SHA:                      "abc123",
+			},
+		},
+		{
+			name:   "squash message needs squash",
+			config: Config{Messages: messages},
+			expected: gitlab.AcceptMergeRequestOptions{
+				MergeCommitMessage: "Merge 'release/1.2' into 'main'\n\nSee group/repo-a!4",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.mergeRequests[1] = []gitlab.MergeRequest{
+				{IID: 4, Title: "Release 1.2", SourceBranch: "release/1.2", TargetBranch: "main", SHA: "abc123"},
+			}
+
+			config := tt.config
+			config.TargetBranch = "main"
+			config.Projects = projects(1)
+			config.Confirm = answers(map[int]bool{4: true})
+
+			results, _ := NewService(client, config).Run(context.Background())
+
+			if results[0].Status != StatusMerged {
+				t.Fatalf("expected MERGED, got %s (%s)", results[0].Status, results[0].ErrorMessage)
+			}
+			if got := client.acceptOptions[4]; got != tt.expected {
+				t.Errorf("unexpected accept options:\n got %+v\nwant %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRun_SHAMismatch(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 5, SHA: "abc123"}}
+	client.acceptErrors[5] = &gitlab.APIError{StatusCode: http.StatusConflict, Message: "SHA does not match HEAD of source branch"}
+
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1),
+		SHAGuard:     true,
+		Confirm:      answers(map[int]bool{5: true}),
+	})
+
+	results, _ := service.Run(context.Background())
+
+	if results[0].Status != StatusError {
+		t.Fatalf("expected ERROR, got %s", results[0].Status)
+	}
+	if !strings.Contains(results[0].ErrorMessage, "source branch changed") {
+		t.Errorf("unexpected error message: %s", results[0].ErrorMessage)
+	}
+}
+
+func TestNewMessages_InvalidTemplate(t *testing.T) {
+	if _, err := NewMessages("{{.MergeRequest.Title", ""); err == nil {
+		t.Error("expected an error for an invalid merge commit template")
+	}
+	if _, err := NewMessages("", "{{end}}"); err == nil {
+		t.Error("expected an error for an invalid squash commit template")
+	}
+}
+
+func TestRun_Rebase(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		rebaseError string
+		rebase      bool
+		expected    Status
+		reason      string
+		rebased     bool
+	}{
+		{
+			name:     "rebases behind MR and merges the rebased head",
+			rebase:   true,
+			expected: StatusMerged,
+			rebased:  true,
+		},
+		{
+			name:     "fast-forward MR needing a rebase",
+			status:   "need_rebase",
+			rebase:   true,
+			expected: StatusMerged,
+			rebased:  true,
+		},
+		{
+			name:     "without --rebase a needed rebase blocks the merge",
+			status:   "need_rebase",
+			expected: StatusNotMergeable,
+			reason:   "source branch must be rebased",
+		},
+		{
+			name:        "failed rebase",
+			rebase:      true,
+			rebaseError: "Rebase failed: conflicts",
+			expected:    StatusNotMergeable,
+			reason:      "rebase failed: Rebase failed: conflicts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 6, SHA: "original"}}
+			client.pipelines[6] = "success"
+			client.behind[6] = true
+			client.mergeStatuses[6] = tt.status
+			client.rebaseErrors[6] = tt.rebaseError
+
+			service := NewService(client, Config{
+				TargetBranch: "main",
+				Projects:     projects(1),
+				Policy:       Policy{RequirePipelineSuccess: true},
+				Rebase:       tt.rebase,
+				SHAGuard:     true,
+			})
+
+			results, _ := service.Run(context.Background())
+
+			result := results[0]
+			if result.Status != tt.expected || result.Reason != tt.reason || result.Rebased != tt.rebased {
+				t.Fatalf("expected %s (%q, rebased=%v), got %s (%q, rebased=%v) %s", tt.expected, tt.reason, tt.rebased, result.Status, result.Reason, result.Rebased, result.ErrorMessage)
+			}
+			if tt.expected == StatusMerged && client.acceptOptions[6].SHA != "rebased" {
+				t.Errorf("expected the rebased head to be merged, got SHA %q", client.acceptOptions[6].SHA)
+			}
+		})
+	}
+}
+
+func TestRun_AwaitsMergeCompletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		acceptState string
+		outcome     *gitlab.MergeRequest
+		status      Status
+		sha         string
+		errMessage  string
+	}{
+		{
+			name:   "merged right away",
+			status: StatusMerged,
+			sha:    "merge-4",
+		},
+		{
+			name:        "merged in the background",
+			acceptState: "locked",
+			outcome:     &gitlab.MergeRequest{IID: 4, State: "merged", SHA: "head", SquashCommitSHA: "squash"},
+			status:      StatusMerged,
+			sha:         "squash",
+		},
+		{
+			name:        "fast-forward merge",
+			acceptState: "opened",
+			outcome:     &gitlab.MergeRequest{IID: 4, State: "merged", SHA: "head"},
+			status:      StatusMerged,
+			sha:         "head",
+		},
+		{
+			name:        "merge failed",
+			acceptState: "opened",
+			outcome:     &gitlab.MergeRequest{IID: 4, State: "opened", MergeError: "Merge failed: hook declined"},
+			status:      StatusError,
+			errMessage:  "merge failed: Merge failed: hook declined",
+		},
+		{
+			name:        "still running after the timeout",
+			acceptState: "locked",
+			outcome:     &gitlab.MergeRequest{IID: 4, State: "locked"},
+			status:      StatusError,
+			errMessage:  "merge did not complete in time (state: locked)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 4}}
+			if tt.acceptState != "" {
+				client.acceptStates[4] = tt.acceptState
+			}
+			if tt.outcome != nil {
+				client.mergeOutcomes[4] = *tt.outcome
+			}
+
+			service := NewService(client, Config{
+				TargetBranch: "main",
+				Projects:     projects(1),
+			})
+
+			results, _ := service.Run(context.Background())
+
+			result := results[0]
+			if result.Status != tt.status || result.MergeCommitSHA != tt.sha || result.ErrorMessage != tt.errMessage {
+				t.Errorf("expected %s (sha %q, error %q), got %s (sha %q, error %q)", tt.status, tt.sha, tt.errMessage, result.Status, result.MergeCommitSHA, result.ErrorMessage)
+			}
+		})
+	}
+}
+
+func TestRun_MergeTrainScheduled(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}, {IID: 2}, {IID: 3}, {IID: 4}}
+	for iid := 1; iid <= 4; iid++ {
+		client.pipelines[iid] = "running"
+	}
+	client.trainCars[1] = &gitlab.MergeTrainCar{Status: "merged"}
+	client.mergeOutcomes[1] = gitlab.MergeRequest{IID: 1, State: "merged", MergeCommitSHA: "train-1"}
+	client.mergeOutcomes[2] = gitlab.MergeRequest{IID: 2, State: "opened", HeadPipeline: &gitlab.Pipeline{Status: "failed"}}
+	client.mergeOutcomes[3] = gitlab.MergeRequest{IID: 3, State: "opened", HeadPipeline: &gitlab.Pipeline{Status: "running"}}
+	client.trainCars[4] = &gitlab.MergeTrainCar{Status: "fresh"}
+
+	var tracked []Status
+	service := NewService(client, Config{
+		TargetBranch:              "main",
+		Projects:                  projects(1),
+		Train:                     true,
+		MergeWhenPipelineSucceeds: true,
+		Tracked:                   func(result Result) { tracked = append(tracked, result.Status) },
+	})
+
+	results, summary := service.Run(context.Background())
+
+	expected := []struct {
+		status Status
+		reason string
+	}{
+		{StatusMerged, ""},
+		{StatusDropped, "did not join the merge train (pipeline failed)"},
+		{StatusScheduled, "has not joined the merge train yet"},
+		{StatusQueued, "still on the merge train (status: fresh)"},
+	}
+	for i, want := range expected {
+		if got := results[i]; got.Status != want.status || got.Reason != want.reason {
+			t.Errorf("result %d: expected %s (%q), got %s (%q)", i, want.status, want.reason, got.Status, got.Reason)
+		}
+	}
+	if fmt.Sprint(tracked) != "[MERGED DROPPED SCHEDULED QUEUED]" {
+		t.Errorf("expected every scheduled MR tracked, got %v", tracked)
+	}
+	if summary != (Summary{Total: 4, Merged: 1, Scheduled: 1, Queued: 1, Dropped: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestRun_MergeTrain(t *testing.T) {
+	client := newMockClient()
+	client.mergeRequests[1] = []gitlab.MergeRequest{{IID: 1}, {IID: 2}, {IID: 3}, {IID: 4}}
+	client.mergeRequests[2] = []gitlab.MergeRequest{{IID: 5}}
+	client.trainCars[1] = &gitlab.MergeTrainCar{Status: "merged"}
+	client.mergeOutcomes[1] = gitlab.MergeRequest{IID: 1, State: "merged", MergeCommitSHA: "train-1"}
+	client.mergeOutcomes[2] = gitlab.MergeRequest{IID: 2, State: "opened"}
+	client.trainCars[3] = &gitlab.MergeTrainCar{Status: "fresh", Pipeline: &gitlab.Pipeline{Status: "running"}}
+	client.trainErrors[4] = &gitlab.APIError{StatusCode: http.StatusConflict}
+	client.mergeOutcomes[5] = gitlab.MergeRequest{IID: 5, State: "merged", MergeCommitSHA: "train-5"}
+
+	var progress, tracked []Status
+	service := NewService(client, Config{
+		TargetBranch: "main",
+		Projects:     projects(1, 2),
+		Train:        true,
+		Progress:     func(result Result) { progress = append(progress, result.Status) },
+		Tracked:      func(result Result) { tracked = append(tracked, result.Status) },
+	})
+
+	results, summary := service.Run(context.Background())
+
+	expected := []struct {
+		status Status
+		sha    string
+		reason string
+	}{
+		{StatusMerged, "train-1", ""},
+		{StatusDropped, "", "dropped from the merge train"},
+		{StatusQueued, "", "still on the merge train (status: fresh)"},
+		{StatusError, "", ""},
+		{StatusMerged, "train-5", ""},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.Status != want.status || got.MergeCommitSHA != want.sha || got.Reason != want.reason {
+			t.Errorf("result %d: expected %s (sha %q, %q), got %s (sha %q, %q)", i, want.status, want.sha, want.reason, got.Status, got.MergeCommitSHA, got.Reason)
+		}
+	}
+	if results[2].PipelineStatus != "running" {
+		t.Errorf("expected the train pipeline status, got %q", results[2].PipelineStatus)
+	}
+	if len(client.accepted) != 0 {
+		t.Errorf("expected no direct merges, got %v", client.accepted)
+	}
+	if fmt.Sprint(progress) != "[QUEUED QUEUED QUEUED ERROR QUEUED]" || fmt.Sprint(tracked) != "[MERGED DROPPED QUEUED MERGED]" {
+		t.Errorf("unexpected progress %v and tracked %v", progress, tracked)
+	}
+	if summary != (Summary{Total: 5, Merged: 2, Queued: 1, Dropped: 1, Errors: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
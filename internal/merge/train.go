@@ -0,0 +1,123 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+// trainEntry points at the result of an MR that was added to a merge train,
+// or is set to join it once its pipeline succeeded (joined is false).
+type trainEntry struct {
+	index     int
+	projectID int
+	joined    bool
+}
+
+func (s *Service) addToTrain(ctx context.Context, result Result, projectID int, opts gitlab.AcceptMergeRequestOptions) Result {
+	_, err := s.client.AddToMergeTrain(ctx, projectID, result.MergeRequestIID, gitlab.AddToMergeTrainOptions{
+		SHA:                  opts.SHA,
+		Squash:               opts.Squash,
+		WhenPipelineSucceeds: opts.MergeWhenPipelineSucceeds,
+	})
+	if err != nil {
+		return failed(result, err, failureReason(err))
+	}
+
+	// Such MRs only join the train once their pipeline succeeded; they are
+	// tracked like the others, but may not be on the train yet.
+	if opts.MergeWhenPipelineSucceeds {
+		result.Status = StatusScheduled
+		result.Reason = "joins the merge train when the pipeline succeeds"
+		return result
+	}
+
+	result.Status = StatusQueued
+	result.Reason = "added to the merge train"
+	return result
+}
+
+// trackTrains waits for every queued or scheduled MR to leave its merge train
+// and updates its result. Trains run concurrently on GitLab, so waiting for them one
+// after another takes about as long as the slowest one.
+func (s *Service) trackTrains(ctx context.Context, results []Result, queued []trainEntry) {
+	for _, entry := range queued {
+		if ctx.Err() != nil {
+			return
+		}
+
+		results[entry.index] = s.trackTrain(ctx, results[entry.index], entry)
+		if s.config.Tracked != nil {
+			s.config.Tracked(results[entry.index])
+		}
+	}
+}
+
+func (s *Service) trackTrain(ctx context.Context, result Result, entry trainEntry) Result {
+	projectID := entry.projectID
+	car, removed, err := s.client.WaitForMergeTrainCar(ctx, projectID, result.MergeRequestIID, entry.joined, s.config.TrainTimeout)
+	if err != nil {
+		return failed(result, err, fmt.Sprintf("failed to track the merge train: %v", err))
+	}
+
+	if car != nil && car.Pipeline != nil {
+		result.PipelineStatus = car.Pipeline.Status
+	}
+
+	if car != nil && !removed && !car.IsFinished() {
+		result.Status = StatusQueued
+		result.Reason = fmt.Sprintf("still on the merge train (status: %s)", car.Status)
+		return result
+	}
+
+	// Depending on the GitLab version merged cars are kept or removed, so the
+	// MR itself tells whether it was merged or dropped. Locked means GitLab
+	// is still writing the merge.
+	mr, err := s.client.WaitForMergeRequest(ctx, projectID, result.MergeRequestIID, s.config.MergeTimeout, func(mr *gitlab.MergeRequest) bool {
+		return mr.State != "locked"
+	})
+	if err != nil {
+		return failed(result, err, fmt.Sprintf("failed to check the merge request after the merge train: %v", err))
+	}
+
+	// Without a car the MR never showed up on the train in time, which it
+	// does not when its pipeline fails.
+	notJoined := car == nil && !removed
+
+	switch {
+	case mr.State == "merged":
+		result.Status = StatusMerged
+		result.Reason = ""
+		result.MergeCommitSHA = mr.ResultingSHA()
+	case notJoined && mr.State == "opened" && mr.HeadPipeline != nil && (mr.HeadPipeline.Status == "failed" || mr.HeadPipeline.Status == "canceled"):
+		result.Status = StatusDropped
+		result.Reason = fmt.Sprintf("did not join the merge train (pipeline %s)", mr.HeadPipeline.Status)
+	case notJoined && mr.State == "opened":
+		result.Reason = "has not joined the merge train yet"
+	case mr.State == "opened" || mr.State == "closed":
+		result.Status = StatusDropped
+		result.Reason = dropReason(car, mr)
+	default:
+		result.Status = StatusError
+		result.Reason = ""
+		result.ErrorMessage = fmt.Sprintf("merge did not complete in time (state: %s)", mr.State)
+	}
+
+	return result
+}
+
+// dropReason explains why an MR left its merge train without being merged,
+// as far as the API tells.
+func dropReason(car *gitlab.MergeTrainCar, mr *gitlab.MergeRequest) string {
+	switch {
+	case mr.State == "closed":
+		return "merge request was closed"
+	case mr.MergeError != "":
+		return "dropped from the merge train: " + mr.MergeError
+	case car != nil && car.Pipeline != nil && car.Pipeline.Status != "success":
+		return fmt.Sprintf("dropped from the merge train (pipeline %s)", car.Pipeline.Status)
+	default:
+		return "dropped from the merge train"
+	}
+}
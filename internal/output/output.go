@@ -0,0 +1,88 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatCSV   Format = "csv"
+)
+
+var Formats = []Format{FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+func ParseFormat(value string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(value, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (expected table, json, yaml or csv)", value)
+}
+
+// Encode writes v as a JSON or YAML document. Field names and order come from
+// the json struct tags in both formats, so the two stay in sync.
+func Encode(w io.Writer, format Format, v any) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case FormatYAML:
+		return EncodeYAML(w, v)
+	default:
+		return fmt.Errorf("format %s cannot encode documents", format)
+	}
+}
+
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+type Colors struct {
+	Enabled bool
+}
+
+// NewColors enables colors only when w is a terminal and neither NO_COLOR
+// (https://no-color.org) nor TERM=dumb ask otherwise.
+func NewColors(w io.Writer) Colors {
+	return Colors{Enabled: colorSupported(w)}
+}
+
+func colorSupported(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Paint wraps text in the ANSI SGR sequence for code (e.g. "1;35").
+func (c Colors) Paint(code, text string) string {
+	if !c.Enabled {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
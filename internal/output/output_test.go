@@ -0,0 +1,150 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+type sample struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count"`
+	Enabled bool              `json:"enabled"`
+	Tags    []string          `json:"tags"`
+	Empty   []string          `json:"empty"`
+	Items   []sampleItem      `json:"items"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Missing *sampleItem       `json:"missing"`
+}
+
+type sampleItem struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func TestEncodeYAML(t *testing.T) {
+	value := sample{
+		Name:    "group/repo",
+		Count:   2,
+		Enabled: true,
+		Tags:    []string{"backend", "yes", "1.0"},
+		Items: []sampleItem{
+			{Title: "feat: add thing", Body: "line one\nline two"},
+			{Title: "Plain title", Body: ""},
+		},
+		Meta: map[string]string{"key": "value"},
+	}
+
+	expected := `name: group/repo
+count: 2
+enabled: true
+tags:
+  - backend
+  - "yes"
+  - "1.0"
+empty: null
+items:
+  - title: "feat: add thing"
+    body: "line one\nline two"
+  - title: Plain title
+    body: ""
+meta:
+  key: value
+missing: null
+`
+
+	var buf bytes.Buffer
+	if err := EncodeYAML(&buf, value); err != nil {
+		t.Fatalf("EncodeYAML: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("unexpected YAML:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestEncodeYAML_EmptyCollections(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeYAML(&buf, map[string]any{"list": []int{}, "map": map[string]int{}}); err != nil {
+		t.Fatalf("EncodeYAML: %v", err)
+	}
+	if expected := "list: []\nmap: {}\n"; buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestNeedsQuoting(t *testing.T) {
+	tests := []struct {
+		value  string
+		quoted bool
+	}{
+		{"plain", false},
+		{"group/repo", false},
+		{"v1.2.3", false},
+		{"feature/2026-10-16", false},
+		{"abc123", false},
+		{"42", true},
+		{"-1.5", true},
+		{"1.0", true},
+		{"12e45678", true},
+		{"0x1F", true},
+		{"0o17", true},
+		{"0b1010", true},
+		{"1_000", true},
+		{"1:30", true},
+		{".5", true},
+		{".inf", true},
+		{"-.Inf", true},
+		{".nan", true},
+		{"2026-10-16", true},
+		{"2026-10-16T08:00:00Z", true},
+		{"2026-10-16 08:00:00", true},
+		{"NULL", true},
+		{"On", true},
+	}
+
+	for _, tt := range tests {
+		if got := needsQuoting(tt.value); got != tt.quoted {
+			t.Errorf("needsQuoting(%q) = %v, expected %v", tt.value, got, tt.quoted)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, value := range []string{"table", "JSON", "yaml", "csv"} {
+		if _, err := ParseFormat(value); err != nil {
+			t.Errorf("ParseFormat(%q): %v", value, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for xml")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []string{"project", "details"}, [][]string{{"group/repo", "a, \"quoted\" value"}})
+	if err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if expected := "project,details\ngroup/repo,\"a, \"\"quoted\"\" value\"\n"; buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestColors(t *testing.T) {
+	if NewColors(&bytes.Buffer{}).Enabled {
+		t.Error("colors should be disabled for non-terminal writers")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if NewColors(os.Stdout).Enabled {
+		t.Error("NO_COLOR should disable colors")
+	}
+
+	if got := (Colors{}).Paint("1;35", "text"); got != "text" {
+		t.Errorf("disabled Paint = %q", got)
+	}
+	if got := (Colors{Enabled: true}).Paint("1;35", "text"); got != "\033[1;35mtext\033[0m" {
+		t.Errorf("enabled Paint = %q", got)
+	}
+}
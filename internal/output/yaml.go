@@ -0,0 +1,176 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type yamlNode struct {
+	scalar string
+	isMap  bool
+	isList bool
+	keys   []string
+	values []*yamlNode
+}
+
+// EncodeYAML writes v as YAML. The value goes through encoding/json first,
+// which keeps the output in the same shape as the JSON format; the JSON is
+// then re-read token by token so struct field order is preserved.
+func EncodeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	node, err := decodeYAMLNode(decoder)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if node.isMap || node.isList {
+		writeYAMLNode(&b, node, 0)
+	} else {
+		b.WriteString(node.scalar + "\n")
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func decodeYAMLNode(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		node := &yamlNode{isMap: t == '{', isList: t == '['}
+		for decoder.More() {
+			if node.isMap {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, keyToken.(string))
+			}
+
+			value, err := decodeYAMLNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yamlNode{scalar: yamlString(t)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{scalar: strconv.FormatBool(t)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON token %v", token)
+	}
+}
+
+func writeYAMLNode(b *strings.Builder, node *yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	for i, value := range node.values {
+		if node.isMap {
+			b.WriteString(pad + yamlString(node.keys[i]) + ":")
+		} else {
+			b.WriteString(pad + "-")
+		}
+
+		switch {
+		case (value.isMap || value.isList) && len(value.values) == 0:
+			if value.isMap {
+				b.WriteString(" {}\n")
+			} else {
+				b.WriteString(" []\n")
+			}
+		case value.isMap && !node.isMap:
+			// List items that are maps start on the dash line.
+			var nested strings.Builder
+			writeYAMLNode(&nested, value, indent+2)
+			b.WriteString(" " + nested.String()[indent+2:])
+		case value.isMap || value.isList:
+			b.WriteString("\n")
+			writeYAMLNode(b, value, indent+2)
+		default:
+			b.WriteString(" " + value.scalar + "\n")
+		}
+	}
+}
+
+// yamlString leaves a string plain when YAML would read it back unchanged
+// and quotes it otherwise. Go's quoting escapes are valid in YAML
+// double-quoted scalars.
+func yamlString(s string) string {
+	if needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// yamlImplicitPattern matches plain scalars that YAML 1.2 (core schema) or
+// YAML 1.1 parsers read as numbers or timestamps rather than strings, such as
+// "0x1F", "1_000", "12e45678", ".inf", "1:30" or "2026-10-16".
+var yamlImplicitPattern = regexp.MustCompile(`^(?:` +
+	`[-+]?(?:\.[0-9]+|[0-9][0-9_]*(?:\.[0-9_.]*)?)(?:[eE][-+]?[0-9]+)?` +
+	`|[-+]?0b[01_]+|0o[0-7]+|[-+]?0x[0-9a-fA-F_]+` +
+	`|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+(?:\.[0-9_]*)?` +
+	`|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+	`|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt \t].*)?` +
+	`)$`)
+
+func needsQuoting(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+
+	if yamlImplicitPattern.MatchString(s) {
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	return false
+}
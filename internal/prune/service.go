@@ -0,0 +1,358 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/workpool"
+)
+
+type GitLabClient interface {
+	ListBranches(ctx context.Context, projectID int) ([]gitlab.Branch, error)
+	ListOpenMergeRequests(ctx context.Context, projectID int) ([]gitlab.MergeRequest, error)
+	CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error)
+	GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error)
+	DeleteBranch(ctx context.Context, projectID int, branch string) error
+}
+
+type Config struct {
+	Projects []gitlab.Project
+	// Merged selects branches that are merged into Target, or into each
+	// project's default branch when Target is empty.
+	Merged bool
+	Target string
+	// StaleAfter, if positive, selects branches without commits for that
+	// long.
+	StaleAfter  time.Duration
+	Verbose     bool
+	Concurrency int
+	// Now is the reference time for StaleAfter; zero means time.Now.
+	Now time.Time
+	// Progress, if set, is called once per project as soon as it finishes.
+	// Calls are serialized, so the callback does not need to be thread-safe.
+	Progress func(done, total int, result ProjectResult)
+}
+
+type ResultStatus string
+
+const (
+	StatusPlanned        ResultStatus = "PLANNED"
+	StatusPruned         ResultStatus = "PRUNED"
+	StatusNothingToPrune ResultStatus = "NOTHING_TO_PRUNE"
+	StatusUnauthorized   ResultStatus = "UNAUTHORIZED"
+	StatusError          ResultStatus = "ERROR"
+	StatusCanceled       ResultStatus = "CANCELED"
+)
+
+// Branch is a branch selected for deletion.
+type Branch struct {
+	Name          string    `json:"name"`
+	SHA           string    `json:"sha"`
+	CommittedDate time.Time `json:"committed_date"`
+	Reason        string    `json:"reason"`
+	Deleted       bool      `json:"deleted"`
+	// Kept says why a planned branch was not deleted after all.
+	Kept         string `json:"kept,omitempty"`
+	ErrorMessage string `json:"error,omitempty"`
+}
+
+type ProjectResult struct {
+	Project      string       `json:"project"`
+	Status       ResultStatus `json:"status"`
+	Branches     []Branch     `json:"branches"`
+	Details      string       `json:"details,omitempty"`
+	ErrorMessage string       `json:"error,omitempty"`
+
+	projectID int
+}
+
+type Summary struct {
+	Total          int `json:"total"`
+	Planned        int `json:"planned"`
+	Pruned         int `json:"pruned"`
+	NothingToPrune int `json:"nothing_to_prune"`
+	Unauthorized   int `json:"unauthorized"`
+	Errors         int `json:"errors"`
+	Canceled       int `json:"canceled"`
+	// Branches counts the selected branches, Deleted those deleted.
+	Branches int `json:"branches"`
+	Deleted  int `json:"deleted"`
+}
+
+type Service struct {
+	client GitLabClient
+	config Config
+}
+
+func NewService(client GitLabClient, config Config) *Service {
+	return &Service{
+		client: client,
+		config: config,
+	}
+}
+
+// Plan selects the branches to delete in every project without deleting
+// anything. Protected and default branches, the target and branches with an
+// open MR, as source or target, are never selected.
+func (s *Service) Plan(ctx context.Context) ([]ProjectResult, Summary) {
+	projects := s.config.Projects
+
+	results := s.run(ctx, len(projects), func(i int) ProjectResult {
+		return s.planProject(ctx, projects[i])
+	}, func(i int) string {
+		return projects[i].PathWithNamespace
+	})
+
+	return results, summarize(results)
+}
+
+// Prune deletes the branches of the planned projects. Results of other
+// projects are returned as they are.
+func (s *Service) Prune(ctx context.Context, plan []ProjectResult) ([]ProjectResult, Summary) {
+	results := s.run(ctx, len(plan), func(i int) ProjectResult {
+		if plan[i].Status != StatusPlanned {
+			return plan[i]
+		}
+		return s.pruneProject(ctx, plan[i])
+	}, func(i int) string {
+		return plan[i].Project
+	})
+
+	return results, summarize(results)
+}
+
+func (s *Service) run(ctx context.Context, n int, process func(i int) ProjectResult, name func(i int) string) []ProjectResult {
+	var progress func(finished int, result ProjectResult)
+	if s.config.Progress != nil {
+		progress = func(finished int, result ProjectResult) {
+			s.config.Progress(finished, n, result)
+		}
+	}
+
+	return workpool.Run(n, s.config.Concurrency, func(i int) ProjectResult {
+		if ctx.Err() != nil {
+			return ProjectResult{
+				Project: name(i),
+				Status:  StatusCanceled,
+				Details: "Not processed: run was canceled",
+			}
+		}
+
+		result := process(i)
+		if result.Status == StatusError && ctx.Err() != nil {
+			result.Status = StatusCanceled
+		}
+		return result
+	}, progress)
+}
+
+func (s *Service) planProject(ctx context.Context, project gitlab.Project) ProjectResult {
+	result := ProjectResult{
+		Project:   project.PathWithNamespace,
+		Branches:  []Branch{},
+		projectID: project.ID,
+	}
+
+	branches, err := s.client.ListBranches(ctx, project.ID)
+	if err != nil {
+		return failed(result, "failed to list branches", err)
+	}
+
+	mrs, err := s.client.ListOpenMergeRequests(ctx, project.ID)
+	if err != nil {
+		return failed(result, "failed to list merge requests", err)
+	}
+
+	// MRs from forks may name a branch of another project; keeping a
+	// branch of the same name here errs on the safe side.
+	inUse := make(map[string]bool)
+	for _, mr := range mrs {
+		inUse[mr.SourceBranch] = true
+		inUse[mr.TargetBranch] = true
+	}
+
+	target := s.config.Target
+	if target == "" {
+		target = project.DefaultBranch
+	}
+
+	now := s.config.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for _, branch := range branches {
+		if branch.Protected || branch.Default || branch.Name == target || inUse[branch.Name] {
+			continue
+		}
+
+		reason, err := s.selectReason(ctx, project, branch, target, now)
+		if err != nil {
+			return failed(result, fmt.Sprintf("failed to compare %s with %s", branch.Name, target), err)
+		}
+		if reason == "" {
+			continue
+		}
+
+		result.Branches = append(result.Branches, Branch{
+			Name:          branch.Name,
+			SHA:           branch.Commit.ID,
+			CommittedDate: branch.Commit.CommittedDate,
+			Reason:        reason,
+		})
+	}
+
+	if len(result.Branches) == 0 {
+		result.Status = StatusNothingToPrune
+		result.Details = "No branches to prune"
+		return result
+	}
+
+	result.Status = StatusPlanned
+	result.Details = fmt.Sprintf("%d branch(es) to prune", len(result.Branches))
+	return result
+}
+
+// selectReason returns why branch should be deleted, or an empty string.
+func (s *Service) selectReason(ctx context.Context, project gitlab.Project, branch gitlab.Branch, target string, now time.Time) (string, error) {
+	if s.config.Merged && target != "" {
+		merged := branch.Merged
+		if target != project.DefaultBranch {
+			// GitLab's merged flag is relative to the default branch, so
+			// other targets are checked for commits missing from them.
+			compare, err := s.client.CompareBranches(ctx, project.ID, branch.Name, target)
+			if err != nil {
+				return "", err
+			}
+			merged = len(compare.Commits) == 0
+		}
+		if merged {
+			return fmt.Sprintf("merged into %s", target), nil
+		}
+	}
+
+	if s.config.StaleAfter > 0 && !branch.Commit.CommittedDate.IsZero() {
+		if age := now.Sub(branch.Commit.CommittedDate); age >= s.config.StaleAfter {
+			return fmt.Sprintf("no commits in %d days", int(age.Hours()/24)), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (s *Service) pruneProject(ctx context.Context, planned ProjectResult) ProjectResult {
+	result := planned
+	result.Branches = make([]Branch, len(planned.Branches))
+	copy(result.Branches, planned.Branches)
+
+	var deleted, kept, failures int
+	var lastErr error
+	for i := range result.Branches {
+		branch := &result.Branches[i]
+
+		if ctx.Err() != nil {
+			branch.Kept = "run was canceled"
+			kept++
+			continue
+		}
+
+		// A branch that moved since the plan has new work on it, so the
+		// reason it was selected may no longer hold.
+		current, err := s.client.GetBranch(ctx, planned.projectID, branch.Name)
+		switch {
+		case gitlab.IsNotFound(err):
+			branch.Deleted = true
+			deleted++
+			continue
+		case err != nil:
+			branch.ErrorMessage = err.Error()
+			failures++
+			lastErr = err
+			continue
+		case current.Commit.ID != branch.SHA:
+			branch.Kept = fmt.Sprintf("moved to %s since the plan", shortSHA(current.Commit.ID))
+			kept++
+			continue
+		}
+
+		if s.config.Verbose {
+			log.Printf("[%s] Deleting %s (%s)...", planned.Project, branch.Name, branch.Reason)
+		}
+
+		if err := s.client.DeleteBranch(ctx, planned.projectID, branch.Name); err != nil && !gitlab.IsNotFound(err) {
+			branch.ErrorMessage = err.Error()
+			failures++
+			lastErr = err
+			continue
+		}
+
+		branch.Deleted = true
+		deleted++
+	}
+
+	result.Details = fmt.Sprintf("Deleted %d of %d branch(es)", deleted, len(result.Branches))
+	if kept > 0 {
+		result.Details += fmt.Sprintf(", kept %d", kept)
+	}
+
+	switch {
+	case failures > 0:
+		result = failed(result, fmt.Sprintf("failed to delete %d branch(es)", failures), lastErr)
+	case ctx.Err() != nil && kept > 0:
+		result.Status = StatusCanceled
+	default:
+		result.Status = StatusPruned
+	}
+
+	return result
+}
+
+func summarize(results []ProjectResult) Summary {
+	summary := Summary{Total: len(results)}
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusPlanned:
+			summary.Planned++
+		case StatusPruned:
+			summary.Pruned++
+		case StatusNothingToPrune:
+			summary.NothingToPrune++
+		case StatusUnauthorized:
+			summary.Unauthorized++
+		case StatusError:
+			summary.Errors++
+		case StatusCanceled:
+			summary.Canceled++
+		}
+
+		summary.Branches += len(result.Branches)
+		for _, branch := range result.Branches {
+			if branch.Deleted {
+				summary.Deleted++
+			}
+		}
+	}
+
+	return summary
+}
+
+func failed(result ProjectResult, context string, err error) ProjectResult {
+	result.Status = StatusError
+	if gitlab.IsUnauthorized(err) || gitlab.IsForbidden(err) {
+		result.Status = StatusUnauthorized
+	}
+
+	result.ErrorMessage = fmt.Sprintf("%s: %v", context, err)
+	return result
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,251 @@
+package prune
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/gitlab"
+)
+
+var now = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+type mockGitLabClient struct {
+	branches     map[int][]gitlab.Branch
+	mrs          map[int][]gitlab.MergeRequest
+	listErrors   map[int]error
+	unmerged     map[string]bool
+	deleteErrors map[string]error
+	// moved gives the current SHA of branches pushed to after the plan.
+	moved map[string]string
+
+	mu      sync.Mutex
+	deleted []string
+}
+
+func newMockClient() *mockGitLabClient {
+	return &mockGitLabClient{
+		branches:     make(map[int][]gitlab.Branch),
+		mrs:          make(map[int][]gitlab.MergeRequest),
+		listErrors:   make(map[int]error),
+		unmerged:     make(map[string]bool),
+		deleteErrors: make(map[string]error),
+		moved:        make(map[string]string),
+	}
+}
+
+func (m *mockGitLabClient) ListBranches(ctx context.Context, projectID int) ([]gitlab.Branch, error) {
+	if err := m.listErrors[projectID]; err != nil {
+		return nil, err
+	}
+	return m.branches[projectID], nil
+}
+
+func (m *mockGitLabClient) ListOpenMergeRequests(ctx context.Context, projectID int) ([]gitlab.MergeRequest, error) {
+	return m.mrs[projectID], nil
+}
+
+func (m *mockGitLabClient) CompareBranches(ctx context.Context, projectID int, sourceBranch, targetBranch string) (*gitlab.Compare, error) {
+	if m.unmerged[sourceBranch] {
+		return &gitlab.Compare{Commits: []gitlab.Commit{{ID: "c1"}}}, nil
+	}
+	return &gitlab.Compare{}, nil
+}
+
+func (m *mockGitLabClient) GetBranch(ctx context.Context, projectID int, branch string) (*gitlab.Branch, error) {
+	if sha, ok := m.moved[branch]; ok {
+		if sha == "" {
+			return nil, &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Branch Not Found"}
+		}
+		return &gitlab.Branch{Name: branch, Commit: gitlab.Commit{ID: sha}}, nil
+	}
+	for _, b := range m.branches[projectID] {
+		if b.Name == branch {
+			return &b, nil
+		}
+	}
+	return nil, &gitlab.APIError{StatusCode: http.StatusNotFound, Message: "404 Branch Not Found"}
+}
+
+func (m *mockGitLabClient) DeleteBranch(ctx context.Context, projectID int, branch string) error {
+	if err := m.deleteErrors[branch]; err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted = append(m.deleted, branch)
+	return nil
+}
+
+func branch(name string, age time.Duration) gitlab.Branch {
+	return gitlab.Branch{Name: name, Commit: gitlab.Commit{ID: name + "-sha", CommittedDate: now.Add(-age)}}
+}
+
+func names(branches []Branch) []string {
+	var out []string
+	for _, b := range branches {
+		out = append(out, b.Name)
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	day := 24 * time.Hour
+	merged := branch("feature/merged", day)
+	merged.Merged = true
+	protected := branch("release/1", 400*day)
+	protected.Protected = true
+	main := branch("main", 400*day)
+	main.Default = true
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+		reasons  []string
+	}{
+		{
+			name:     "merged into the default branch",
+			config:   Config{Merged: true},
+			expected: []string{"feature/merged"},
+			reasons:  []string{"merged into main"},
+		},
+		{
+			name:     "merged into another target",
+			config:   Config{Merged: true, Target: "op-stage"},
+			expected: []string{"feature/merged", "feature/old"},
+			reasons:  []string{"merged into op-stage", "merged into op-stage"},
+		},
+		{
+			name:     "stale",
+			config:   Config{StaleAfter: 90 * day},
+			expected: []string{"feature/old"},
+			reasons:  []string{"no commits in 120 days"},
+		},
+		{
+			name:     "merged or stale",
+			config:   Config{Merged: true, StaleAfter: 90 * day},
+			expected: []string{"feature/merged", "feature/old"},
+			reasons:  []string{"merged into main", "no commits in 120 days"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockClient()
+			client.branches[1] = []gitlab.Branch{
+				main,
+				protected,
+				merged,
+				branch("feature/old", 120*day),
+				branch("feature/active", day),
+				branch("feature/in-review", 200*day),
+				branch("op-stage", day),
+			}
+			client.unmerged["feature/active"] = true
+			client.mrs[1] = []gitlab.MergeRequest{{SourceBranch: "feature/in-review", TargetBranch: "main"}}
+
+			tt.config.Projects = []gitlab.Project{{ID: 1, PathWithNamespace: "group/a", DefaultBranch: "main"}}
+			tt.config.Now = now
+
+			results, summary := NewService(client, tt.config).Plan(context.Background())
+
+			got := names(results[0].Branches)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected branches %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] || results[0].Branches[i].Reason != tt.reasons[i] {
+					t.Errorf("branch %d: expected %s (%s), got %s (%s)", i, tt.expected[i], tt.reasons[i], got[i], results[0].Branches[i].Reason)
+				}
+			}
+			if results[0].Status != StatusPlanned || summary.Planned != 1 || summary.Branches != len(tt.expected) {
+				t.Errorf("unexpected result: %+v, summary %+v", results[0], summary)
+			}
+			if len(client.deleted) != 0 {
+				t.Errorf("planning deleted branches: %v", client.deleted)
+			}
+		})
+	}
+}
+
+func TestPlan_NothingToPruneAndErrors(t *testing.T) {
+	client := newMockClient()
+	client.branches[1] = []gitlab.Branch{branch("feature/active", time.Hour)}
+	client.listErrors[2] = &gitlab.APIError{StatusCode: http.StatusForbidden, Message: "403 Forbidden"}
+
+	config := Config{
+		Projects: []gitlab.Project{
+			{ID: 1, PathWithNamespace: "group/a", DefaultBranch: "main"},
+			{ID: 2, PathWithNamespace: "group/b", DefaultBranch: "main"},
+		},
+		StaleAfter: 24 * time.Hour,
+		Now:        now,
+	}
+
+	results, summary := NewService(client, config).Plan(context.Background())
+
+	if results[0].Status != StatusNothingToPrune || results[1].Status != StatusUnauthorized {
+		t.Errorf("unexpected statuses: %s, %s", results[0].Status, results[1].Status)
+	}
+	if summary.NothingToPrune != 1 || summary.Unauthorized != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	client := newMockClient()
+	client.branches[1] = []gitlab.Branch{branch("a", 0), branch("b", 0), branch("c", 0), branch("d", 0)}
+	client.moved["b"] = "b-new"
+	client.moved["c"] = ""
+	client.deleteErrors["d"] = &gitlab.APIError{StatusCode: http.StatusInternalServerError, Message: "500"}
+
+	plan := []ProjectResult{
+		{
+			Project:   "group/a",
+			Status:    StatusPlanned,
+			projectID: 1,
+			Branches: []Branch{
+				{Name: "a", SHA: "a-sha"},
+				{Name: "b", SHA: "b-sha"},
+				{Name: "c", SHA: "c-sha"},
+			},
+		},
+		{
+			Project:   "group/b",
+			Status:    StatusPlanned,
+			projectID: 1,
+			Branches:  []Branch{{Name: "d", SHA: "d-sha"}},
+		},
+		{Project: "group/c", Status: StatusNothingToPrune, Branches: []Branch{}},
+	}
+
+	results, summary := NewService(client, Config{}).Prune(context.Background(), plan)
+
+	pruned := results[0]
+	if pruned.Status != StatusPruned || pruned.Details != "Deleted 2 of 3 branch(es), kept 1" {
+		t.Errorf("unexpected result: %s %q", pruned.Status, pruned.Details)
+	}
+	if !pruned.Branches[0].Deleted || pruned.Branches[1].Deleted || pruned.Branches[1].Kept != "moved to b-new since the plan" || !pruned.Branches[2].Deleted {
+		t.Errorf("unexpected branches: %+v", pruned.Branches)
+	}
+	if plan[0].Branches[0].Deleted {
+		t.Error("the plan was modified")
+	}
+
+	if results[1].Status != StatusError || results[1].Branches[0].ErrorMessage == "" {
+		t.Errorf("expected delete failure, got %+v", results[1])
+	}
+	if results[2].Status != StatusNothingToPrune {
+		t.Errorf("expected unplanned project to pass through, got %s", results[2].Status)
+	}
+
+	if len(client.deleted) != 1 || client.deleted[0] != "a" {
+		t.Errorf("unexpected deletions: %v", client.deleted)
+	}
+	if summary.Pruned != 1 || summary.Errors != 1 || summary.Branches != 4 || summary.Deleted != 2 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
@@ -0,0 +1,316 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkbranch"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkmr"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkrelease"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/cherrypick"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/merge"
+)
+
+type Outcome int
+
+const (
+	Passed Outcome = iota
+	Failed
+	Skipped
+)
+
+// Case is one project (or merge request) outcome in a JUnit report.
+type Case struct {
+	Name      string
+	ClassName string
+	Outcome   Outcome
+	Message   string
+	Output    string
+}
+
+type Suite struct {
+	Name      string
+	Timestamp time.Time
+	Cases     []Case
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+func WriteJUnit(path string, suites ...Suite) error {
+	data, err := MarshalJUnit(suites...)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	return nil
+}
+
+func MarshalJUnit(suites ...Suite) ([]byte, error) {
+	doc := junitTestSuites{Name: "gitlab-tools"}
+
+	for _, suite := range suites {
+		out := junitTestSuite{Name: suite.Name, Tests: len(suite.Cases)}
+		if !suite.Timestamp.IsZero() {
+			out.Timestamp = suite.Timestamp.UTC().Format("2006-01-02T15:04:05")
+		}
+
+		for _, c := range suite.Cases {
+			testCase := junitTestCase{Name: c.Name, ClassName: c.ClassName}
+
+			switch c.Outcome {
+			case Failed:
+				out.Failures++
+				testCase.Failure = &junitMessage{Message: firstLine(c.Message), Body: c.Output}
+			case Skipped:
+				out.Skipped++
+				testCase.Skipped = &junitMessage{Message: firstLine(c.Message)}
+				testCase.SystemOut = c.Output
+			default:
+				testCase.SystemOut = c.Output
+			}
+
+			out.Cases = append(out.Cases, testCase)
+		}
+
+		doc.Tests += out.Tests
+		doc.Failures += out.Failures
+		doc.Skipped += out.Skipped
+		doc.Suites = append(doc.Suites, out)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// BulkMRSuite maps bulk MR results to test cases: errors fail, skips and
+// canceled projects are skipped, created (or would-be created) MRs pass.
+func BulkMRSuite(name, targetBranch string, startedAt time.Time, results []bulkmr.ProjectResult) Suite {
+	suite := Suite{Name: name, Timestamp: startedAt}
+
+	for _, result := range results {
+		c := Case{
+			Name:      result.Project,
+			ClassName: fmt.Sprintf("%s.%s", name, targetBranch),
+			Message:   fmt.Sprintf("%s: %s", result.Status, result.Details),
+			Output:    joinLines(result.Details, result.ErrorMessage),
+		}
+
+		switch result.Status {
+		case bulkmr.StatusCreated, bulkmr.StatusWouldCreate:
+			c.Outcome = Passed
+		case bulkmr.StatusSkippedExists, bulkmr.StatusSkippedDraft, bulkmr.StatusSkippedBranch,
+			bulkmr.StatusSkippedNoChange, bulkmr.StatusCanceled:
+			c.Outcome = Skipped
+		case bulkmr.StatusConflict, bulkmr.StatusNotMergeable:
+			c.Outcome = Failed
+		default:
+			c.Outcome = Failed
+			c.Message = fmt.Sprintf("%s: %s", result.Status, failureReason(result.ErrorMessage, result.Details))
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite
+}
+
+func BulkBranchSuite(name, branch string, startedAt time.Time, results []bulkbranch.ProjectResult) Suite {
+	suite := Suite{Name: name, Timestamp: startedAt}
+
+	for _, result := range results {
+		c := Case{
+			Name:      result.Project,
+			ClassName: fmt.Sprintf("%s.%s", name, branch),
+			Message:   fmt.Sprintf("%s: %s", result.Status, result.Details),
+			Output:    joinLines(result.Details, result.ErrorMessage),
+		}
+
+		// A branch at another commit than requested needs someone to decide
+		// which one is right, so it fails.
+		switch result.Status {
+		case bulkbranch.StatusCreated:
+			c.Outcome = Passed
+		case bulkbranch.StatusSkippedExists, bulkbranch.StatusSkippedNoRef, bulkbranch.StatusCanceled:
+			c.Outcome = Skipped
+		case bulkbranch.StatusConflict:
+			c.Outcome = Failed
+		default:
+			c.Outcome = Failed
+			c.Message = fmt.Sprintf("%s: %s", result.Status, failureReason(result.ErrorMessage, result.Details))
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite
+}
+
+func BulkReleaseSuite(name, tag string, startedAt time.Time, results []bulkrelease.ProjectResult) Suite {
+	suite := Suite{Name: name, Timestamp: startedAt}
+
+	for _, result := range results {
+		c := Case{
+			Name:      result.Project,
+			ClassName: fmt.Sprintf("%s.%s", name, tag),
+			Message:   fmt.Sprintf("%s: %s", result.Status, result.Details),
+			Output:    joinLines(result.Details, result.ReleaseURL, result.ErrorMessage),
+		}
+
+		switch result.Status {
+		case bulkrelease.StatusCreated, bulkrelease.StatusWouldCreate:
+			c.Outcome = Passed
+		case bulkrelease.StatusSkippedExists, bulkrelease.StatusSkippedNoRef, bulkrelease.StatusCanceled:
+			c.Outcome = Skipped
+		case bulkrelease.StatusConflict:
+			c.Outcome = Failed
+		default:
+			c.Outcome = Failed
+			c.Message = fmt.Sprintf("%s: %s", result.Status, failureReason(result.ErrorMessage, result.Details))
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite
+}
+
+func CherryPickSuite(name, branch string, startedAt time.Time, results []cherrypick.ProjectResult) Suite {
+	suite := Suite{Name: name, Timestamp: startedAt}
+
+	for _, result := range results {
+		c := Case{
+			Name:      result.Project,
+			ClassName: fmt.Sprintf("%s.%s", name, branch),
+			Message:   fmt.Sprintf("%s: %s", result.Status, result.Details),
+			Output:    joinLines(result.Details, result.ErrorMessage),
+		}
+
+		switch result.Status {
+		case cherrypick.StatusPicked, cherrypick.StatusWouldPick:
+			c.Outcome = Passed
+		case cherrypick.StatusSkippedApplied, cherrypick.StatusSkippedNoCommit, cherrypick.StatusSkippedBranch, cherrypick.StatusCanceled:
+			c.Outcome = Skipped
+		case cherrypick.StatusConflict:
+			c.Outcome = Failed
+		default:
+			c.Outcome = Failed
+			c.Message = fmt.Sprintf("%s: %s", result.Status, failureReason(result.ErrorMessage, result.Details))
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite
+}
+
+func MergeSuite(targetBranch string, startedAt time.Time, results []merge.Result) Suite {
+	suite := Suite{Name: "merge", Timestamp: startedAt}
+
+	for _, result := range results {
+		name := result.Project
+		if result.MergeRequestIID != 0 {
+			name = fmt.Sprintf("%s!%d %s", result.Project, result.MergeRequestIID, result.Title)
+		}
+
+		// With a target pattern each MR may go into a different branch.
+		branch := targetBranch
+		if result.TargetBranch != "" {
+			branch = result.TargetBranch
+		}
+
+		c := Case{
+			Name:      name,
+			ClassName: "merge." + branch,
+			Message:   string(result.Status),
+			Output:    joinLines(result.MergeRequestURL, result.MergeCommitSHA, result.Reason, result.ErrorMessage),
+		}
+		if result.Reason != "" {
+			c.Message = fmt.Sprintf("%s: %s", result.Status, result.Reason)
+		}
+
+		// Conflicting, blocked and dropped MRs need someone to act, so they
+		// fail like errors instead of being skipped. MRs still on a merge
+		// train have no outcome yet, and blocked MRs wait for another
+		// project's outcome.
+		switch result.Status {
+		case merge.StatusMerged, merge.StatusScheduled:
+			c.Outcome = Passed
+		case merge.StatusSkipped, merge.StatusQueued, merge.StatusBlocked:
+			c.Outcome = Skipped
+		case merge.StatusConflict, merge.StatusNotMergeable, merge.StatusDropped:
+			c.Outcome = Failed
+		default:
+			c.Outcome = Failed
+			c.Message = fmt.Sprintf("%s: %s", result.Status, failureReason(result.ErrorMessage, result.Reason))
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite
+}
+
+// failureReason is the error of a failed result or, for failures that are no
+// error such as a missing project, its details.
+func failureReason(errorMessage, details string) string {
+	if errorMessage != "" {
+		return errorMessage
+	}
+	return details
+}
+
+func joinLines(lines ...string) string {
+	var kept []string
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSuffix(line, ": ")
+}
@@ -0,0 +1,151 @@
+package report
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkbranch"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkmr"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/bulkrelease"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/cherrypick"
+	"github.com/sajjad-fatehi/gitlab-tools/internal/merge"
+)
+
+func TestMarshalJUnit_BulkMR(t *testing.T) {
+	results := []bulkmr.ProjectResult{
+		{Project: "group/a", Status: bulkmr.StatusCreated, Details: "MR !1: https://gitlab.example.com/group/a/-/merge_requests/1"},
+		{Project: "group/b", Status: bulkmr.StatusSkippedNoChange, Details: "No changes between op-stage and op-rc"},
+		{Project: "group/c", Status: bulkmr.StatusError, ErrorMessage: "failed to compare branches: API request failed with status 500"},
+		{Project: "group/d", Status: bulkmr.StatusNotFound, Details: "Project 'group/d' does not exist"},
+	}
+
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := MarshalJUnit(BulkMRSuite("bulk-mr-topic", "op-rc", startedAt, results))
+	if err != nil {
+		t.Fatalf("MarshalJUnit: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, data)
+	}
+
+	if doc.Tests != 4 || doc.Failures != 2 || doc.Skipped != 1 {
+		t.Errorf("unexpected totals: tests=%d failures=%d skipped=%d", doc.Tests, doc.Failures, doc.Skipped)
+	}
+
+	suite := doc.Suites[0]
+	if suite.Name != "bulk-mr-topic" || suite.Timestamp != "2026-01-02T03:04:05" {
+		t.Errorf("unexpected suite: %s %s", suite.Name, suite.Timestamp)
+	}
+
+	created, skipped, failed := suite.Cases[0], suite.Cases[1], suite.Cases[2]
+	if created.ClassName != "bulk-mr-topic.op-rc" || created.Failure != nil || created.Skipped != nil {
+		t.Errorf("created project should pass: %+v", created)
+	}
+	if skipped.Skipped == nil || skipped.Skipped.Message != "SKIPPED_NO_CHANGE: No changes between op-stage and op-rc" {
+		t.Errorf("unexpected skipped case: %+v", skipped)
+	}
+	if failed.Failure == nil || !strings.Contains(failed.Failure.Body, "status 500") {
+		t.Errorf("unexpected failed case: %+v", failed)
+	}
+	if missing := suite.Cases[3]; missing.Failure == nil || missing.Failure.Message != "NOT_FOUND: Project 'group/d' does not exist" {
+		t.Errorf("expected the details as the message of a missing project: %+v", missing)
+	}
+}
+
+func TestBulkBranchSuite(t *testing.T) {
+	results := []bulkbranch.ProjectResult{
+		{Project: "group/a", Status: bulkbranch.StatusCreated, Details: "Created op-rc-2026.10 from op-stage at aaaaaaaa"},
+		{Project: "group/b", Status: bulkbranch.StatusSkippedExists, Details: "Branch 'op-rc-2026.10' already exists at aaaaaaaa"},
+		{Project: "group/c", Status: bulkbranch.StatusConflict, Details: "Branch 'op-rc-2026.10' already exists at bbbbbbbb, but 'op-stage' is at aaaaaaaa"},
+		{Project: "group/d", Status: bulkbranch.StatusError, ErrorMessage: "failed to create branch: API request failed with status 500"},
+		{Project: "group/e", Status: bulkbranch.StatusNotFound, Details: "Project 'group/e' does not exist"},
+	}
+
+	suite := BulkBranchSuite("bulk-branch-create", "op-rc-2026.10", time.Time{}, results)
+
+	expected := []Outcome{Passed, Skipped, Failed, Failed, Failed}
+	for i, outcome := range expected {
+		if suite.Cases[i].Outcome != outcome {
+			t.Errorf("case %d: expected outcome %d, got %d", i, outcome, suite.Cases[i].Outcome)
+		}
+	}
+	if suite.Cases[0].ClassName != "bulk-branch-create.op-rc-2026.10" || suite.Cases[3].Message != "ERROR: failed to create branch: API request failed with status 500" {
+		t.Errorf("unexpected cases: %+v", suite.Cases)
+	}
+	if suite.Cases[4].Message != "NOT_FOUND: Project 'group/e' does not exist" {
+		t.Errorf("unexpected message: %q", suite.Cases[4].Message)
+	}
+}
+
+func TestBulkReleaseSuite(t *testing.T) {
+	results := []bulkrelease.ProjectResult{
+		{Project: "group/a", Status: bulkrelease.StatusCreated, Details: "Released v2026.10 at aaaaaaaa (3 commit(s) since v2026.09)"},
+		{Project: "group/b", Status: bulkrelease.StatusSkippedExists, Details: "Tag 'v2026.10' already exists at aaaaaaaa"},
+		{Project: "group/c", Status: bulkrelease.StatusConflict, Details: "Tag 'v2026.10' already exists at bbbbbbbb, but 'op-rc' is at aaaaaaaa"},
+		{Project: "group/d", Status: bulkrelease.StatusUnauthorized, ErrorMessage: "failed to create release: API request failed with status 403"},
+	}
+
+	suite := BulkReleaseSuite("bulk-release", "v2026.10", time.Time{}, results)
+
+	expected := []Outcome{Passed, Skipped, Failed, Failed}
+	for i, outcome := range expected {
+		if suite.Cases[i].Outcome != outcome {
+			t.Errorf("case %d: expected outcome %d, got %d", i, outcome, suite.Cases[i].Outcome)
+		}
+	}
+	if suite.Cases[3].Message != "UNAUTHORIZED: failed to create release: API request failed with status 403" {
+		t.Errorf("unexpected message: %q", suite.Cases[3].Message)
+	}
+}
+
+func TestCherryPickSuite(t *testing.T) {
+	results := []cherrypick.ProjectResult{
+		{Project: "group/a", Status: cherrypick.StatusPicked, Details: "Picked 1 commit(s) onto op-rc"},
+		{Project: "group/b", Status: cherrypick.StatusSkippedApplied, Details: "op-rc already has the change(s)"},
+		{Project: "group/c", Status: cherrypick.StatusConflict, Details: "aaaaaaaa does not apply cleanly onto op-rc"},
+		{Project: "group/d", Status: cherrypick.StatusError, ErrorMessage: "failed to find commits: API request failed with status 500"},
+	}
+
+	suite := CherryPickSuite("bulk-cherry-pick", "op-rc", time.Time{}, results)
+
+	expected := []Outcome{Passed, Skipped, Failed, Failed}
+	for i, outcome := range expected {
+		if suite.Cases[i].Outcome != outcome {
+			t.Errorf("case %d: expected outcome %d, got %d", i, outcome, suite.Cases[i].Outcome)
+		}
+	}
+	if suite.Cases[2].Message != "CONFLICT: aaaaaaaa does not apply cleanly onto op-rc" || suite.Cases[2].ClassName != "bulk-cherry-pick.op-rc" {
+		t.Errorf("unexpected case: %+v", suite.Cases[2])
+	}
+}
+
+func TestMergeSuite(t *testing.T) {
+	results := []merge.Result{
+		{Project: "group/a", MergeRequestIID: 3, Title: "Release", Status: merge.StatusMerged},
+		{Project: "group/b", MergeRequestIID: 4, Title: "Hotfix", Status: merge.StatusSkipped, Reason: "head pipeline is running"},
+		{Project: "group/c", Status: merge.StatusUnauthorized, ErrorMessage: "failed to fetch merge requests"},
+		{Project: "group/d", MergeRequestIID: 5, Title: "Bump", Status: merge.StatusConflict, Reason: "merge request has conflicts"},
+		{Project: "group/e", MergeRequestIID: 6, Title: "Train", Status: merge.StatusDropped, Reason: "dropped from the merge train"},
+		{Project: "group/f", MergeRequestIID: 7, Title: "Train", Status: merge.StatusQueued, Reason: "still on the merge train (status: fresh)"},
+		{Project: "group/g", MergeRequestIID: 8, Title: "Service", Status: merge.StatusBlocked, Reason: "waits for group/a: !3 was not merged (SKIPPED)"},
+	}
+
+	suite := MergeSuite("main", time.Time{}, results)
+
+	expected := []Outcome{Passed, Skipped, Failed, Failed, Failed, Skipped, Skipped}
+	for i, outcome := range expected {
+		if suite.Cases[i].Outcome != outcome {
+			t.Errorf("case %d: expected outcome %d, got %d", i, outcome, suite.Cases[i].Outcome)
+		}
+	}
+	if suite.Cases[0].Name != "group/a!3 Release" || suite.Cases[2].Name != "group/c" {
+		t.Errorf("unexpected case names: %q, %q", suite.Cases[0].Name, suite.Cases[2].Name)
+	}
+	if suite.Cases[1].Message != "SKIPPED: head pipeline is running" || suite.Cases[3].Message != "CONFLICT: merge request has conflicts" {
+		t.Errorf("unexpected messages: %q, %q", suite.Cases[1].Message, suite.Cases[3].Message)
+	}
+}
@@ -0,0 +1,50 @@
+package workpool
+
+import "sync"
+
+// Run calls process for 0..n-1 on up to workers goroutines and returns the
+// results in input order. done, if set, is called after every item with the
+// number of items finished so far; calls are serialized.
+func Run[T any](n, workers int, process func(i int) T, done func(finished int, result T)) []T {
+	results := make([]T, n)
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		finished int
+	)
+
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := process(i)
+				results[i] = result
+
+				if done != nil {
+					mu.Lock()
+					finished++
+					done(finished, result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,42 @@
+package workpool
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var running, peak atomic.Int32
+	var finished []int
+
+	results := Run(10, 3, func(i int) int {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		return i * i
+	}, func(n int, result int) {
+		finished = append(finished, n)
+	})
+
+	if !reflect.DeepEqual(results, []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81}) {
+		t.Errorf("results out of order: %v", results)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 workers, saw %d", peak.Load())
+	}
+	if !reflect.DeepEqual(finished, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+		t.Errorf("unexpected progress counts: %v", finished)
+	}
+}
+
+func TestRun_NoItems(t *testing.T) {
+	if results := Run(0, 4, func(i int) int { return i }, nil); len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+}